@@ -0,0 +1,230 @@
+// Package auswertung erstellt die Ranglisten für die Siegerehrung.
+// Wie planung arbeitet es nur auf einfachen Eingabedaten.
+package auswertung
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Altersklasse fasst Jahrgänge zusammen (z. B. "U10")
+type Altersklasse struct {
+	Name        string `json:"name"`
+	JahrgangVon int    `json:"jahrgangVon"`
+	JahrgangBis int    `json:"jahrgangBis"`
+}
+
+// Tie-Break-Regeln, in der konfigurierten Reihenfolge angewendet
+const (
+	TiebreakMeisteStationen    = "meisteStationen"    // mehr gewertete Stationen
+	TiebreakBesteEinzelwertung = "besteEinzelwertung" // höchste Punktzahl an einer Station
+	TiebreakStation            = "station:"           // "station:<stationsNummer>" – mehr Punkte dort
+)
+
+// Regeln der Rangliste (config.json "rangliste")
+type Regeln struct {
+	Altersklassen []Altersklasse `json:"altersklassen,omitempty"` // leer → je Jahrgang
+	Tiebreak      []string       `json:"tiebreak,omitempty"`
+}
+
+// Prüft Altersklassen und Tie-Break-Regeln
+func (r Regeln) Validate() error {
+	for i, k := range r.Altersklassen {
+		if k.Name == "" || k.JahrgangVon > k.JahrgangBis {
+			return fmt.Errorf("Altersklasse %d ungültig", i+1)
+		}
+		for _, a := range r.Altersklassen[:i] {
+			if k.JahrgangVon <= a.JahrgangBis && a.JahrgangVon <= k.JahrgangBis {
+				return fmt.Errorf("Altersklassen %s und %s überschneiden sich", a.Name, k.Name)
+			}
+		}
+	}
+	for _, t := range r.Tiebreak {
+		switch {
+		case t == TiebreakMeisteStationen, t == TiebreakBesteEinzelwertung:
+		case strings.HasPrefix(t, TiebreakStation):
+			if _, err := strconv.Atoi(strings.TrimPrefix(t, TiebreakStation)); err != nil {
+				return fmt.Errorf("Tie-Break %q: Stationsnummer ungültig", t)
+			}
+		default:
+			return fmt.Errorf("unbekannter Tie-Break %q", t)
+		}
+	}
+	return nil
+}
+
+// Klasse liefert den Namen der Altersklasse zum Jahrgang
+func (r Regeln) Klasse(jahrgang int) string {
+	if len(r.Altersklassen) == 0 {
+		return "Jahrgang " + strconv.Itoa(jahrgang)
+	}
+	for _, k := range r.Altersklassen {
+		if jahrgang >= k.JahrgangVon && jahrgang <= k.JahrgangBis {
+			return k.Name
+		}
+	}
+	return "ohne Altersklasse"
+}
+
+// Teilnehmer – ein Kind mit seinen Pflichtstationen (Stationsnummern)
+type Teilnehmer struct {
+	KindObjectID string
+	VorName      string
+	NachName     string
+	Jahrgang     int
+	Geschlecht   string
+	Pflicht      []int
+}
+
+// Ergebnis – ein Resultat (Punkte eines Kindes an einer Station)
+type Ergebnis struct {
+	KindObjectID   string
+	StationsNummer int
+	Punkte         int
+}
+
+// Platzierung – eine Zeile der Rangliste
+type Platzierung struct {
+	Platz               int         `json:"platz,omitempty"` // nur bei vollständigen Teilnehmern
+	KindObjectID        string      `json:"kindObjectId"`
+	VorName             string      `json:"vorName"`
+	NachName            string      `json:"nachName"`
+	Jahrgang            int         `json:"jahrgang"`
+	Gesamtpunkte        int         `json:"gesamtpunkte"`
+	AnzStationen        int         `json:"anzStationen"`
+	AnzPflichtStationen int         `json:"anzPflichtStationen"`
+	FehlendeStationen   []int       `json:"fehlendeStationen,omitempty"`
+	Punkte              map[int]int `json:"punkte"` // Stationsnummer → Punkte
+	besteEinzelwertung  int
+}
+
+// Wertungsgruppe – Altersklasse × Geschlecht
+type Wertungsgruppe struct {
+	Klasse         string        `json:"klasse"`
+	Geschlecht     string        `json:"geschlecht"`
+	Vollstaendig   []Platzierung `json:"vollstaendig"`
+	Unvollstaendig []Platzierung `json:"unvollstaendig"`
+}
+
+// Rangliste summiert die Punkte je Kind, gruppiert nach Altersklasse und
+// Geschlecht und vergibt Plätze an alle Kinder, die jede Pflichtstation
+// absolviert haben. Bei Punktgleichheit entscheiden die Tie-Break-Regeln;
+// sind auch diese gleich, teilen sich die Kinder den Platz (1, 1, 3, …).
+func Rangliste(teilnehmer []Teilnehmer, ergebnisse []Ergebnis, regeln Regeln) ([]Wertungsgruppe, error) {
+	if err := regeln.Validate(); err != nil {
+		return nil, err
+	}
+
+	punkte := map[string]map[int]int{}
+	for _, e := range ergebnisse {
+		if punkte[e.KindObjectID] == nil {
+			punkte[e.KindObjectID] = map[int]int{}
+		}
+		punkte[e.KindObjectID][e.StationsNummer] = e.Punkte
+	}
+
+	gruppen := map[string]*Wertungsgruppe{}
+	for _, t := range teilnehmer {
+		p := Platzierung{
+			KindObjectID:        t.KindObjectID,
+			VorName:             t.VorName,
+			NachName:            t.NachName,
+			Jahrgang:            t.Jahrgang,
+			AnzPflichtStationen: len(t.Pflicht),
+			Punkte:              map[int]int{},
+		}
+		for nr, pkt := range punkte[t.KindObjectID] {
+			p.Punkte[nr] = pkt
+			p.Gesamtpunkte += pkt
+			p.AnzStationen++
+			if pkt > p.besteEinzelwertung {
+				p.besteEinzelwertung = pkt
+			}
+		}
+		for _, nr := range t.Pflicht {
+			if _, ok := p.Punkte[nr]; !ok {
+				p.FehlendeStationen = append(p.FehlendeStationen, nr)
+			}
+		}
+		sort.Ints(p.FehlendeStationen)
+
+		klasse := regeln.Klasse(t.Jahrgang)
+		key := klasse + "|" + t.Geschlecht
+		g, ok := gruppen[key]
+		if !ok {
+			g = &Wertungsgruppe{
+				Klasse:         klasse,
+				Geschlecht:     t.Geschlecht,
+				Vollstaendig:   []Platzierung{},
+				Unvollstaendig: []Platzierung{},
+			}
+			gruppen[key] = g
+		}
+		if len(p.FehlendeStationen) == 0 {
+			g.Vollstaendig = append(g.Vollstaendig, p)
+		} else {
+			g.Unvollstaendig = append(g.Unvollstaendig, p)
+		}
+	}
+
+	ergebnis := make([]Wertungsgruppe, 0, len(gruppen))
+	for _, g := range gruppen {
+		sortiere(g.Vollstaendig, regeln.Tiebreak)
+		sortiere(g.Unvollstaendig, regeln.Tiebreak)
+
+		for i := range g.Vollstaendig {
+			if i > 0 && vergleiche(g.Vollstaendig[i-1], g.Vollstaendig[i], regeln.Tiebreak) == 0 {
+				g.Vollstaendig[i].Platz = g.Vollstaendig[i-1].Platz
+			} else {
+				g.Vollstaendig[i].Platz = i + 1
+			}
+		}
+		ergebnis = append(ergebnis, *g)
+	}
+
+	sort.Slice(ergebnis, func(i, j int) bool {
+		if ergebnis[i].Klasse != ergebnis[j].Klasse {
+			return ergebnis[i].Klasse < ergebnis[j].Klasse
+		}
+		return ergebnis[i].Geschlecht < ergebnis[j].Geschlecht
+	})
+	return ergebnis, nil
+}
+
+// Bessere zuerst; bei völliger Gleichheit alphabetisch
+func sortiere(liste []Platzierung, tiebreak []string) {
+	sort.SliceStable(liste, func(i, j int) bool {
+		if c := vergleiche(liste[i], liste[j], tiebreak); c != 0 {
+			return c > 0
+		}
+		if liste[i].NachName != liste[j].NachName {
+			return liste[i].NachName < liste[j].NachName
+		}
+		return liste[i].VorName < liste[j].VorName
+	})
+}
+
+// > 0: a ist besser, < 0: b ist besser, 0: gleichwertig
+func vergleiche(a, b Platzierung, tiebreak []string) int {
+	if a.Gesamtpunkte != b.Gesamtpunkte {
+		return a.Gesamtpunkte - b.Gesamtpunkte
+	}
+	for _, t := range tiebreak {
+		var wa, wb int
+		switch {
+		case t == TiebreakMeisteStationen:
+			wa, wb = a.AnzStationen, b.AnzStationen
+		case t == TiebreakBesteEinzelwertung:
+			wa, wb = a.besteEinzelwertung, b.besteEinzelwertung
+		case strings.HasPrefix(t, TiebreakStation):
+			nr, _ := strconv.Atoi(strings.TrimPrefix(t, TiebreakStation))
+			wa, wb = a.Punkte[nr], b.Punkte[nr]
+		}
+		if wa != wb {
+			return wa - wb
+		}
+	}
+	return 0
+}
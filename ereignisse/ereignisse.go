@@ -0,0 +1,196 @@
+// Package ereignisse verteilt Live-Ereignisse (Resultate, Riegen-Fortschritt,
+// Anmeldungen) an verbundene Clients, z. B. die Ergebnistafel oder die
+// Tablets der Organisatoren.
+//
+// Die letzten Ereignisse werden in einem Ringpuffer gehalten, damit ein
+// Client nach einem Verbindungsabbruch mit Last-Event-ID dort weitermachen
+// kann, wo er aufgehört hat. Der Puffer lebt nur im Speicher: nach einem
+// Neustart beginnt ein neuer Lauf und Clients müssen neu laden.
+package ereignisse
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Typ eines Ereignisses
+type Typ string
+
+const (
+	TypResultat      Typ = "resultat"      // Resultat gespeichert oder korrigiert
+	TypRiegenLogging Typ = "riegenLogging" // Station einer Riege erledigt
+	TypRiegeBeendet  Typ = "riegeBeendet"  // alle Pflichtstationen der Riege erledigt
+	TypKind          Typ = "kind"          // Kind angemeldet oder geändert
+)
+
+func (t Typ) Gueltig() bool {
+	switch t {
+	case TypResultat, TypRiegenLogging, TypRiegeBeendet, TypKind:
+		return true
+	}
+	return false
+}
+
+// Ereignis – eine Nachricht an die Clients
+type Ereignis struct {
+	ID        string    `json:"id"` // "<Lauf>-<Nummer>", für Last-Event-ID
+	Typ       Typ       `json:"typ"`
+	Zeitpunkt time.Time `json:"zeitpunkt"`
+	RiegeID   string    `json:"riegeObjectId,omitempty"`
+	StationID string    `json:"stationObjectId,omitempty"`
+	Daten     any       `json:"daten"`
+
+	nummer uint64
+}
+
+// Filter – leere Felder filtern nicht
+type Filter struct {
+	RiegeID   string
+	StationID string
+	Typen     []Typ
+}
+
+// Passt prüft, ob das Ereignis den Filter erfüllt.
+// Ereignisse ohne Riege (bzw. Station) passen nicht zu einem Riegen- (bzw. Stations-)Filter.
+func (f Filter) Passt(e Ereignis) bool {
+	if f.RiegeID != "" && e.RiegeID != f.RiegeID {
+		return false
+	}
+	if f.StationID != "" && e.StationID != f.StationID {
+		return false
+	}
+	if len(f.Typen) > 0 && !slices.Contains(f.Typen, e.Typ) {
+		return false
+	}
+	return true
+}
+
+// Puffergröße je Abonnent; wer nicht nachkommt, wird getrennt
+// und setzt nach dem Wiederverbinden per Last-Event-ID fort.
+const aboPuffer = 64
+
+// Bus verteilt Ereignisse und hält die letzten im Ringpuffer
+type Bus struct {
+	mu     sync.Mutex
+	lauf   string
+	puffer []Ereignis // Ringpuffer
+	anfang int        // Index des ältesten Ereignisses
+	anzahl int
+	nummer uint64 // Nummer des letzten Ereignisses
+	abos   map[*Abo]struct{}
+}
+
+// NewBus erzeugt einen Bus, der die letzten kapazitaet Ereignisse behält
+func NewBus(kapazitaet int) *Bus {
+	if kapazitaet <= 0 {
+		kapazitaet = 1000
+	}
+	return &Bus{
+		lauf:   strconv.FormatInt(time.Now().Unix(), 36),
+		puffer: make([]Ereignis, kapazitaet),
+		abos:   map[*Abo]struct{}{},
+	}
+}
+
+// Abo – ein verbundener Client
+type Abo struct {
+	C <-chan Ereignis // wird geschlossen, wenn der Client nicht nachkommt oder abgemeldet wird
+
+	c      chan Ereignis
+	filter Filter
+	bus    *Bus
+}
+
+// Veroeffentlichen vergibt ID und Zeitpunkt und verteilt das Ereignis.
+// Ein nil-Bus verwirft Ereignisse (Live-Ereignisse nicht konfiguriert).
+func (b *Bus) Veroeffentlichen(typ Typ, riegeID, stationID string, daten any) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nummer++
+	e := Ereignis{
+		ID:        b.lauf + "-" + strconv.FormatUint(b.nummer, 10),
+		Typ:       typ,
+		Zeitpunkt: time.Now().UTC(),
+		RiegeID:   riegeID,
+		StationID: stationID,
+		Daten:     daten,
+		nummer:    b.nummer,
+	}
+
+	// ---- in den Ringpuffer ----
+	if b.anzahl < len(b.puffer) {
+		b.puffer[(b.anfang+b.anzahl)%len(b.puffer)] = e
+		b.anzahl++
+	} else {
+		b.puffer[b.anfang] = e
+		b.anfang = (b.anfang + 1) % len(b.puffer)
+	}
+
+	// ---- an die Abonnenten ----
+	for a := range b.abos {
+		if !a.filter.Passt(e) {
+			continue
+		}
+		select {
+		case a.c <- e:
+		default:
+			// Client kommt nicht nach → trennen
+			delete(b.abos, a)
+			close(a.c)
+		}
+	}
+}
+
+// Abonnieren meldet einen Client an. Mit letzteID (Last-Event-ID) werden
+// die seither verpassten, zum Filter passenden Ereignisse zurückgegeben.
+// vollstaendig ist false, wenn die Lücke nicht mehr im Puffer liegt oder
+// die ID aus einem früheren Lauf stammt – der Client muss dann neu laden.
+func (b *Bus) Abonnieren(f Filter, letzteID string) (abo *Abo, verpasst []Ereignis, vollstaendig bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan Ereignis, aboPuffer)
+	abo = &Abo{C: c, c: c, filter: f, bus: b}
+	b.abos[abo] = struct{}{}
+
+	if letzteID == "" {
+		return abo, nil, true
+	}
+
+	lauf, nr, ok := strings.Cut(letzteID, "-")
+	nummer, err := strconv.ParseUint(nr, 10, 64)
+	if !ok || err != nil || lauf != b.lauf || nummer > b.nummer {
+		return abo, nil, false
+	}
+
+	// ältestes Ereignis im Puffer muss direkt auf letzteID folgen
+	vollstaendig = true
+	if b.anzahl > 0 && b.puffer[b.anfang].nummer > nummer+1 {
+		vollstaendig = false
+	}
+	for i := 0; i < b.anzahl; i++ {
+		e := b.puffer[(b.anfang+i)%len(b.puffer)]
+		if e.nummer > nummer && f.Passt(e) {
+			verpasst = append(verpasst, e)
+		}
+	}
+	return abo, verpasst, vollstaendig
+}
+
+// Abmelden trennt den Client; mehrfacher Aufruf ist unschädlich
+func (a *Abo) Abmelden() {
+	b := a.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.abos[a]; ok {
+		delete(b.abos, a)
+		close(a.c)
+	}
+}
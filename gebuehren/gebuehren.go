@@ -0,0 +1,171 @@
+// Package gebuehren berechnet die Startgebühr je Kind: Grundgebühr,
+// Geschwisterrabatt, Zuschlag für Nachmeldungen und Befreiungen.
+// Wie planung und auswertung arbeitet es nur auf einfachen Eingabedaten;
+// alle Beträge sind in Cent.
+package gebuehren
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Konfiguration aus config.json ("gebuehren")
+type Konfiguration struct {
+	Grundgebuehr int `json:"grundgebuehr"` // 0 → keine Gebühren
+	// Rabatt je Geschwisterposition in Anmeldereihenfolge: [0, 200, 400]
+	// = erstes Kind voller Preis, zweites 2 € günstiger, ab dem dritten 4 €
+	GeschwisterRabatt []int `json:"geschwisterRabatt,omitempty"`
+	// Zuschlag für Anmeldungen ab NachmeldungAb (weiche Frist vor der Deadline)
+	NachmeldeZuschlag int        `json:"nachmeldeZuschlag,omitempty"`
+	NachmeldungAb     *time.Time `json:"nachmeldungAb,omitempty"`
+	// Befreiungsgründe → erlassener Anteil in Prozent (z. B. "helferkind": 100)
+	Befreiungen map[string]int `json:"befreiungen,omitempty"`
+}
+
+// Aktiv: sind überhaupt Gebühren konfiguriert?
+func (k Konfiguration) Aktiv() bool {
+	return k.Grundgebuehr > 0
+}
+
+// Prüft Beträge und Prozentsätze
+func (k Konfiguration) Validate() error {
+	if k.Grundgebuehr < 0 || k.NachmeldeZuschlag < 0 {
+		return errors.New("Beträge dürfen nicht negativ sein")
+	}
+	for i, r := range k.GeschwisterRabatt {
+		if r < 0 || r > k.Grundgebuehr {
+			return fmt.Errorf("geschwisterRabatt %d ungültig", i+1)
+		}
+	}
+	if k.NachmeldeZuschlag > 0 && k.NachmeldungAb == nil {
+		return errors.New("nachmeldeZuschlag erfordert nachmeldungAb")
+	}
+	for grund, p := range k.Befreiungen {
+		if grund == "" || p <= 0 || p > 100 {
+			return fmt.Errorf("Befreiung %q: Prozentsatz muss zwischen 1 und 100 liegen", grund)
+		}
+	}
+	return nil
+}
+
+// Posten-Arten einer Gebühr
+const (
+	PostenGrundgebuehr      = "grundgebuehr"
+	PostenGeschwisterRabatt = "geschwisterRabatt"
+	PostenNachmeldeZuschlag = "nachmeldeZuschlag"
+	PostenBefreiung         = "befreiung"
+)
+
+// Posten – eine Zeile der Berechnung (Rabatte negativ)
+type Posten struct {
+	Art    string `json:"art"`
+	Text   string `json:"text"`
+	Betrag int    `json:"betrag"`
+}
+
+// Kind – was für die Berechnung gebraucht wird
+type Kind struct {
+	ObjectID   string
+	NachName   string
+	Kontakt    string    // gemeinsamer Kontakt der Erziehungsberechtigten, "" = unbekannt
+	Angemeldet time.Time // createdAt
+	Befreiung  string    // Befreiungsgrund, "" = keiner
+}
+
+// Gebuehr eines Kindes
+type Gebuehr struct {
+	KindObjectID string   `json:"kindObjectId"`
+	Familie      string   `json:"familie,omitempty"`
+	Betrag       int      `json:"betrag"`
+	Posten       []Posten `json:"posten"`
+}
+
+// Familie: gleicher Nachname (ohne Groß-/Kleinschreibung) und gleicher
+// Kontakt. Ohne Kontakt gibt es keine Familie und keinen Geschwisterrabatt.
+func Familie(nachName, kontakt string) string {
+	if kontakt == "" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(nachName)) + "|" + kontakt
+}
+
+// Berechne liefert die Gebühr jedes Kindes. Für den Geschwisterrabatt
+// müssen alle Kinder der betroffenen Familien übergeben werden; die
+// Position ergibt sich aus der Anmeldereihenfolge, spätere Anmeldungen
+// ändern die Gebühr früher angemeldeter Geschwister also nicht.
+func (k Konfiguration) Berechne(kinder []Kind) map[string]Gebuehr {
+	ergebnis := make(map[string]Gebuehr, len(kinder))
+	if !k.Aktiv() {
+		for _, kind := range kinder {
+			ergebnis[kind.ObjectID] = Gebuehr{KindObjectID: kind.ObjectID, Posten: []Posten{}}
+		}
+		return ergebnis
+	}
+
+	// ---- Geschwisterposition je Familie ----
+	familien := map[string][]Kind{}
+	for _, kind := range kinder {
+		if f := Familie(kind.NachName, kind.Kontakt); f != "" {
+			familien[f] = append(familien[f], kind)
+		}
+	}
+	position := map[string]int{}
+	for _, geschwister := range familien {
+		sort.Slice(geschwister, func(i, j int) bool {
+			if !geschwister[i].Angemeldet.Equal(geschwister[j].Angemeldet) {
+				return geschwister[i].Angemeldet.Before(geschwister[j].Angemeldet)
+			}
+			return geschwister[i].ObjectID < geschwister[j].ObjectID
+		})
+		for i, g := range geschwister {
+			position[g.ObjectID] = i
+		}
+	}
+
+	for _, kind := range kinder {
+		g := Gebuehr{
+			KindObjectID: kind.ObjectID,
+			Familie:      Familie(kind.NachName, kind.Kontakt),
+			Posten:       []Posten{{Art: PostenGrundgebuehr, Text: "Grundgebühr", Betrag: k.Grundgebuehr}},
+		}
+
+		if n := len(k.GeschwisterRabatt); n > 0 && g.Familie != "" {
+			pos := min(position[kind.ObjectID], n-1)
+			if r := k.GeschwisterRabatt[pos]; r > 0 {
+				g.Posten = append(g.Posten, Posten{
+					Art:    PostenGeschwisterRabatt,
+					Text:   fmt.Sprintf("Geschwisterrabatt (%d. Kind)", position[kind.ObjectID]+1),
+					Betrag: -r,
+				})
+			}
+		}
+
+		if k.NachmeldeZuschlag > 0 && !kind.Angemeldet.Before(*k.NachmeldungAb) {
+			g.Posten = append(g.Posten, Posten{
+				Art:    PostenNachmeldeZuschlag,
+				Text:   "Zuschlag Nachmeldung",
+				Betrag: k.NachmeldeZuschlag,
+			})
+		}
+
+		summe := 0
+		for _, p := range g.Posten {
+			summe += p.Betrag
+		}
+		if prozent, ok := k.Befreiungen[kind.Befreiung]; ok && kind.Befreiung != "" {
+			erlass := (summe*prozent + 50) / 100
+			g.Posten = append(g.Posten, Posten{
+				Art:    PostenBefreiung,
+				Text:   fmt.Sprintf("Befreiung %s (%d %%)", kind.Befreiung, prozent),
+				Betrag: -erlass,
+			})
+			summe -= erlass
+		}
+		g.Betrag = max(summe, 0)
+		ergebnis[kind.ObjectID] = g
+	}
+	return ergebnis
+}
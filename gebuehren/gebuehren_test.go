@@ -0,0 +1,100 @@
+package gebuehren
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBerechne(t *testing.T) {
+	frist := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	vorher := frist.Add(-24 * time.Hour)
+	konfig := Konfiguration{
+		Grundgebuehr:      1000,
+		GeschwisterRabatt: []int{0, 200, 400},
+		NachmeldeZuschlag: 300,
+		NachmeldungAb:     &frist,
+		Befreiungen:       map[string]int{"helferkind": 100, "ermaessigt": 33},
+	}
+
+	tests := []struct {
+		name   string
+		konfig Konfiguration
+		kinder []Kind
+		want   map[string]int
+	}{
+		{"Einzelkind", konfig,
+			[]Kind{{ObjectID: "a", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher}},
+			map[string]int{"a": 1000}},
+		{"Geschwister in Anmeldereihenfolge", konfig,
+			[]Kind{
+				{ObjectID: "c", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher.Add(2 * time.Minute)},
+				{ObjectID: "d", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher.Add(3 * time.Minute)},
+				{ObjectID: "a", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher},
+				{ObjectID: "b", NachName: "muster ", Kontakt: "k1", Angemeldet: vorher.Add(time.Minute)},
+			},
+			map[string]int{"a": 1000, "b": 800, "c": 600, "d": 600}},
+		{"gleiche Anmeldezeit: objectId entscheidet", konfig,
+			[]Kind{
+				{ObjectID: "y", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher},
+				{ObjectID: "x", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher},
+			},
+			map[string]int{"x": 1000, "y": 800}},
+		{"ohne Kontakt keine Geschwister", konfig,
+			[]Kind{
+				{ObjectID: "a", NachName: "Muster", Angemeldet: vorher},
+				{ObjectID: "b", NachName: "Muster", Angemeldet: vorher.Add(time.Minute)},
+			},
+			map[string]int{"a": 1000, "b": 1000}},
+		{"anderer Nachname, gleicher Kontakt", konfig,
+			[]Kind{
+				{ObjectID: "a", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher},
+				{ObjectID: "b", NachName: "Beispiel", Kontakt: "k1", Angemeldet: vorher.Add(time.Minute)},
+			},
+			map[string]int{"a": 1000, "b": 1000}},
+		{"Nachmeldung genau ab Frist", konfig,
+			[]Kind{
+				{ObjectID: "a", Angemeldet: frist.Add(-time.Millisecond)},
+				{ObjectID: "b", Angemeldet: frist},
+				{ObjectID: "c", Angemeldet: frist.Add(time.Hour)},
+			},
+			map[string]int{"a": 1000, "b": 1300, "c": 1300}},
+		{"Teilbefreiung wird kaufmännisch gerundet", konfig,
+			// 33 % von 1300 = 429, von 1000 = 330
+			[]Kind{
+				{ObjectID: "a", NachName: "Muster", Kontakt: "k1", Angemeldet: frist, Befreiung: "ermaessigt"},
+				{ObjectID: "b", Angemeldet: vorher, Befreiung: "ermaessigt"},
+			},
+			map[string]int{"a": 871, "b": 670}},
+		{"Teilbefreiung: halber Cent rundet auf", Konfiguration{Grundgebuehr: 999, Befreiungen: map[string]int{"haelfte": 50}},
+			[]Kind{{ObjectID: "a", Befreiung: "haelfte"}},
+			map[string]int{"a": 499}},
+		{"volle Befreiung", konfig,
+			[]Kind{{ObjectID: "a", Angemeldet: frist, Befreiung: "helferkind"}},
+			map[string]int{"a": 0}},
+		{"unbekannter Befreiungsgrund", konfig,
+			[]Kind{{ObjectID: "a", Angemeldet: vorher, Befreiung: "unbekannt"}},
+			map[string]int{"a": 1000}},
+		{"nie unter 0", Konfiguration{Grundgebuehr: 1000, GeschwisterRabatt: []int{0, 1500}},
+			[]Kind{
+				{ObjectID: "a", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher},
+				{ObjectID: "b", NachName: "Muster", Kontakt: "k1", Angemeldet: vorher.Add(time.Minute)},
+			},
+			map[string]int{"a": 1000, "b": 0}},
+		{"keine Gebühren", Konfiguration{},
+			[]Kind{{ObjectID: "a", Angemeldet: frist}},
+			map[string]int{"a": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.konfig.Berechne(tt.kinder)
+			if len(got) != len(tt.kinder) {
+				t.Fatalf("%d Gebühren, want %d", len(got), len(tt.kinder))
+			}
+			for id, want := range tt.want {
+				if g := got[id]; g.Betrag != want {
+					t.Errorf("%s: %d, want %d (%+v)", id, g.Betrag, want, g.Posten)
+				}
+			}
+		})
+	}
+}
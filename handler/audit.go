@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Audit-Log  ======
+//
+// Jede Änderung (anlegen, ändern, löschen, Zahlungen, Resultate) landet
+// mit altem und neuem Stand, Akteur, Version und Request-ID in der
+// Klasse "auditLog". Der Server legt Einträge nur an; Clients ohne
+// Master-Key können die Klasse weder lesen noch ändern (schema.go).
+// Wer den Master-Key hat, kann Einträge trotzdem ändern oder löschen –
+// ein manipulationssicheres Protokoll ist auditLog also nicht.
+
+// Header mit der Request-ID; fehlt er, erzeugt der Server eine
+const requestIDHeader = "X-Request-ID"
+
+type requestIDSchluessel struct{}
+
+// RequestID versieht jede Anfrage mit einer ID: die vom Client gesendete
+// (bis 128 druckbare Zeichen) oder eine neue. Sie steht im Antwort-Header
+// und in jedem Audit-Eintrag der Anfrage.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rid := r.Header.Get(requestIDHeader)
+		if !gueltigeRequestID(rid) {
+			rid = neueRequestID()
+		}
+		w.Header().Set(requestIDHeader, rid)
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDSchluessel{}, rid)))
+	})
+}
+
+func gueltigeRequestID(rid string) bool {
+	if rid == "" || len(rid) > 128 {
+		return false
+	}
+	for i := 0; i < len(rid); i++ {
+		if rid[i] < 0x21 || rid[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func neueRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Request-ID der Anfrage (ohne Middleware: der gesendete Header)
+func requestIDVon(r *http.Request) string {
+	if rid, ok := r.Context().Value(requestIDSchluessel{}).(string); ok {
+		return rid
+	}
+	return r.Header.Get(requestIDHeader)
+}
+
+// Akteur für das Protokoll: Name, sonst Benutzer-ID
+func akteurVon(id *Identitaet) string {
+	if id.Name != "" {
+		return id.Name
+	}
+	return id.UserID
+}
+
+// Schreibt einen Audit-Eintrag. Wie beim Superuser-Protokoll macht ein
+// Fehler die Änderung nicht rückgängig, wird aber geloggt.
+func (h *KindHandler) audit(r *http.Request, id *Identitaet, aktion, klasse, objektID string, alt, neu any, version int) {
+	eintrag := strukturen.AuditEintrag{
+		Akteur:    akteurVon(id),
+		AkteurID:  id.UserID,
+		Rolle:     string(id.Rolle),
+		Aktion:    aktion,
+		Klasse:    klasse,
+		ObjektID:  objektID,
+		Alt:       alt,
+		Neu:       neu,
+		Version:   version,
+		Zeitpunkt: strukturen.NewParseDate(time.Now()),
+		RequestID: requestIDVon(r),
+	}
+	if _, err := h.Parse.Create(r.Context(), "auditLog", eintrag); err != nil {
+		log.Printf("Audit-Log fehlgeschlagen (%s %s %s/%s): %v",
+			eintrag.Akteur, aktion, klasse, objektID, err)
+	}
+}
+
+// Stand der Felder eines Objekts vor einer Änderung (für alt). Lesefehler
+// werden nur geloggt; das Protokoll hält dann nur den neuen Stand fest.
+func (h *KindHandler) auditStand(ctx context.Context, klasse, objectID string, felder map[string]any) map[string]any {
+	obj, err := h.Parse.Get(ctx, klasse, objectID)
+	if err != nil {
+		if !parse.IsNotFound(err) {
+			log.Printf("Audit-Log: %s/%s nicht lesbar: %v", klasse, objectID, err)
+		}
+		return nil
+	}
+	stand := make(map[string]any, len(felder))
+	for feld := range felder {
+		stand[feld] = obj[feld]
+	}
+	return stand
+}
+
+// ======  GET /audit – nur für Admins  ======
+//
+// Filter (alle optional, kombinierbar):
+//
+//	klasse=Kind&objektId=…   Änderungen an einem Objekt
+//	akteur=…                 Name oder Benutzer-ID
+//	requestId=…              alle Einträge einer Anfrage
+//	von=…&bis=…              YYYY-MM-DD (deutsche Zeit, einschließlich) oder RFC 3339
+//	limit=…&skip=…           Seiten (Standard 100, höchstens 1000), neueste zuerst
+func (h *KindHandler) AuditRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	query := parse.NewQuery()
+	for _, feld := range []string{"klasse", "objektId", "requestId"} {
+		if v := q.Get(feld); v != "" {
+			query.EqualTo(feld, v)
+		}
+	}
+	if akteur := q.Get("akteur"); akteur != "" {
+		query.Or(
+			parse.NewQuery().EqualTo("akteur", akteur),
+			parse.NewQuery().EqualTo("akteurId", akteur),
+		)
+	}
+	if v := q.Get("von"); v != "" {
+		von, err := auditZeitpunkt(v, false)
+		if err != nil {
+			http.Error(w, "von muss YYYY-MM-DD oder RFC 3339 sein", http.StatusBadRequest)
+			return
+		}
+		query.GreaterThanOrEqualTo("zeitpunkt", strukturen.NewParseDate(von))
+	}
+	if v := q.Get("bis"); v != "" {
+		bis, err := auditZeitpunkt(v, true)
+		if err != nil {
+			http.Error(w, "bis muss YYYY-MM-DD oder RFC 3339 sein", http.StatusBadRequest)
+			return
+		}
+		query.LessThan("zeitpunkt", strukturen.NewParseDate(bis))
+	}
+
+	limit, skip := 100, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "limit muss zwischen 1 und 1000 liegen", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("skip"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "skip ungültig", http.StatusBadRequest)
+			return
+		}
+		skip = n
+	}
+	query.Order("-zeitpunkt", "-createdAt").Limit(limit).Skip(skip)
+
+	out, err := h.Parse.Query(r.Context(), "auditLog", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// Zeitgrenze für den Filter; ein Tag als Obergrenze zählt ganz mit
+// (→ Beginn des Folgetags, ausschließlich)
+func auditZeitpunkt(v string, obergrenze bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		if obergrenze {
+			// RFC-3339-Obergrenze einschließlich
+			return t.Add(time.Millisecond), nil
+		}
+		return t, nil
+	}
+	tag, err := time.ParseInLocation(time.DateOnly, v, kassenZone())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if obergrenze {
+		return tag.AddDate(0, 0, 1), nil
+	}
+	return tag, nil
+}
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Rollenmodell  ======
+//
+// eltern          – eigene Kinder registrieren und bearbeiten
+// riegenfuehrer   – eigene Riege verwalten
+// stationshelfer  – Resultate für die eigene Station erfassen
+// admin           – alles (auch der Superuser aus superuser.go)
+//
+// Identitäten kommen aus drei Quellen:
+//   - Authorization: Basic …          → Superuser (Rolle admin)
+//   - X-Parse-Session-Token: …        → Parse-_User, Rolle aus der Klasse Benutzerrolle
+//   - Authorization: Bearer <token>   → lokal ausgestelltes Token (POST /token)
+//
+// Rollen von Parse-Benutzern stehen in "Benutzerrolle" (nur Master-Key,
+// verwaltet über /benutzerrolle) und werden bei jeder Anfrage neu gelesen;
+// ein _User ohne Eintrag ist Eltern.
+// Der eigene _User ist für den Benutzer schreibbar; Rollenfelder dort
+// zählen daher nie und führen zur Ablehnung, wenn sie abweichen.
+
+type Rolle string
+
+const (
+	RolleEltern         Rolle = "eltern"
+	RolleRiegenfuehrer  Rolle = "riegenfuehrer"
+	RolleStationshelfer Rolle = "stationshelfer"
+	RolleAdmin          Rolle = "admin"
+)
+
+func (r Rolle) gueltig() bool {
+	switch r {
+	case RolleEltern, RolleRiegenfuehrer, RolleStationshelfer, RolleAdmin:
+		return true
+	}
+	return false
+}
+
+// Identitaet beschreibt den angemeldeten Aufrufer
+type Identitaet struct {
+	UserID    string `json:"sub"`
+	Name      string `json:"name"`
+	Rolle     Rolle  `json:"rolle"`
+	RiegeID   string `json:"riegeId,omitempty"`   // nur Riegenführer
+	StationID string `json:"stationId,omitempty"` // nur Stationshelfer
+	Ablauf    int64  `json:"exp"`                 // Unix-Zeit, nur lokale Tokens
+	Superuser bool   `json:"-"`
+}
+
+// Darf die Identität die Riege verwalten?
+func (id *Identitaet) darfRiege(riegeID string) bool {
+	return id.Rolle == RolleAdmin ||
+		(id.Rolle == RolleRiegenfuehrer && id.RiegeID != "" && id.RiegeID == riegeID)
+}
+
+// Darf die Identität Resultate für die Station erfassen?
+func (id *Identitaet) darfStation(stationID string) bool {
+	return id.Rolle == RolleAdmin ||
+		(id.Rolle == RolleStationshelfer && id.StationID != "" && id.StationID == stationID)
+}
+
+// Darf die Identität das Kind (Parse-Objekt) bearbeiten?
+func (id *Identitaet) darfKind(kind parse.Object) bool {
+	return id.Rolle == RolleAdmin ||
+		(id.Rolle == RolleEltern && id.UserID != "" && kind.String("elternID") == id.UserID)
+}
+
+var errNichtAngemeldet = errors.New("keine Zugangsdaten")
+
+// ======  Authentifizierung  ======
+
+// Ermittelt die Identität des Aufrufers.
+// errNichtAngemeldet, wenn keine Zugangsdaten gesendet wurden.
+func (h *KindHandler) authenticate(r *http.Request) (*Identitaet, error) {
+	// ---- Superuser (Basic Auth) ----
+	if name, ok, gesendet := h.superuser(r); gesendet {
+		if !ok {
+			return nil, errors.New("Superuser-Anmeldung fehlgeschlagen")
+		}
+		return &Identitaet{Name: name, Rolle: RolleAdmin, Superuser: true}, nil
+	}
+
+	// ---- Parse-Session ----
+	if token := r.Header.Get("X-Parse-Session-Token"); token != "" {
+		user, err := h.Parse.CurrentUser(r.Context(), token)
+		if err != nil {
+			return nil, errors.New("Session ungültig")
+		}
+		return h.identitaetAusUser(r.Context(), user)
+	}
+
+	// ---- lokales Token ----
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return h.verifyToken(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	return nil, errNichtAngemeldet
+}
+
+// Rolle und Zuständigkeit eines Parse-_User aus der Klasse Benutzerrolle
+func (h *KindHandler) identitaetAusUser(ctx context.Context, user parse.Object) (*Identitaet, error) {
+	// Felder am _User selbst – vom Benutzer schreibbar, nur zum Abgleich
+	var u struct {
+		Username   string                   `json:"username"`
+		Rolle      Rolle                    `json:"rolle"`
+		RiegeID    *strukturen.ParsePointer `json:"riegeID"`
+		StationsID *strukturen.ParsePointer `json:"stationsID"`
+	}
+	if err := user.Decode(&u); err != nil {
+		return nil, errors.New("Benutzer ungültig")
+	}
+
+	eintrag, err := h.benutzerrolle(ctx, user.ObjectID())
+	if err != nil {
+		return nil, errors.New("Rolle nicht lesbar")
+	}
+	// ohne Benutzerrolle ist jeder angemeldete Benutzer Eltern; Riegenführer,
+	// Stationshelfer und Admins brauchen einen Eintrag
+	b := strukturen.Benutzerrolle{Rolle: string(RolleEltern)}
+	if eintrag != nil {
+		if err := eintrag.Decode(&b); err != nil || !Rolle(b.Rolle).gueltig() {
+			return nil, errors.New("Benutzer hat keine gültige Rolle")
+		}
+	}
+
+	id := &Identitaet{
+		UserID:    user.ObjectID(),
+		Name:      u.Username,
+		Rolle:     Rolle(b.Rolle),
+		RiegeID:   zeigerID(b.RiegeID),
+		StationID: zeigerID(b.StationsID),
+	}
+
+	// ---- selbst gesetzte Rollenfelder am _User ablehnen ----
+	if (u.Rolle != "" && u.Rolle != id.Rolle) ||
+		(u.RiegeID != nil && u.RiegeID.ObjectID != id.RiegeID) ||
+		(u.StationsID != nil && u.StationsID.ObjectID != id.StationID) {
+		log.Printf("Anmeldung abgelehnt: Rollenfelder am _User %s weichen von Benutzerrolle ab", id.UserID)
+		return nil, errors.New("Rollenfelder am Benutzerkonto sind nicht zulässig")
+	}
+	return id, nil
+}
+
+// Benutzerrolle eines _User, nil wenn keine vergeben ist
+func (h *KindHandler) benutzerrolle(ctx context.Context, userID string) (parse.Object, error) {
+	out, err := h.Parse.Query(ctx, "Benutzerrolle", parse.NewQuery().PointerTo("userID", "_User", userID))
+	if err != nil {
+		return nil, err
+	}
+	switch len(out.Results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return out.Results[0], nil
+	}
+	return nil, fmt.Errorf("mehrere Benutzerrollen für %s", userID)
+}
+
+func zeigerID(p *strukturen.ParsePointer) string {
+	if p == nil {
+		return ""
+	}
+	return p.ObjectID
+}
+
+// Prüft Anmeldung und Rolle; schreibt bei Fehlschlag 401/403.
+// Ohne Angabe von Rollen genügt eine gültige Anmeldung.
+func (h *KindHandler) requireRolle(w http.ResponseWriter, r *http.Request, rollen ...Rolle) (*Identitaet, bool) {
+	id, err := h.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sporttag"`)
+		http.Error(w, "Nicht angemeldet: "+err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if len(rollen) == 0 {
+		return id, true
+	}
+	for _, rolle := range rollen {
+		if id.Rolle == rolle {
+			return id, true
+		}
+	}
+	http.Error(w, "Keine Berechtigung", http.StatusForbidden)
+	return nil, false
+}
+
+// ======  Lokale Tokens  ======
+//
+// Format: base64url(JSON der Identität) + "." + base64url(HMAC-SHA256)
+// Schlüssel: token_secret aus config.json bzw. SPORTTAG_TOKEN_SECRET
+
+func (h *KindHandler) signToken(id Identitaet) (string, error) {
+	if h.TokenSecret == "" {
+		return "", errors.New("kein token_secret konfiguriert")
+	}
+	payload, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+	teil := base64.RawURLEncoding.EncodeToString(payload)
+	return teil + "." + h.tokenSignatur(teil), nil
+}
+
+func (h *KindHandler) tokenSignatur(teil string) string {
+	mac := hmac.New(sha256.New, []byte(h.TokenSecret))
+	mac.Write([]byte(teil))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (h *KindHandler) verifyToken(token string) (*Identitaet, error) {
+	ungueltig := errors.New("Token ungültig")
+	if h.TokenSecret == "" {
+		return nil, ungueltig
+	}
+
+	teil, signatur, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signatur), []byte(h.tokenSignatur(teil))) {
+		return nil, ungueltig
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(teil)
+	if err != nil {
+		return nil, ungueltig
+	}
+
+	var id Identitaet
+	if err := json.Unmarshal(payload, &id); err != nil || !id.Rolle.gueltig() {
+		return nil, ungueltig
+	}
+	if time.Now().Unix() > id.Ablauf {
+		return nil, errors.New("Token abgelaufen")
+	}
+	return &id, nil
+}
+
+// TokenRequest für POST /token
+type TokenRequest struct {
+	UserID         string `json:"sub,omitempty"` // leer → neue ID
+	Name           string `json:"name"`
+	Rolle          Rolle  `json:"rolle"`
+	RiegeID        string `json:"riegeId,omitempty"`
+	StationID      string `json:"stationId,omitempty"`
+	GueltigStunden int    `json:"gueltigStunden,omitempty"` // Standard 24
+}
+
+// ======  POST /token – lokales Token ausstellen (nur admin) ======
+func (h *KindHandler) TokenRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	var req TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Validierung ----
+	if req.Name == "" || !req.Rolle.gueltig() {
+		http.Error(w, "name und gültige rolle erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.Rolle == RolleRiegenfuehrer && req.RiegeID == "" {
+		http.Error(w, "riegeId für Riegenführer erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.Rolle == RolleStationshelfer && req.StationID == "" {
+		http.Error(w, "stationId für Stationshelfer erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.GueltigStunden <= 0 {
+		req.GueltigStunden = 24
+	}
+	if req.UserID == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		req.UserID = "lokal-" + hex.EncodeToString(b)
+	}
+
+	id := Identitaet{
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Rolle:     req.Rolle,
+		RiegeID:   req.RiegeID,
+		StationID: req.StationID,
+		Ablauf:    time.Now().Add(time.Duration(req.GueltigStunden) * time.Hour).Unix(),
+	}
+	token, err := h.signToken(id)
+	if err != nil {
+		http.Error(w, "Token konnte nicht erstellt werden: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":      token,
+		"sub":        id.UserID,
+		"rolle":      id.Rolle,
+		"gueltigBis": time.Unix(id.Ablauf, 0).UTC(),
+	})
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sporttag/strukturen"
+)
+
+// Rollen von Parse-Benutzern kommen nur aus der Klasse Benutzerrolle,
+// ohne Eintrag sind sie Eltern
+func TestSessionRolle(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	riege := riegeAnlegen(t, mem, 1, false)
+
+	benutzer := func(token string, felder map[string]any) string {
+		t.Helper()
+		felder["sessionToken"] = token
+		felder["username"] = token
+		out, err := mem.Create(ctx, "_User", felder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.ObjectID()
+	}
+	rolle := func(userID string, b strukturen.Benutzerrolle) {
+		t.Helper()
+		b.UserID = strukturen.NewParsePointer("_User", userID)
+		if _, err := mem.Create(ctx, "Benutzerrolle", b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eltern := benutzer("eltern", map[string]any{})
+	rolle(eltern, strukturen.Benutzerrolle{Rolle: "eltern"})
+	// Eltern hat sich per PUT /users/<self> selbst zum Admin gemacht
+	aufsteiger := benutzer("aufsteiger", map[string]any{"rolle": "admin"})
+	rolle(aufsteiger, strukturen.Benutzerrolle{Rolle: "eltern"})
+	// nur Rollenfeld am _User, keine Benutzerrolle
+	benutzer("ohneRolle", map[string]any{"rolle": "admin"})
+	// frisch registriert, noch keine Benutzerrolle → Eltern
+	benutzer("neu", map[string]any{})
+	// Eltern ohne Eintrag, trägt sich selbst als Riegenführer ein
+	benutzer("neuFuehrer", map[string]any{"rolle": "riegenfuehrer", "riegeID": strukturen.NewParsePointer("Riege", riege)})
+	fuehrer := benutzer("fuehrer", map[string]any{})
+	rolle(fuehrer, strukturen.Benutzerrolle{Rolle: "riegenfuehrer", RiegeID: strukturen.NewParsePointer("Riege", riege)})
+	// fremde Riege am _User eingetragen
+	fremdeRiege := benutzer("fremdeRiege", map[string]any{"riegeID": strukturen.NewParsePointer("Riege", "andere")})
+	rolle(fremdeRiege, strukturen.Benutzerrolle{Rolle: "riegenfuehrer", RiegeID: strukturen.NewParsePointer("Riege", riege)})
+	// alte, vom Admin gesetzte und übereinstimmende Felder stören nicht
+	alt := benutzer("alt", map[string]any{"rolle": "admin"})
+	rolle(alt, strukturen.Benutzerrolle{Rolle: "admin"})
+
+	tests := []struct {
+		token     string
+		wantErr   bool
+		wantRolle Rolle
+		wantRiege string
+	}{
+		{"eltern", false, RolleEltern, ""},
+		{"aufsteiger", true, "", ""},
+		{"ohneRolle", true, "", ""},
+		{"neu", false, RolleEltern, ""},
+		{"neuFuehrer", true, "", ""},
+		{"fuehrer", false, RolleRiegenfuehrer, riege},
+		{"fremdeRiege", true, "", ""},
+		{"alt", false, RolleAdmin, ""},
+		{"unbekannt", true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Parse-Session-Token", tt.token)
+			id, err := h.authenticate(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Anmeldung als %s akzeptiert: %+v", tt.token, id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id.Rolle != tt.wantRolle || id.RiegeID != tt.wantRiege {
+				t.Errorf("Rolle %s, Riege %q; want %s, %q", id.Rolle, id.RiegeID, tt.wantRolle, tt.wantRiege)
+			}
+		})
+	}
+}
+
+// PUT/DELETE /benutzerrolle wirkt bei der nächsten Anfrage; ohne Rolle Eltern
+func TestBenutzerrolleRouter(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	user, err := mem.Create(ctx, "_User", map[string]any{"username": "neu", "sessionToken": "neu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := user.ObjectID()
+	station, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rolleVon := func() (Rolle, string, error) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Parse-Session-Token", "neu")
+		id, err := h.authenticate(r)
+		if err != nil {
+			return "", "", err
+		}
+		return id.Rolle, id.StationID, nil
+	}
+
+	tests := []struct {
+		name        string
+		id          *Identitaet
+		method      string
+		body        BenutzerrolleRequest
+		wantStatus  int
+		wantRolle   Rolle
+		wantStation string
+	}{
+		{"nur admin", &testEltern, http.MethodPut, BenutzerrolleRequest{UserID: userID, Rolle: RolleAdmin}, http.StatusForbidden, RolleEltern, ""},
+		{"unbekannter Benutzer", &testAdmin, http.MethodPut, BenutzerrolleRequest{UserID: "gibtsnicht", Rolle: RolleEltern}, http.StatusNotFound, RolleEltern, ""},
+		{"Station fehlt", &testAdmin, http.MethodPut, BenutzerrolleRequest{UserID: userID, Rolle: RolleStationshelfer}, http.StatusBadRequest, RolleEltern, ""},
+		{"Eltern", &testAdmin, http.MethodPut, BenutzerrolleRequest{UserID: userID, Rolle: RolleEltern}, http.StatusCreated, RolleEltern, ""},
+		{"Stationshelfer", &testAdmin, http.MethodPut, BenutzerrolleRequest{UserID: userID, Rolle: RolleStationshelfer, StationID: station.ObjectID()}, http.StatusOK, RolleStationshelfer, station.ObjectID()},
+		{"zurück zu Eltern", &testAdmin, http.MethodPut, BenutzerrolleRequest{UserID: userID, Rolle: RolleEltern}, http.StatusOK, RolleEltern, ""},
+		{"entziehen", &testAdmin, http.MethodDelete, BenutzerrolleRequest{UserID: userID}, http.StatusOK, RolleEltern, ""},
+		{"erneut entziehen", &testAdmin, http.MethodDelete, BenutzerrolleRequest{UserID: userID}, http.StatusNotFound, RolleEltern, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.BenutzerrolleRouter, tt.method, tt.id, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			rolle, station, err := rolleVon()
+			if err != nil || rolle != tt.wantRolle || station != tt.wantStation {
+				t.Errorf("Rolle %s/%q (%v), want %s/%q", rolle, station, err, tt.wantRolle, tt.wantStation)
+			}
+		})
+	}
+
+	// PUT ersetzt die Rolle, DELETE entfernt sie – nichts bleibt übrig
+	out, err := mem.Query(ctx, "Benutzerrolle", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 0 {
+		t.Errorf("%d Benutzerrollen übrig", len(out.Results))
+	}
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  /benutzerrolle – Rollen der Parse-Benutzer (nur admin)  ======
+//
+// Rollen stehen nicht am _User (dort für den Benutzer selbst schreibbar),
+// sondern in der Klasse "Benutzerrolle", die nur der Server mit dem
+// Master-Key erreicht (siehe auth.go und schema.go).
+//
+//	GET    /benutzerrolle[?userId=…]                      alle bzw. eine Rolle
+//	PUT    {"userId", "rolle", "riegeId"?, "stationId"?}  Rolle setzen
+//	DELETE {"userId"}                                     Rolle entziehen (→ eltern)
+
+type BenutzerrolleRequest struct {
+	UserID    string `json:"userId"`
+	Rolle     Rolle  `json:"rolle,omitempty"`
+	RiegeID   string `json:"riegeId,omitempty"`
+	StationID string `json:"stationId,omitempty"`
+}
+
+// Lock-Key je Benutzer, damit keine zwei Rollen entstehen
+func benutzerrolleLockKey(userID string) string {
+	return "Benutzerrolle|" + userID
+}
+
+func (h *KindHandler) BenutzerrolleRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, PUT, DELETE, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := parse.NewQuery()
+		if userID := r.URL.Query().Get("userId"); userID != "" {
+			query.PointerTo("userID", "_User", userID)
+		}
+		rollen, err := parse.QueryAll(r.Context(), h.Parse, "Benutzerrolle", query)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": rollen,
+		})
+	case http.MethodPut:
+		h.setzeBenutzerrolle(w, r, id)
+	case http.MethodDelete:
+		h.entzieheBenutzerrolle(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== PUT – Rolle setzen (anlegen oder ersetzen) =====
+func (h *KindHandler) setzeBenutzerrolle(w http.ResponseWriter, r *http.Request, id *Identitaet) {
+	var req BenutzerrolleRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ---- Validierung (wie POST /token) ----
+	if req.UserID == "" || !req.Rolle.gueltig() {
+		http.Error(w, "userId und gültige rolle erforderlich", http.StatusBadRequest)
+		return
+	}
+	if (req.Rolle == RolleRiegenfuehrer) != (req.RiegeID != "") {
+		http.Error(w, "riegeId genau für Riegenführer erforderlich", http.StatusBadRequest)
+		return
+	}
+	if (req.Rolle == RolleStationshelfer) != (req.StationID != "") {
+		http.Error(w, "stationId genau für Stationshelfer erforderlich", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Bezüge prüfen ----
+	bezuege := []struct{ klasse, objectID, fehlt string }{
+		{"_User", req.UserID, "Benutzer nicht gefunden"},
+		{"Riege", req.RiegeID, "Riege nicht gefunden"},
+		{"Station", req.StationID, "Station nicht gefunden"},
+	}
+	for _, b := range bezuege {
+		if b.objectID == "" {
+			continue
+		}
+		if _, err := h.Parse.Get(r.Context(), b.klasse, b.objectID); parse.IsNotFound(err) {
+			http.Error(w, b.fehlt, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+	}
+
+	unlock, ok := h.lockAll(benutzerrolleLockKey(req.UserID))
+	if !ok {
+		http.Error(w, "Rolle wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	alt, err := h.benutzerrolle(r.Context(), req.UserID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	neu := strukturen.Benutzerrolle{
+		UserID: strukturen.NewParsePointer("_User", req.UserID),
+		Rolle:  string(req.Rolle),
+	}
+	if req.RiegeID != "" {
+		neu.RiegeID = strukturen.NewParsePointer("Riege", req.RiegeID)
+	}
+	if req.StationID != "" {
+		neu.StationsID = strukturen.NewParsePointer("Station", req.StationID)
+	}
+
+	var objectID string
+	status := http.StatusOK
+	if alt == nil {
+		out, err := h.Parse.Create(r.Context(), "Benutzerrolle", neu)
+		if err != nil {
+			http.Error(w, "Speichern fehlgeschlagen", http.StatusBadGateway)
+			return
+		}
+		objectID = out.ObjectID()
+		status = http.StatusCreated
+	} else {
+		objectID = alt.ObjectID()
+		update := map[string]any{
+			"rolle":      neu.Rolle,
+			"riegeID":    map[string]any{"__op": "Delete"},
+			"stationsID": map[string]any{"__op": "Delete"},
+		}
+		if neu.RiegeID != nil {
+			update["riegeID"] = neu.RiegeID
+		}
+		if neu.StationsID != nil {
+			update["stationsID"] = neu.StationsID
+		}
+		if _, err := h.Parse.Update(r.Context(), "Benutzerrolle", objectID, update, nil); err != nil {
+			http.Error(w, "Speichern fehlgeschlagen", http.StatusBadGateway)
+			return
+		}
+	}
+	h.audit(r, id, "rolle", "Benutzerrolle", objectID, alt, neu, 0)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  "Rolle gespeichert",
+		"objectId": objectID,
+		"userId":   req.UserID,
+		"rolle":    req.Rolle,
+	})
+}
+
+// ===== DELETE – Rolle entziehen =====
+func (h *KindHandler) entzieheBenutzerrolle(w http.ResponseWriter, r *http.Request, id *Identitaet) {
+	var req BenutzerrolleRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		http.Error(w, "userId fehlt", http.StatusBadRequest)
+		return
+	}
+
+	unlock, ok := h.lockAll(benutzerrolleLockKey(req.UserID))
+	if !ok {
+		http.Error(w, "Rolle wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	alt, err := h.benutzerrolle(r.Context(), req.UserID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if alt == nil {
+		http.Error(w, "Benutzer hat keine Rolle", http.StatusNotFound)
+		return
+	}
+	if err := h.Parse.Delete(r.Context(), "Benutzerrolle", alt.ObjectID()); err != nil && !parse.IsNotFound(err) {
+		http.Error(w, "Löschen fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "loeschen", "Benutzerrolle", alt.ObjectID(), alt, nil, 0)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Rolle entzogen",
+		"userId":  req.UserID,
+	})
+}
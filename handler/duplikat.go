@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"sporttag/namen"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Duplikatprüfung bei der Anmeldung  ======
+//
+// Geprüft wird beim Anlegen und beim Umbenennen (PUT /kind).
+// Verglichen wird mit allen angemeldeten (nicht abgemeldeten) Kindern
+// gleichen Jahrgangs und Geschlechts:
+//   - Vor- und Nachname nach Normalisierung gleich ("max " = "Max",
+//     "Hoeferlin" = "Höferlin") → sicher dasselbe Kind, 409
+//   - beide Namen ähnlich (Kölner Phonetik, Tippfehler) → wahrscheinlich
+//     dasselbe Kind: 428 mit Warnung; der Client kann die Anmeldung mit
+//     "duplikatBestaetigt": true erneut schicken.
+//
+// Ein abgemeldetes Kind wird bei erneuter Anmeldung neu angelegt; sein
+// Kassenbuch (Erstattung) bleibt beim alten Datensatz.
+
+// Status der Warnung: ohne Bestätigung wird nichts gespeichert
+const statusDuplikatWarnung = http.StatusPreconditionRequired
+
+// Ähnliche, bereits angemeldete Kinder eines neuen Kindes
+type duplikatWarnung struct {
+	Kind     strukturen.Kind  `json:"kind"`
+	Aehnlich []map[string]any `json:"aehnlich"`
+}
+
+// Sucht Kinder, die k entsprechen (gleich) oder ihm ähneln (aehnlich);
+// ausserObjectID (≠ "") ist das umbenannte Kind selbst
+func (h *KindHandler) kindDuplikate(ctx context.Context, k strukturen.Kind, ausserObjectID string) (gleich, aehnlich []parse.Object, err error) {
+	query := parse.NewQuery().
+		EqualTo("jahrgang", k.Jahrgang).
+		EqualTo("geschlecht", k.Geschlecht).
+		NotEqualTo("abgemeldet", true)
+	kandidaten, err := parse.QueryAll(ctx, h.Parse, "Kind", query)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range kandidaten {
+		if c.ObjectID() == ausserObjectID {
+			continue
+		}
+		vorName, nachName := c.String("vorName"), c.String("nachName")
+		switch {
+		case namen.Gleich(vorName, k.VorName) && namen.Gleich(nachName, k.NachName):
+			gleich = append(gleich, c)
+		case namen.Aehnlich(vorName, k.VorName) && namen.Aehnlich(nachName, k.NachName):
+			aehnlich = append(aehnlich, c)
+		}
+	}
+	return gleich, aehnlich, nil
+}
+
+// Prüft alle neuen (bzw. umbenannten) Kinder; schreibt bei Fund die
+// Antwort selbst (409 bzw. Warnung) und liefert false.
+func (h *KindHandler) pruefeDuplikate(w http.ResponseWriter, r *http.Request, id *Identitaet, kinder []strukturen.Kind, bestaetigt bool, ausserObjectID string) bool {
+	var warnungen []duplikatWarnung
+	for _, k := range kinder {
+		gleich, aehnlich, err := h.kindDuplikate(r.Context(), k, ausserObjectID)
+		if err != nil {
+			http.Error(w, "Duplikatprüfung fehlgeschlagen", http.StatusInternalServerError)
+			return false
+		}
+		if len(gleich) > 0 {
+			http.Error(w, "Kind existiert bereits: "+k.VorName+" "+k.NachName, http.StatusConflict)
+			return false
+		}
+		if len(aehnlich) == 0 || bestaetigt {
+			continue
+		}
+
+		warnung := duplikatWarnung{Kind: k}
+		for _, c := range aehnlich {
+			// Namen fremder Kinder bekommen Eltern nicht zu sehen
+			if !id.darfKind(c) {
+				warnung.Aehnlich = append(warnung.Aehnlich, map[string]any{"anderesKonto": true})
+				continue
+			}
+			warnung.Aehnlich = append(warnung.Aehnlich, map[string]any{
+				"objectId":   c.ObjectID(),
+				"vorName":    c.String("vorName"),
+				"nachName":   c.String("nachName"),
+				"jahrgang":   c.Int("jahrgang"),
+				"geschlecht": c.String("geschlecht"),
+			})
+		}
+		warnungen = append(warnungen, warnung)
+	}
+	if len(warnungen) == 0 {
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusDuplikatWarnung)
+	json.NewEncoder(w).Encode(map[string]any{
+		"warnung":    "moeglichesDuplikat",
+		"message":    "Möglicherweise bereits angemeldet; zum Speichern mit duplikatBestaetigt=true erneut senden",
+		"kandidaten": warnungen,
+	})
+	return false
+}
+
+// Namen zum Speichern bereinigen (Leerraum, NFC)
+func bereinigeKind(k strukturen.Kind) strukturen.Kind {
+	k.VorName = namen.Bereinigen(k.VorName)
+	k.NachName = namen.Bereinigen(k.NachName)
+	return k
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sporttag/ereignisse"
+)
+
+// Kommentarzeile an wartende Clients, damit Proxys die Verbindung offen halten
+const ereignisHerzschlag = 20 * time.Second
+
+// ===== GET /ereignisse =====
+// Live-Ereignisse als Server-Sent Events (oder WebSocket, falls der Client
+// ein Upgrade anfragt).
+//
+// Filter: ?riegeObjectId=<id> &stationObjectId=<id> &typ=resultat,riegeBeendet
+// Fortsetzen: Header Last-Event-ID (SSE) bzw. ?lastEventId=<id> (WebSocket)
+// Anmeldung: wie überall oder ?token=<lokales Token>, da EventSource und
+// WebSocket im Browser keine eigenen Header senden können.
+func (h *KindHandler) EreignisseRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+		}
+	}()
+
+	q := r.URL.Query()
+	if token := q.Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if _, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleStationshelfer, RolleAdmin); !ok {
+		return
+	}
+	if h.Ereignisse == nil {
+		http.Error(w, "Live-Ereignisse nicht konfiguriert", http.StatusNotFound)
+		return
+	}
+
+	// ---- Filter ----
+	filter := ereignisse.Filter{
+		RiegeID:   q.Get("riegeObjectId"),
+		StationID: q.Get("stationObjectId"),
+	}
+	if typen := q.Get("typ"); typen != "" {
+		for _, t := range strings.Split(typen, ",") {
+			typ := ereignisse.Typ(strings.TrimSpace(t))
+			if !typ.Gueltig() {
+				http.Error(w, "Unbekannter typ: "+string(typ), http.StatusBadRequest)
+				return
+			}
+			filter.Typen = append(filter.Typen, typ)
+		}
+	}
+
+	if istWebSocket(r) {
+		h.ereignisseWebSocket(w, r, filter, q.Get("lastEventId"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming nicht unterstützt", http.StatusInternalServerError)
+		return
+	}
+
+	letzteID := r.Header.Get("Last-Event-ID")
+	if letzteID == "" {
+		letzteID = q.Get("lastEventId")
+	}
+	abo, verpasst, vollstaendig := h.Ereignisse.Abonnieren(filter, letzteID)
+	defer abo.Abmelden()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Wartezeit des Browsers vor dem automatischen Wiederverbinden
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// Lücke nicht mehr im Puffer → Client muss den Stand neu laden
+	if !vollstaendig {
+		fmt.Fprint(w, "event: reset\ndata: {\"grund\":\"Ereignisse seit Last-Event-ID nicht mehr verfügbar\"}\n\n")
+	}
+	for _, e := range verpasst {
+		if err := schreibeSSE(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	herzschlag := time.NewTicker(ereignisHerzschlag)
+	defer herzschlag.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-abo.C:
+			if !ok {
+				// zu langsam – der Client verbindet sich mit Last-Event-ID neu
+				return
+			}
+			if err := schreibeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-herzschlag.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func schreibeSSE(w http.ResponseWriter, e ereignisse.Ereignis) error {
+	daten, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Typ, daten)
+	return err
+}
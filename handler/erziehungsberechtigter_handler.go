@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// Höchstzahl Kinder je Anmeldung (eine Batch-Anfrage)
+const maxKinderJeAnmeldung = 10
+
+// AnmeldungRequest für POST /anmeldung: ein Erziehungsberechtigter
+// (neu oder bestehend) und mehrere Kinder in einer Anfrage
+type AnmeldungRequest struct {
+	Erziehungsberechtigter         *strukturen.Erziehungsberechtigter `json:"erziehungsberechtigter,omitempty"`
+	ErziehungsberechtigterObjectID string                             `json:"erziehungsberechtigterObjectId,omitempty"`
+	Kinder                         []strukturen.Kind                  `json:"kinder"`
+	// Warnung "moeglichesDuplikat" für alle Kinder bestätigt (siehe duplikat.go)
+	DuplikatBestaetigt bool `json:"duplikatBestaetigt,omitempty"`
+}
+
+var telefonnummer = regexp.MustCompile(`^\+?[0-9][0-9 ()/-]{4,}$`)
+
+// Prüft Pflichtfelder und Format der Kontaktdaten
+func pruefeErziehungsberechtigter(e strukturen.Erziehungsberechtigter) error {
+	if strings.TrimSpace(e.VorName) == "" || strings.TrimSpace(e.NachName) == "" {
+		return errors.New("vorName und nachName des Erziehungsberechtigten erforderlich")
+	}
+	if !telefonnummer.MatchString(strings.TrimSpace(e.Telefon)) {
+		return errors.New("telefon fehlt oder ungültig")
+	}
+	if e.Email != "" {
+		if a, err := mail.ParseAddress(e.Email); err != nil || a.Address != e.Email {
+			return errors.New("email ungültig")
+		}
+	}
+	if e.NotfallTelefon != "" && !telefonnummer.MatchString(strings.TrimSpace(e.NotfallTelefon)) {
+		return errors.New("notfallTelefon ungültig")
+	}
+	if e.NotfallName != "" && e.NotfallTelefon == "" {
+		return errors.New("notfallTelefon für den Notfallkontakt erforderlich")
+	}
+	return nil
+}
+
+// objectId des Erziehungsberechtigten eines Kindes, "" = keiner
+func erziehungsberechtigterVon(kind parse.Object) string {
+	var k struct {
+		EB *strukturen.ParsePointer `json:"erziehungsberechtigterID"`
+	}
+	if err := kind.Decode(&k); err != nil || k.EB == nil {
+		return ""
+	}
+	return k.EB.ObjectID
+}
+
+// Lädt einen bestehenden Erziehungsberechtigten und prüft, ob die
+// Identität Kinder mit ihm verknüpfen darf (Eltern nur den eigenen).
+// Schreibt bei Fehlschlag die Antwort selbst.
+func (h *KindHandler) eigenerErziehungsberechtigter(w http.ResponseWriter, r *http.Request, id *Identitaet, objectID string) bool {
+	eb, err := h.Parse.Get(r.Context(), "Erziehungsberechtigter", objectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Erziehungsberechtigter nicht gefunden", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return false
+	}
+	if id.Rolle != RolleAdmin && (id.UserID == "" || eb.String("elternID") != id.UserID) {
+		http.Error(w, "Keine Berechtigung für diesen Erziehungsberechtigten", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Alle Kinder, die mit dem Kind eine Familie bilden können:
+// gleicher Erziehungsberechtigter, sonst gleiches Eltern-Konto. Abgemeldete
+// sind enthalten, zählen für die Gebühr aber nicht (gebuehrenFuer).
+func (h *KindHandler) familieVon(ctx context.Context, kind parse.Object) ([]parse.Object, error) {
+	query := parse.NewQuery()
+	switch {
+	case erziehungsberechtigterVon(kind) != "":
+		query.PointerTo("erziehungsberechtigterID", "Erziehungsberechtigter", erziehungsberechtigterVon(kind))
+	case kind.String("elternID") != "":
+		query.EqualTo("elternID", kind.String("elternID"))
+	default:
+		return []parse.Object{kind}, nil
+	}
+	return parse.QueryAll(ctx, h.Parse, "Kind", query)
+}
+
+// ===== POST /anmeldung =====
+// Meldet mehrere Kinder mit ihrem Erziehungsberechtigten an.
+// Entweder alle Kinder werden angelegt oder keines.
+func (h *KindHandler) AnmeldungRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req AnmeldungRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ---- Validierung ----
+	if (req.Erziehungsberechtigter == nil) == (req.ErziehungsberechtigterObjectID == "") {
+		http.Error(w, "genau einer von erziehungsberechtigter oder erziehungsberechtigterObjectId erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.Erziehungsberechtigter != nil {
+		if err := pruefeErziehungsberechtigter(*req.Erziehungsberechtigter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Kinder) == 0 || len(req.Kinder) > maxKinderJeAnmeldung {
+		http.Error(w, "1 bis 10 Kinder je Anmeldung", http.StatusBadRequest)
+		return
+	}
+	keys := make([]string, 0, len(req.Kinder))
+	for i := range req.Kinder {
+		req.Kinder[i] = bereinigeKind(req.Kinder[i])
+		k := req.Kinder[i]
+		if k.VorName == "" || k.NachName == "" || k.Geschlecht == "" || k.Jahrgang == 0 {
+			http.Error(w, fmt.Sprintf("Pflichtfelder fehlen bei Kind %d", i+1), http.StatusBadRequest)
+			return
+		}
+		key := kindBusinessKey(k)
+		for _, vorher := range keys {
+			if vorher == key {
+				http.Error(w, fmt.Sprintf("Kind %d ist doppelt angegeben", i+1), http.StatusBadRequest)
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	// ---- Business-Key Sperren aller Kinder ----
+	unlock, ok := h.lockAll(keys...)
+	if !ok {
+		http.Error(w, "Kind wird bereits erfasst", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	// ---- Deadline prüfen (Superuser darf auch danach) ----
+	override, ok := h.checkDeadline(w, id)
+	if !ok {
+		return
+	}
+
+	// ---- Duplikatprüfung ----
+	if !h.pruefeDuplikate(w, r, id, req.Kinder, req.DuplikatBestaetigt, "") {
+		return
+	}
+
+	// ---- Erziehungsberechtigter ----
+	ebID := req.ErziehungsberechtigterObjectID
+	ebNeu := false
+	var eb strukturen.Erziehungsberechtigter
+	if ebID != "" {
+		if !h.eigenerErziehungsberechtigter(w, r, id, ebID) {
+			return
+		}
+	} else {
+		eb = *req.Erziehungsberechtigter
+		eb.ElternID = ""
+		if id.Rolle == RolleEltern {
+			eb.ElternID = id.UserID
+		}
+		out, err := h.Parse.Create(r.Context(), "Erziehungsberechtigter", eb)
+		if err != nil {
+			http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+			return
+		}
+		ebID = out.ObjectID()
+		ebNeu = true
+	}
+
+	// ---- Kinder anlegen (ein Batch) ----
+	payloads := make([]map[string]any, 0, len(req.Kinder))
+	ops := make([]parse.BatchOp, 0, len(req.Kinder))
+	for _, k := range req.Kinder {
+		payload := map[string]any{
+			"vorName":                  k.VorName,
+			"nachName":                 k.NachName,
+			"jahrgang":                 k.Jahrgang,
+			"geschlecht":               k.Geschlecht,
+			"bezahlt":                  false,
+			"version":                  1,
+			"erziehungsberechtigterID": strukturen.NewParsePointer("Erziehungsberechtigter", ebID),
+		}
+		// Eltern werden als Besitzer eingetragen
+		if id.Rolle == RolleEltern {
+			payload["elternID"] = id.UserID
+		}
+		payloads = append(payloads, payload)
+		ops = append(ops, parse.BatchOp{Method: http.MethodPost, ClassName: "Kind", Body: payload})
+	}
+	ids, err := h.batchAnlegen(r.Context(), ops)
+	if err != nil || len(ids) != len(ops) {
+		// Parse-Batches sind nicht transaktional → Kompensation
+		h.rueckgaengig(r.Context(), "Kind", ids)
+		if ebNeu {
+			h.rueckgaengig(r.Context(), "Erziehungsberechtigter", []string{ebID})
+		}
+		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+
+	// ---- Protokoll, Ereignisse, Gebühren ----
+	if ebNeu {
+		h.audit(r, id, "anlegen", "Erziehungsberechtigter", ebID, nil, eb, 0)
+	}
+	kinder := make([]map[string]any, 0, len(ids))
+	for i, objectID := range ids {
+		k := req.Kinder[i]
+		h.audit(r, id, "anlegen", "Kind", objectID, nil, payloads[i], 1)
+		if override != "" {
+			h.logOverride(r, override, "registrieren", "Kind", objectID)
+		}
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+			"aktion":     "angemeldet",
+			"objectId":   objectID,
+			"vorName":    k.VorName,
+			"nachName":   k.NachName,
+			"jahrgang":   k.Jahrgang,
+			"geschlecht": k.Geschlecht,
+			"version":    1,
+		})
+		payloads[i]["objectId"] = objectID
+		kinder = append(kinder, map[string]any{
+			"objectId": objectID,
+			"vorName":  k.VorName,
+			"nachName": k.NachName,
+		})
+	}
+
+	antwort := map[string]any{
+		"message":                        "Anmeldung erfolgreich gespeichert",
+		"erziehungsberechtigterObjectId": ebID,
+		"kinder":                         kinder,
+	}
+	betraege := map[string]int{}
+	if h.Gebuehren.Aktiv() {
+		familie, err := h.familieVon(r.Context(), parse.Object(payloads[0]))
+		if err != nil {
+			// Kinder sind gespeichert; die Gebühren stehen in GET /gebuehren
+			log.Println("Gebühr:", err)
+		} else {
+			berechnet := h.gebuehrenFuer(familie)
+			summe := 0
+			for i, objectID := range ids {
+				g := berechnet[objectID]
+				kinder[i]["gebuehr"] = g
+				betraege[objectID] = g.Betrag
+				summe += g.Betrag
+			}
+			antwort["gebuehr"] = summe
+		}
+	}
+
+	// ---- eine Bestätigung für alle Kinder ----
+	angelegt := make([]parse.Object, 0, len(payloads))
+	for _, p := range payloads {
+		angelegt = append(angelegt, parse.Object(p))
+	}
+	h.mailBestaetigung(r.Context(), angelegt, betraege)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(antwort)
+}
+
+// ===== GET /erziehungsberechtigter =====
+// Kontaktdaten nur für admin und Riegenführer.
+//
+//	?kindObjectId=<id>   Erziehungsberechtigter eines Kindes
+//	?riegeObjectId=<id>  Erziehungsberechtigte aller Kinder einer Riege
+//	?objectId=<id>       ein Erziehungsberechtigter (nur admin)
+//	ohne Parameter       alle Erziehungsberechtigten (nur admin)
+//
+// Riegenführer sehen nur die Kinder ihrer eigenen Riege.
+func (h *KindHandler) ErziehungsberechtigterRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var kinder []parse.Object
+
+	switch {
+	case q.Get("kindObjectId") != "":
+		kindObjectID := q.Get("kindObjectId")
+		if id.Rolle == RolleRiegenfuehrer {
+			riege, err := h.riegeVonKind(r.Context(), kindObjectID)
+			if err != nil {
+				http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+				return
+			}
+			if riege == nil || !id.darfRiege(riege.ObjectID()) {
+				http.Error(w, "Keine Berechtigung für dieses Kind", http.StatusForbidden)
+				return
+			}
+		}
+		kind, err := h.Parse.Get(r.Context(), "Kind", kindObjectID, "erziehungsberechtigterID")
+		if parse.IsNotFound(err) {
+			http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		kinder = []parse.Object{kind}
+
+	case q.Get("riegeObjectId") != "":
+		riegeObjectID := q.Get("riegeObjectId")
+		if !id.darfRiege(riegeObjectID) {
+			http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+			return
+		}
+		query := parse.NewQuery().
+			PointerTo("riegenID", "Riege", riegeObjectID).
+			Order("position").
+			Include("kindID.erziehungsberechtigterID")
+		zuordnungen, err := parse.QueryAll(r.Context(), h.Parse, "kinderDerRiege", query)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		for _, z := range zuordnungen {
+			var zd strukturen.KinderDerRiege
+			if err := z.Decode(&zd); err != nil || !zd.KindID.Expanded() {
+				continue
+			}
+			var kind parse.Object
+			if err := zd.KindID.Decode(&kind); err == nil {
+				kinder = append(kinder, kind)
+			}
+		}
+
+	default:
+		if id.Rolle != RolleAdmin {
+			http.Error(w, "kindObjectId oder riegeObjectId erforderlich", http.StatusBadRequest)
+			return
+		}
+		if objectID := q.Get("objectId"); objectID != "" {
+			eb, err := h.Parse.Get(r.Context(), "Erziehungsberechtigter", objectID)
+			if parse.IsNotFound(err) {
+				http.Error(w, "Erziehungsberechtigter nicht gefunden", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(eb)
+			return
+		}
+		alle, err := parse.QueryAll(r.Context(), h.Parse, "Erziehungsberechtigter", parse.NewQuery().Order("nachName", "vorName"))
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"results": alle})
+		return
+	}
+
+	// ---- je Kind: Name und Kontaktdaten ----
+	results := make([]map[string]any, 0, len(kinder))
+	for _, kind := range kinder {
+		var k struct {
+			EB *strukturen.ParsePointer `json:"erziehungsberechtigterID"`
+		}
+		var eb any
+		if err := kind.Decode(&k); err == nil && k.EB.Expanded() {
+			var obj parse.Object
+			if err := k.EB.Decode(&obj); err == nil {
+				delete(obj, "__type")
+				delete(obj, "className")
+				eb = obj
+			}
+		}
+		results = append(results, map[string]any{
+			"kindObjectId":           kind.ObjectID(),
+			"vorName":                kind.String("vorName"),
+			"nachName":               kind.String("nachName"),
+			"erziehungsberechtigter": eb,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
@@ -0,0 +1,492 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"sporttag/ereignisse"
+	"sporttag/gebuehren"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// BefreiungRequest für POST /gebuehren/befreiung
+type BefreiungRequest struct {
+	KindObjectID    string `json:"kindObjectId"`
+	ExpectedVersion int    `json:"expectedVersion"`
+	Befreiung       string `json:"befreiung"` // Grund aus config.json, "" = aufheben
+}
+
+// Zeile der Gebührenübersicht
+type gebuehrZeile struct {
+	KindObjectID string             `json:"kindObjectId"`
+	VorName      string             `json:"vorName"`
+	NachName     string             `json:"nachName"`
+	Jahrgang     int                `json:"jahrgang"`
+	Familie      string             `json:"familie,omitempty"`
+	Gebuehr      int                `json:"gebuehr"`
+	Posten       []gebuehren.Posten `json:"posten"`
+	Bezahlt      int                `json:"bezahlt"` // Saldo des Kassenbuchs
+	Offen        int                `json:"offen"`
+}
+
+// Eingabe der Gebührenberechnung aus einem Kind-Objekt.
+// Gemeinsamer Kontakt ist der Erziehungsberechtigte, sonst das
+// Eltern-Konto, das die Kinder angemeldet hat.
+func gebuehrenKind(k parse.Object) gebuehren.Kind {
+	angemeldet, _ := time.Parse(time.RFC3339, k.String("createdAt"))
+	kontakt := k.String("elternID")
+	if eb := erziehungsberechtigterVon(k); eb != "" {
+		kontakt = "eb:" + eb
+	}
+	return gebuehren.Kind{
+		ObjectID:   k.ObjectID(),
+		NachName:   k.String("nachName"),
+		Kontakt:    kontakt,
+		Angemeldet: angemeldet,
+		Befreiung:  k.String("befreiung"),
+	}
+}
+
+// Gebühr eines Kindes; lädt dazu seine möglichen Geschwister.
+// Das übergebene Kind hat Vorrang vor dem gespeicherten Stand.
+func (h *KindHandler) gebuehrVon(ctx context.Context, kind parse.Object) (gebuehren.Gebuehr, error) {
+	familie := []parse.Object{kind}
+	if h.Gebuehren.Aktiv() {
+		geschwister, err := h.familieVon(ctx, kind)
+		if err != nil {
+			return gebuehren.Gebuehr{}, err
+		}
+		for _, g := range geschwister {
+			if g.ObjectID() != kind.ObjectID() {
+				familie = append(familie, g)
+			}
+		}
+	}
+	return h.gebuehrenFuer(familie)[kind.ObjectID()], nil
+}
+
+// Abgemeldete Kinder schulden nichts und zählen nicht als Geschwister;
+// ihr Saldo bleibt sichtbar (Erstattung).
+func (h *KindHandler) gebuehrenFuer(kinder []parse.Object) map[string]gebuehren.Gebuehr {
+	eingabe := make([]gebuehren.Kind, 0, len(kinder))
+	var abgemeldet []string
+	for _, k := range kinder {
+		if k.Bool("abgemeldet") {
+			abgemeldet = append(abgemeldet, k.ObjectID())
+			continue
+		}
+		eingabe = append(eingabe, gebuehrenKind(k))
+	}
+	berechnet := h.Gebuehren.Berechne(eingabe)
+	for _, objectID := range abgemeldet {
+		berechnet[objectID] = gebuehren.Gebuehr{KindObjectID: objectID, Posten: []gebuehren.Posten{}}
+	}
+	return berechnet
+}
+
+// bezahlt leitet sich aus Saldo und Gebühr ab.
+// Ohne konfigurierte Gebühren genügt jede Zahlung.
+func (h *KindHandler) istBezahlt(saldo int, g gebuehren.Gebuehr) bool {
+	if !h.Gebuehren.Aktiv() {
+		return saldo > 0
+	}
+	return saldo >= g.Betrag
+}
+
+// Leitet bezahlt der Geschwister von kind neu ab, nachdem sich die Familie
+// geändert hat (Abmeldung: die übrigen rücken in der Geschwisterposition
+// auf, ihre Gebühr steigt). Jedes Kind mit Versionsprüfung und unter
+// seinem Business-Key-Lock. Liefert die geänderten und die nicht
+// aktualisierbaren Kinder (Konflikt, Lock, Parse-Fehler).
+func (h *KindHandler) geschwisterBezahltAbleiten(r *http.Request, id *Identitaet, kind parse.Object) (geaendert, fehlgeschlagen []string, err error) {
+	if !h.Gebuehren.Aktiv() {
+		return nil, nil, nil // ohne Gebühren hängt bezahlt nur am eigenen Saldo
+	}
+	ctx := r.Context()
+	familie, err := h.familieVon(ctx, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, 0, len(familie))
+	for _, k := range familie {
+		ids = append(ids, k.ObjectID())
+	}
+	zahlungen, err := h.zahlungenJeKind(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	berechnet := h.gebuehrenFuer(familie)
+
+	for _, g := range familie {
+		if g.ObjectID() == kind.ObjectID() || g.Bool("abgemeldet") {
+			continue
+		}
+		saldo, _ := kassenstand(zahlungen[g.ObjectID()])
+		bezahlt := h.istBezahlt(saldo, berechnet[g.ObjectID()])
+		if bezahlt == g.Bool("bezahlt") {
+			continue
+		}
+
+		var k strukturen.Kind
+		if err := g.Decode(&k); err != nil {
+			fehlgeschlagen = append(fehlgeschlagen, g.ObjectID())
+			continue
+		}
+		unlock, ok := h.lockAll(kindBusinessKey(k))
+		if !ok {
+			fehlgeschlagen = append(fehlgeschlagen, g.ObjectID())
+			continue
+		}
+		_, err := h.updateWithVersion(ctx, "Kind", g.ObjectID(), g.Int("version"), map[string]any{"bezahlt": bezahlt})
+		unlock()
+		if err != nil {
+			log.Printf("bezahlt von %s nicht neu abgeleitet: %v", g.ObjectID(), err)
+			fehlgeschlagen = append(fehlgeschlagen, g.ObjectID())
+			continue
+		}
+		geaendert = append(geaendert, g.ObjectID())
+		h.audit(r, id, "bezahlt", "Kind", g.ObjectID(),
+			map[string]any{"bezahlt": g.Bool("bezahlt")},
+			map[string]any{"bezahlt": bezahlt, "gebuehr": berechnet[g.ObjectID()].Betrag, "saldo": saldo},
+			g.Int("version")+1)
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+			"aktion":   "bezahlt",
+			"objectId": g.ObjectID(),
+			"bezahlt":  bezahlt,
+			"saldo":    saldo,
+			"version":  g.Int("version") + 1,
+		})
+	}
+	return geaendert, fehlgeschlagen, nil
+}
+
+// Übersicht für die Kinder: Gebühr, Saldo, offener Betrag.
+// kinder muss alle Geschwister enthalten, zahlungen alle ihre Buchungen.
+func (h *KindHandler) gebuehrZeilen(kinder []parse.Object, zahlungen map[string][]parse.Object) []gebuehrZeile {
+	berechnet := h.gebuehrenFuer(kinder)
+
+	zeilen := make([]gebuehrZeile, 0, len(kinder))
+	for _, k := range kinder {
+		g := berechnet[k.ObjectID()]
+		saldo, _ := kassenstand(zahlungen[k.ObjectID()])
+		zeilen = append(zeilen, gebuehrZeile{
+			KindObjectID: k.ObjectID(),
+			VorName:      k.String("vorName"),
+			NachName:     k.String("nachName"),
+			Jahrgang:     k.Int("jahrgang"),
+			Familie:      g.Familie,
+			Gebuehr:      g.Betrag,
+			Posten:       g.Posten,
+			Bezahlt:      saldo,
+			Offen:        max(g.Betrag-saldo, 0),
+		})
+	}
+	return zeilen
+}
+
+// Buchungen je Kind; ids == nil → alle Buchungen
+func (h *KindHandler) zahlungenJeKind(ctx context.Context, ids []string) (map[string][]parse.Object, error) {
+	query := parse.NewQuery().Order("zeitpunkt")
+	if ids != nil {
+		query.PointerIn("kindID", "Kind", ids...)
+	}
+	alle, err := parse.QueryAll(ctx, h.Parse, "zahlung", query)
+	if err != nil {
+		return nil, err
+	}
+	jeKind := map[string][]parse.Object{}
+	for _, e := range alle {
+		var z strukturen.Zahlung
+		if err := e.Decode(&z); err != nil || z.KindID == nil {
+			continue
+		}
+		jeKind[z.KindID.ObjectID] = append(jeKind[z.KindID.ObjectID], e)
+	}
+	return jeKind, nil
+}
+
+// ===== GET /gebuehren =====
+// Familienübersicht: Eltern sehen ihre eigenen Kinder,
+// Admins die Familie zu ?elternId=<id> oder ?kindObjectId=<id>.
+func (h *KindHandler) GebuehrenRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	// ---- Kontakt bestimmen ----
+	kontakt := id.UserID
+	if id.Rolle == RolleAdmin {
+		kontakt = r.URL.Query().Get("elternId")
+		if kindObjectID := r.URL.Query().Get("kindObjectId"); kindObjectID != "" {
+			kind, err := h.Parse.Get(r.Context(), "Kind", kindObjectID)
+			if parse.IsNotFound(err) {
+				http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+				return
+			}
+			kinder, err := h.familieVon(r.Context(), kind)
+			if err != nil {
+				http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+				return
+			}
+			h.schreibeFamilien(w, r, kinder)
+			return
+		}
+		if kontakt == "" {
+			http.Error(w, "elternId oder kindObjectId erforderlich", http.StatusBadRequest)
+			return
+		}
+	}
+
+	kinder, err := parse.QueryAll(r.Context(), h.Parse, "Kind", parse.NewQuery().EqualTo("elternID", kontakt))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	h.schreibeFamilien(w, r, kinder)
+}
+
+func (h *KindHandler) schreibeFamilien(w http.ResponseWriter, r *http.Request, kinder []parse.Object) {
+	ids := make([]string, 0, len(kinder))
+	for _, k := range kinder {
+		ids = append(ids, k.ObjectID())
+	}
+	zahlungen, err := h.zahlungenJeKind(r.Context(), ids)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zeilen := h.gebuehrZeilen(kinder, zahlungen)
+
+	type familie struct {
+		Familie string         `json:"familie,omitempty"`
+		Kinder  []gebuehrZeile `json:"kinder"`
+		Gebuehr int            `json:"gebuehr"`
+		Bezahlt int            `json:"bezahlt"`
+		Offen   int            `json:"offen"`
+	}
+	nachFamilie := map[string]*familie{}
+	var reihenfolge []string
+	for _, z := range zeilen {
+		// Kinder ohne Familie werden einzeln aufgeführt
+		schluessel := z.Familie
+		if schluessel == "" {
+			schluessel = "|" + z.KindObjectID
+		}
+		f, ok := nachFamilie[schluessel]
+		if !ok {
+			f = &familie{Familie: z.Familie}
+			nachFamilie[schluessel] = f
+			reihenfolge = append(reihenfolge, schluessel)
+		}
+		f.Kinder = append(f.Kinder, z)
+		f.Gebuehr += z.Gebuehr
+		f.Bezahlt += z.Bezahlt
+		f.Offen += z.Offen
+	}
+	sort.Strings(reihenfolge)
+
+	familien := make([]*familie, 0, len(reihenfolge))
+	offen := 0
+	for _, s := range reihenfolge {
+		familien = append(familien, nachFamilie[s])
+		offen += nachFamilie[s].Offen
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"familien": familien,
+		"offen":    offen,
+	})
+}
+
+// ===== GET /gebuehren/offen =====
+// Alle angemeldeten Kinder mit offenem Betrag (nur admin)
+func (h *KindHandler) OffeneGebuehrenRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	// abgemeldete Kinder schulden nichts (gebuehrenFuer)
+	kinder, err := parse.QueryAll(r.Context(), h.Parse, "Kind", parse.NewQuery().NotEqualTo("abgemeldet", true))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zahlungen, err := h.zahlungenJeKind(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zeilen := h.gebuehrZeilen(kinder, zahlungen)
+
+	offen := []gebuehrZeile{}
+	summe := 0
+	for _, z := range zeilen {
+		// ohne Gebühren gilt als offen, wer gar nichts bezahlt hat
+		if z.Offen > 0 || (!h.Gebuehren.Aktiv() && z.Bezahlt <= 0) {
+			offen = append(offen, z)
+			summe += z.Offen
+		}
+	}
+	sort.Slice(offen, func(i, j int) bool {
+		if offen[i].NachName != offen[j].NachName {
+			return offen[i].NachName < offen[j].NachName
+		}
+		return offen[i].VorName < offen[j].VorName
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": offen,
+		"anzahl":  len(offen),
+		"offen":   summe,
+	})
+}
+
+// ===== POST /gebuehren/befreiung =====
+// Setzt oder entfernt den Befreiungsgrund eines Kindes (nur admin)
+// und leitet bezahlt neu ab.
+func (h *KindHandler) BefreiungRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req BefreiungRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.KindObjectID == "" || req.ExpectedVersion <= 0 {
+		http.Error(w, "kindObjectId und expectedVersion erforderlich", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.Gebuehren.Befreiungen[req.Befreiung]; req.Befreiung != "" && !ok {
+		http.Error(w, "Unbekannter Befreiungsgrund: "+req.Befreiung, http.StatusBadRequest)
+		return
+	}
+
+	kind, err := h.Parse.Get(r.Context(), "Kind", req.KindObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Lock über den Business-Key des Kindes ----
+	var k strukturen.Kind
+	if err := kind.Decode(&k); err != nil {
+		http.Error(w, "Kind ungültig", http.StatusInternalServerError)
+		return
+	}
+	lock := h.lockForKey(kindBusinessKey(k))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Konflikt: Kind wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+
+	// ---- Gebühr mit neuer Befreiung ----
+	vorher := kind.String("befreiung")
+	kind["befreiung"] = req.Befreiung
+	g, err := h.gebuehrVon(r.Context(), kind)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	eintraege, err := h.zahlungenVon(r.Context(), kind.ObjectID())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	saldo, _ := kassenstand(eintraege)
+	bezahlt := h.istBezahlt(saldo, g)
+
+	update := map[string]interface{}{"bezahlt": bezahlt, "befreiung": req.Befreiung}
+	if req.Befreiung == "" {
+		update["befreiung"] = map[string]interface{}{"__op": "Delete"}
+	}
+	out, err := h.updateWithVersion(r.Context(), "Kind", kind.ObjectID(), req.ExpectedVersion, update)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Kind wurde zwischenzeitlich geändert", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "befreiung", "Kind", kind.ObjectID(),
+		map[string]any{"befreiung": vorher, "bezahlt": kind.Bool("bezahlt")},
+		map[string]any{"befreiung": req.Befreiung, "bezahlt": bezahlt},
+		req.ExpectedVersion+1)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Befreiung gespeichert",
+		"gebuehr":    g,
+		"saldo":      saldo,
+		"bezahlt":    bezahlt,
+		"newVersion": req.ExpectedVersion + 1,
+		"updatedAt":  out["updatedAt"],
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"sporttag/gebuehren"
+	"sporttag/strukturen"
+)
+
+// Abgemeldete Kinder schulden nichts, zählen nicht als Geschwister und
+// blockieren keine erneute Anmeldung
+func TestAbgemeldeteKinder(t *testing.T) {
+	h, mem := testHandler(t)
+	h.Gebuehren = gebuehren.Konfiguration{Grundgebuehr: 1000, GeschwisterRabatt: []int{0, 200}}
+	ctx := context.Background()
+	// Anmeldereihenfolge (createdAt) eindeutig, sonst entscheidet die objectId
+	uhr := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	mem.Now = func() time.Time {
+		uhr = uhr.Add(time.Second)
+		return uhr
+	}
+
+	anna := kindRegistrieren(t, h, &testEltern, testKind)
+	ben := kindRegistrieren(t, h, &testEltern, strukturen.Kind{VorName: "Ben", NachName: "Muster", Jahrgang: 2015, Geschlecht: "m"})
+
+	gebuehr := func(objectID string) int {
+		t.Helper()
+		kind, err := mem.Get(ctx, "Kind", objectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := h.gebuehrVon(ctx, kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g.Betrag
+	}
+	if got := gebuehr(ben); got != 800 {
+		t.Fatalf("Ben vor der Abmeldung %d, want 800", got)
+	}
+	w := anfrage(t, h, h.BucheZahlung, http.MethodPost, &testAdmin, ZahlungRequest{
+		KindObjectID: ben, ExpectedVersion: 1, Betrag: 800, Methode: MethodeBar,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Zahlung: %d %s", w.Code, w.Body.String())
+	}
+	w = anfrage(t, h, h.KindRouter, http.MethodDelete, &testEltern, KindAbmeldungRequest{Search: testKind, ExpectedVersion: 1, Grund: "krank"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Abmeldung: %d %s", w.Code, w.Body.String())
+	}
+	// Ben rückt auf, zahlt jetzt die volle Gebühr und ist nicht mehr bezahlt
+	if kind, err := mem.Get(ctx, "Kind", ben); err != nil || kind.Bool("bezahlt") || kind.Int("version") != 3 {
+		t.Errorf("Ben nach der Abmeldung: %v (%v), want bezahlt=false, version 3", kind, err)
+	}
+
+	tests := []struct {
+		name     string
+		objectID string
+		want     int
+	}{
+		{"abgemeldet", anna, 0},
+		{"Geschwister ohne Rabatt", ben, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gebuehr(tt.objectID); got != tt.want {
+				t.Errorf("Gebühr %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	w = anfrage(t, h, h.OffeneGebuehrenRouter, http.MethodGet, &testAdmin, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+	offen, _ := antwort(t, w)["results"].([]any)
+	if len(offen) != 1 || offen[0].(map[string]any)["kindObjectId"] != ben || offen[0].(map[string]any)["offen"] != float64(200) {
+		t.Errorf("offen %v, want nur Ben mit 200", offen)
+	}
+
+	// erneute Anmeldung legt ein neues Kind an
+	if neu := kindRegistrieren(t, h, &testEltern, testKind); neu == anna {
+		t.Error("abgemeldetes Kind wiederverwendet")
+	}
+}
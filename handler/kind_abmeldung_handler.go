@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  DELETE /kind – Abmeldung und endgültiges Löschen  ======
+//
+// Abmeldung (Eltern für eigene Kinder, Admin): das Kind wird mit Grund
+// und Zeitpunkt als abgemeldet markiert, aus seiner Riege genommen (die
+// übrigen Kinder rücken auf) und erscheint in keiner Rangliste mehr.
+// Resultate und Kassenbuch bleiben erhalten.
+//
+// Endgültig löschen ("endgueltig": true, nur Admin): zusätzlich werden
+// die Resultate gelöscht und danach das Kind selbst. Das Kassenbuch muss
+// ausgeglichen sein; seine Einträge bleiben als Historie stehen.
+//
+// Parse kennt keine Transaktionen: zuerst wird das Kind markiert
+// (Versionsprüfung). Scheitert ein späterer Schritt, setzt derselbe
+// Aufruf mit der aktuellen Version die Bereinigung fort.
+
+type KindAbmeldungRequest struct {
+	Search          strukturen.Kind `json:"search"`
+	ExpectedVersion int             `json:"expectedVersion"`
+	Grund           string          `json:"grund"`
+	Endgueltig      bool            `json:"endgueltig,omitempty"`
+}
+
+func (h *KindHandler) AbmeldenKind(w http.ResponseWriter, r *http.Request) {
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- Rolle prüfen: Eltern (eigene Kinder) oder Admin ----
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req KindAbmeldungRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s := req.Search
+	if s.VorName == "" || s.NachName == "" || s.Geschlecht == "" || s.Jahrgang == 0 {
+		http.Error(w, "Pflichtfeld in search fehlt", http.StatusBadRequest)
+		return
+	}
+	if req.ExpectedVersion <= 0 {
+		http.Error(w, "expectedVersion fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+	grund := strings.TrimSpace(req.Grund)
+	if grund == "" {
+		http.Error(w, "grund erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.Endgueltig && id.Rolle != RolleAdmin {
+		http.Error(w, "Endgültig löschen darf nur ein Admin", http.StatusForbidden)
+		return
+	}
+
+	// ---- Deadline prüfen (Superuser darf auch danach) ----
+	override, ok := h.checkDeadline(w, id)
+	if !ok {
+		return
+	}
+
+	// ---- Business-Key Sperre ----
+	unlockKind, ok := h.lockAll(kindBusinessKey(s))
+	if !ok {
+		http.Error(w, "Konflikt: Kind wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlockKind()
+
+	ctx := r.Context()
+	kinder, err := h.findKindBySearch(ctx, s)
+	if err == nil && len(kinder) == 0 {
+		// bereits abgemeldet: mehrere gleiche (nach erneuter Anmeldung)
+		// werden über die erwartete Version unterschieden
+		kinder, err = h.findAbgemeldetBySearch(ctx, s)
+		if len(kinder) > 1 {
+			kinder = slices.DeleteFunc(kinder, func(k parse.Object) bool {
+				return k.Int("version") != req.ExpectedVersion
+			})
+		}
+	}
+	if err != nil {
+		http.Error(w, "Fehler bei der Suche", http.StatusInternalServerError)
+		return
+	}
+	if len(kinder) == 0 {
+		http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if len(kinder) > 1 {
+		http.Error(w, "Dateninkonsistenz: mehrere gleiche Kinder", http.StatusConflict)
+		return
+	}
+	kind := kinder[0]
+	kindID := kind.ObjectID()
+	if !id.darfKind(kind) {
+		http.Error(w, "Keine Berechtigung für dieses Kind", http.StatusForbidden)
+		return
+	}
+	if kind.Int("version") != req.ExpectedVersion {
+		http.Error(w, "Konflikt: Version veraltet", http.StatusConflict)
+		return
+	}
+	var abmeldung strukturen.Abmeldung
+	kind.Decode(&abmeldung)
+
+	// ---- Bezüge des Kindes (für die Sperren) ----
+	zuordnungen, err := h.zuordnungenVonKind(ctx, kindID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	var resultate []parse.Object
+	if req.Endgueltig {
+		resultate, err = parse.QueryAll(ctx, h.Parse, "resultate", parse.NewQuery().PointerTo("kindID", "Kind", kindID))
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+	}
+	keys := []string{zuordnungKindLockKey(kindID)}
+	for _, z := range zuordnungen {
+		keys = append(keys, riegeLockKey(zuordnungRiege(z)))
+	}
+	for _, res := range resultate {
+		var d strukturen.Resultate
+		if err := res.Decode(&d); err == nil && d.StationsID != nil {
+			keys = append(keys, resultateKey(kindID, d.StationsID.ObjectID))
+		}
+	}
+	unlock, ok := h.lockAll(keys...)
+	if !ok {
+		http.Error(w, "Kind wird gerade bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	if !req.Endgueltig && abmeldung.Abgemeldet && len(zuordnungen) == 0 {
+		http.Error(w, "Kind ist bereits abgemeldet", http.StatusConflict)
+		return
+	}
+
+	// ---- Endgültig: Kassenbuch muss ausgeglichen sein ----
+	if req.Endgueltig {
+		zahlungen, err := h.zahlungenVon(ctx, kindID)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		if saldo, _ := kassenstand(zahlungen); saldo != 0 {
+			http.Error(w, fmt.Sprintf("Kassenbuch nicht ausgeglichen (Saldo %d Cent) – zuerst erstatten", saldo), http.StatusConflict)
+			return
+		}
+	}
+
+	// ---- 1. Kind als abgemeldet markieren (Versionsprüfung) ----
+	// bei einem erneuten Aufruf bleiben Grund und Zeitpunkt der ersten
+	// Abmeldung stehen
+	markierung := map[string]any{}
+	if !abmeldung.Abgemeldet {
+		abmeldung = strukturen.Abmeldung{
+			Abgemeldet:    true,
+			AbmeldeGrund:  grund,
+			AbgemeldetAm:  strukturen.NewParseDate(time.Now()),
+			AbgemeldetVon: akteurVon(id),
+		}
+		markierung = map[string]any{
+			"abgemeldet":    true,
+			"abmeldeGrund":  abmeldung.AbmeldeGrund,
+			"abgemeldetAm":  abmeldung.AbgemeldetAm,
+			"abgemeldetVon": abmeldung.AbgemeldetVon,
+		}
+	}
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	out, err := h.updateWithVersion(ctx, "Kind", kindID, req.ExpectedVersion, maps.Clone(markierung))
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Datensatz wurde zwischenzeitlich geändert", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	neueVersion := req.ExpectedVersion + 1
+
+	unvollstaendig := func() {
+		http.Error(w, "Abmeldung unvollständig – Kind ist abgemeldet, bitte mit aktueller Version erneut ausführen", http.StatusBadGateway)
+	}
+
+	// ---- 2. Aus der Riege nehmen, die Riege rückt auf ----
+	for _, z := range zuordnungen {
+		if err := h.ausRiegeNehmen(ctx, z); err != nil {
+			log.Println("Abmeldung:", err)
+			unvollstaendig()
+			return
+		}
+	}
+	entfernt := map[string]any{"zuordnungen": len(zuordnungen)}
+
+	// ---- Geschwister: Gebühr kann steigen, bezahlt neu ableiten ----
+	// Die Abmeldung selbst ist gespeichert; was hier scheitert, steht in
+	// der Antwort und lässt sich mit einer Buchung bzw. erneutem Aufruf nachholen.
+	geschwister := map[string]any{}
+	if geaendert, fehlgeschlagen, err := h.geschwisterBezahltAbleiten(r, id, kind); err != nil {
+		log.Println("Abmeldung (Geschwister):", err)
+		geschwister["fehler"] = "Geschwister konnten nicht geladen werden"
+	} else {
+		if len(geaendert) > 0 {
+			geschwister["bezahltGeaendert"] = geaendert
+		}
+		if len(fehlgeschlagen) > 0 {
+			geschwister["bezahltNichtAktualisiert"] = fehlgeschlagen
+		}
+	}
+
+	if !req.Endgueltig {
+		h.audit(r, id, "abmelden", "Kind", kindID, kind, markierung, neueVersion)
+		if override != "" {
+			h.logOverride(r, override, "abmelden", "Kind", kindID)
+		}
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+			"aktion":   "abgemeldet",
+			"objectId": kindID,
+			"version":  neueVersion,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":      "Kind erfolgreich abgemeldet",
+			"objectId":     kindID,
+			"newVersion":   neueVersion,
+			"updatedAt":    out["updatedAt"],
+			"abmeldeGrund": abmeldung.AbmeldeGrund,
+			"abgemeldetAm": abmeldung.AbgemeldetAm,
+			"entfernt":     entfernt,
+			"geschwister":  geschwister,
+		})
+		return
+	}
+
+	// ---- 3. Endgültig: Resultate und Kind löschen ----
+	var ops []parse.BatchOp
+	for _, res := range resultate {
+		ops = append(ops, parse.BatchOp{Method: http.MethodDelete, ClassName: "resultate", ObjectID: res.ObjectID()})
+	}
+	if err := h.batchAusfuehren(ctx, ops); err != nil {
+		log.Println("Löschen:", err)
+		unvollstaendig()
+		return
+	}
+	entfernt["resultate"] = len(resultate)
+	if err := h.Parse.Delete(ctx, "Kind", kindID); err != nil && !parse.IsNotFound(err) {
+		unvollstaendig()
+		return
+	}
+
+	h.audit(r, id, "loeschen", "Kind", kindID,
+		map[string]any{"kind": kind, "resultate": resultate},
+		map[string]any{"grund": grund, "entfernt": entfernt},
+		neueVersion)
+	if override != "" {
+		h.logOverride(r, override, "loeschen", "Kind", kindID)
+	}
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+		"aktion":   "geloescht",
+		"objectId": kindID,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":     "Kind endgültig gelöscht",
+		"objectId":    kindID,
+		"entfernt":    entfernt,
+		"geschwister": geschwister,
+	})
+}
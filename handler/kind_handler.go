@@ -1,25 +1,51 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strconv"
 	"sync"
 	"time"
 
+	"sporttag/auswertung"
+	"sporttag/ereignisse"
+	"sporttag/gebuehren"
+	"sporttag/mailer"
+	"sporttag/namen"
+	"sporttag/parse"
+	"sporttag/planung"
 	"sporttag/strukturen"
+	"sporttag/urkunde"
+	"sporttag/wertung"
 )
 
 // ===== Handler-Struktur =====
 type KindHandler struct {
-	Deadline       time.Time
-	ParseServerURL string
-	ParseAppID     string
-	ParseJSKey     string
+	Deadline time.Time
+	// Superuser für Änderungen nach der Deadline: Name → Passwort-Hash
+	// (siehe superuser.go)
+	Superuser map[string]string
+	// Schlüssel für lokal ausgestellte Tokens (siehe auth.go)
+	TokenSecret string
+	// Zugriff auf den Parse-Server (REST oder In-Memory)
+	Parse parse.Client
+	// Standardregeln für POST /riegen-bildung
+	RiegenBildung planung.Regeln
+	// Punktetabellen (nil, wenn keine Datei konfiguriert ist)
+	Wertung *wertung.Tabellen
+	// Altersklassen und Tie-Break-Regeln für GET /rangliste
+	Rangliste auswertung.Regeln
+	// Urkunden-Vorlage (nil, wenn keine konfiguriert ist)
+	Urkunden *urkunde.Generator
+	// Startgebühren (Grundgebühr 0 → keine Gebühren)
+	Gebuehren gebuehren.Konfiguration
+	// Live-Ereignisse für GET /ereignisse (nil → keine)
+	Ereignisse *ereignisse.Bus
+	// E-Mail-Versand und Vorlagen (nil → keine E-Mails), siehe mail_handler.go
+	Mailer       mailer.Mailer
+	MailVorlagen *mailer.Vorlagen
+	mailWecker   chan struct{}
 	// Sperrmechanismus für Business-Keys
 	// Business-Key = VorName|NachName|Jahrgang|Geschlecht (Primary Key als Kombination)
 	locks sync.Map // map[string]chan struct{}
@@ -33,20 +59,34 @@ func (h *KindHandler) lockForKey(key string) chan struct{} {
 	return actual.(chan struct{})
 }
 
-// Erzeugt den Business-Key aus Suchkriterien
+// Erzeugt den Business-Key aus Suchkriterien; Namen in Vergleichsform,
+// damit "max " und "Max" dieselbe Sperre verwenden
 func kindBusinessKey(s strukturen.Kind) string {
-	return s.VorName + "|" +
-		s.NachName + "|" +
+	return namen.Normalisieren(s.VorName) + "|" +
+		namen.Normalisieren(s.NachName) + "|" +
 		strconv.Itoa(s.Jahrgang) + "|" +
 		s.Geschlecht
 }
 
+// Abfrage über den Business-Key (alle vier Felder gleich)
+func kindSearchQuery(s strukturen.Kind) *parse.Query {
+	return parse.NewQuery().
+		EqualTo("vorName", s.VorName).
+		EqualTo("nachName", s.NachName).
+		EqualTo("jahrgang", s.Jahrgang).
+		EqualTo("geschlecht", s.Geschlecht)
+}
+
+// Erlaube Anfragen von der Frontend-Domain auf sporttag.b4a.app
+func setCORSHeaders(w http.ResponseWriter, methods string) {
+	w.Header().Set("Access-Control-Allow-Origin", "https://sporttag.b4a.app")
+	w.Header().Set("Access-Control-Allow-Methods", methods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Parse-Session-Token, X-Request-ID")
+}
+
 func (h *KindHandler) KindRouter(w http.ResponseWriter, r *http.Request) {
 	// ---- CORS ----
-	// Erlaube Anfragen von der Frontend-Domain auf sporttag.b4a.app
-	w.Header().Set("Access-Control-Allow-Origin", "https://sporttag.b4a.app")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	setCORSHeaders(w, "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -61,6 +101,8 @@ func (h *KindHandler) KindRouter(w http.ResponseWriter, r *http.Request) {
 		h.RegisterKind(w, r)
 	case http.MethodPut, http.MethodPatch:
 		h.UpdateKindByCriteria(w, r)
+	case http.MethodDelete:
+		h.AbmeldenKind(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -83,12 +125,25 @@ func (h *KindHandler) RegisterKind(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ---- Rolle prüfen: Eltern registrieren eigene Kinder ----
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleAdmin)
+	if !ok {
+		return
+	}
+
 	// ---- JSON-Daten einlesen ----
-	var k strukturen.Kind
-	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+	// optional mit bestehendem Erziehungsberechtigten (siehe POST /anmeldung)
+	var req struct {
+		strukturen.Kind
+		ErziehungsberechtigterObjectID string `json:"erziehungsberechtigterObjectId,omitempty"`
+		// Warnung "moeglichesDuplikat" bestätigt (siehe duplikat.go)
+		DuplikatBestaetigt bool `json:"duplikatBestaetigt,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Ungültige JSON-Daten", http.StatusBadRequest)
 		return
 	}
+	k := bereinigeKind(req.Kind)
 
 	// ---- Business-Key Sperre ----
 	key := kindBusinessKey(k)
@@ -107,40 +162,20 @@ func (h *KindHandler) RegisterKind(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ---- Deadline prüfen ----
-	if time.Now().UTC().After(h.Deadline) {
-		http.Error(w, "Anmeldung geschlossen", http.StatusForbidden)
+	// ---- Deadline prüfen (Superuser darf auch danach) ----
+	override, ok := h.checkDeadline(w, id)
+	if !ok {
 		return
 	}
 
 	// ---- Duplikatprüfung ----
-	query := `{"vorName":"` + k.VorName +
-		`","nachName":"` + k.NachName +
-		`","jahrgang":` + strconv.Itoa(k.Jahrgang) +
-		`,"geschlecht":"` + k.Geschlecht + `"}`
-	req, _ := http.NewRequest(
-		http.MethodGet,
-		h.ParseServerURL+"/classes/Kind?where="+url.QueryEscape(query),
-		nil,
-	)
-	req.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	req.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-	//---- Anfrage an Parse-Server ----
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		http.Error(w, "Duplikatprüfung fehlgeschlagen", http.StatusInternalServerError)
+	// gleich → 409, ähnlich → Warnung, solange nicht bestätigt
+	if !h.pruefeDuplikate(w, r, id, []strukturen.Kind{k}, req.DuplikatBestaetigt, "") {
 		return
 	}
-	defer resp.Body.Close()
-	//---- Prüfen ob bereits ein Eintrag existiert ----
-	var existing struct {
-		Results []any `json:"results"`
-	}
-	//---- Antwort parsen ----
-	json.NewDecoder(resp.Body).Decode(&existing)
-	//---- Wenn Eintrag existiert, Fehler zurückgeben ----
-	if len(existing.Results) > 0 {
-		http.Error(w, "Kind existiert bereits", http.StatusConflict)
+
+	// ---- Erziehungsberechtigter ----
+	if req.ErziehungsberechtigterObjectID != "" && !h.eigenerErziehungsberechtigter(w, r, id, req.ErziehungsberechtigterObjectID) {
 		return
 	}
 
@@ -153,33 +188,55 @@ func (h *KindHandler) RegisterKind(w http.ResponseWriter, r *http.Request) {
 		"bezahlt":    false,
 		"version":    1,
 	}
-	//---- Anfrage an Parse-Server ----
-	body, _ := json.Marshal(payload)
+	// Eltern werden als Besitzer eingetragen
+	if id.Rolle == RolleEltern {
+		payload["elternID"] = id.UserID
+	}
+	if req.ErziehungsberechtigterObjectID != "" {
+		payload["erziehungsberechtigterID"] = strukturen.NewParsePointer("Erziehungsberechtigter", req.ErziehungsberechtigterObjectID)
+	}
 	//---- Neues Kind anlegen ----
-	req, _ = http.NewRequest(
-		http.MethodPost,
-		h.ParseServerURL+"/classes/Kind",
-		bytes.NewBuffer(body),
-	)
-	req.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	req.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-	req.Header.Set("Content-Type", "application/json")
-	//---- Anfrage an Parse-Server ----
-	resp, err = http.DefaultClient.Do(req)
+	out, err := h.Parse.Create(r.Context(), "Kind", payload)
 	if err != nil {
 		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
-	//---- Antwort parsen ----
-	var out map[string]any
-	json.NewDecoder(resp.Body).Decode(&out)
+	h.audit(r, id, "anlegen", "Kind", out.ObjectID(), nil, payload, 1)
+	if override != "" {
+		h.logOverride(r, override, "registrieren", "Kind", out.ObjectID())
+	}
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+		"aktion":     "angemeldet",
+		"objectId":   out.ObjectID(),
+		"vorName":    k.VorName,
+		"nachName":   k.NachName,
+		"jahrgang":   k.Jahrgang,
+		"geschlecht": k.Geschlecht,
+		"version":    1,
+	})
+	//---- Gebühr berechnen ----
+	antwort := map[string]any{
+		"message":  "Kind erfolgreich gespeichert",
+		"objectId": out.ObjectID(),
+	}
+	payload["objectId"] = out.ObjectID()
+	payload["createdAt"] = out["createdAt"]
+	betraege := map[string]int{}
+	if h.Gebuehren.Aktiv() {
+		g, err := h.gebuehrVon(r.Context(), parse.Object(payload))
+		if err != nil {
+			// Kind ist gespeichert; die Gebühr steht in GET /gebuehren
+			log.Println("Gebühr:", err)
+		} else {
+			antwort["gebuehr"] = g
+			betraege[out.ObjectID()] = g.Betrag
+		}
+	}
+	//---- Bestätigung per E-Mail (über den Ausgang) ----
+	h.mailBestaetigung(r.Context(), []parse.Object{payload}, betraege)
 	//---- Erfolg zurückgeben ----
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]any{
-		"message":  "Kind erfolgreich gespeichert",
-		"objectId": out["objectId"],
-	})
+	json.NewEncoder(w).Encode(antwort)
 }
 
 // ======  Get Kinder -- Liste aller Kinder abrufen ======
@@ -190,23 +247,26 @@ func (h *KindHandler) GetKinder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Anfrage an Parse-Server an die Tabelle 'Kind' weiterleiten
-	req, _ := http.NewRequest(
-		http.MethodGet,
-		h.ParseServerURL+"/classes/Kind",
-		nil,
-	)
+	id, ok := h.requireRolle(w, r)
+	if !ok {
+		return
+	}
 
-	req.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	req.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
+	// Eltern sehen nur die eigenen Kinder
+	query := parse.NewQuery()
+	if id.Rolle == RolleEltern {
+		query.EqualTo("elternID", id.UserID)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	// Anfrage an Parse-Server an die Tabelle 'Kind' weiterleiten
+	result, err := h.Parse.Query(r.Context(), "Kind", query)
 	if err != nil {
 		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
 
 	w.Header().Set("Content-Type", "application/json")
-	io.Copy(w, resp.Body)
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": result.Results,
+	})
 }
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Testhilfen  ======
+
+var (
+	testAdmin  = Identitaet{UserID: "admin1", Name: "Admin", Rolle: RolleAdmin}
+	testEltern = Identitaet{UserID: "eltern1", Name: "Eltern", Rolle: RolleEltern}
+	testFremd  = Identitaet{UserID: "eltern2", Name: "Andere Eltern", Rolle: RolleEltern}
+)
+
+// Handler gegen einen leeren In-Memory-Parse, Anmeldefrist offen
+func testHandler(t *testing.T) (*KindHandler, *parse.MemoryClient) {
+	t.Helper()
+	mem := parse.NewMemoryClient()
+	return &KindHandler{
+		Deadline:    time.Now().Add(24 * time.Hour),
+		TokenSecret: "test-secret",
+		Parse:       mem,
+	}, mem
+}
+
+// Führt eine Anfrage mit lokalem Token für id aus (id nil → ohne Anmeldung)
+func anfrage(t *testing.T, h *KindHandler, handler http.HandlerFunc, method string, id *Identitaet, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, "/", bytes.NewReader(b))
+	if id != nil {
+		tid := *id
+		tid.Ablauf = time.Now().Add(time.Hour).Unix()
+		token, err := h.signToken(tid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// Dekodiert die JSON-Antwort
+func antwort(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("Antwort kein JSON: %q", w.Body.String())
+	}
+	return out
+}
+
+// Registriert ein Kind über POST /kind und liefert die objectId
+func kindRegistrieren(t *testing.T, h *KindHandler, id *Identitaet, k strukturen.Kind) string {
+	t.Helper()
+	w := anfrage(t, h, h.KindRouter, http.MethodPost, id, k)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Registrieren: %d %s", w.Code, w.Body.String())
+	}
+	return antwort(t, w)["objectId"].(string)
+}
+
+var testKind = strukturen.Kind{VorName: "Anna", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"}
+
+// ======  POST /kind  ======
+
+func TestRegisterKind(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         *Identitaet
+		vorhanden  bool // testKind ist bereits registriert
+		fristAbgel bool
+		kind       strukturen.Kind
+		wantStatus int
+	}{
+		{"Eltern", &testEltern, false, false, testKind, http.StatusCreated},
+		{"Admin", &testAdmin, false, false, testKind, http.StatusCreated},
+		{"Namen werden bereinigt", &testEltern, false, false,
+			strukturen.Kind{VorName: "  Anna ", NachName: "Muster  ", Jahrgang: 2014, Geschlecht: "w"}, http.StatusCreated},
+		{"nicht angemeldet", nil, false, false, testKind, http.StatusUnauthorized},
+		{"falsche Rolle", &Identitaet{UserID: "s1", Rolle: RolleStationshelfer, StationID: "x"}, false, false, testKind, http.StatusForbidden},
+		{"Pflichtfeld fehlt", &testEltern, false, false, strukturen.Kind{VorName: "Anna", NachName: "Muster", Geschlecht: "w"}, http.StatusBadRequest},
+		{"bereits registriert", &testEltern, true, false, testKind, http.StatusConflict},
+		{"Frist abgelaufen", &testEltern, false, true, testKind, http.StatusForbidden},
+		{"Frist abgelaufen, Admin", &testAdmin, false, true, testKind, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mem := testHandler(t)
+			if tt.vorhanden {
+				kindRegistrieren(t, h, &testAdmin, testKind)
+			}
+			if tt.fristAbgel {
+				h.Deadline = time.Now().Add(-time.Hour)
+			}
+
+			w := anfrage(t, h, h.KindRouter, http.MethodPost, tt.id, tt.kind)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+
+			kind, err := mem.Get(context.Background(), "Kind", antwort(t, w)["objectId"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind.Int("version") != 1 || kind.Bool("bezahlt") {
+				t.Errorf("version/bezahlt: %v", kind)
+			}
+			if kind.String("vorName") != "Anna" || kind.String("nachName") != "Muster" {
+				t.Errorf("Namen nicht bereinigt: %v", kind)
+			}
+			// Eltern werden als Besitzer eingetragen, Admins nicht
+			wantEltern := ""
+			if tt.id.Rolle == RolleEltern {
+				wantEltern = tt.id.UserID
+			}
+			if kind.String("elternID") != wantEltern {
+				t.Errorf("elternID = %q, want %q", kind.String("elternID"), wantEltern)
+			}
+		})
+	}
+}
+
+// Ein abgemeldetes und erneut angemeldetes Kind bleibt bearbeitbar
+func TestRegisterKindNachAbmeldung(t *testing.T) {
+	h, mem := testHandler(t)
+	alt := kindRegistrieren(t, h, &testEltern, testKind)
+	abmelden := func(version int, endgueltig bool) *httptest.ResponseRecorder {
+		return anfrage(t, h, h.KindRouter, http.MethodDelete, &testAdmin, KindAbmeldungRequest{
+			Search: testKind, ExpectedVersion: version, Grund: "krank", Endgueltig: endgueltig,
+		})
+	}
+	if w := abmelden(1, false); w.Code != http.StatusOK {
+		t.Fatalf("Abmeldung: %d %s", w.Code, w.Body.String())
+	}
+	neu := kindRegistrieren(t, h, &testEltern, testKind)
+	altStand, err := mem.Get(context.Background(), "Kind", alt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       any
+		wantStatus int
+	}{
+		{"PATCH", http.MethodPatch, map[string]any{
+			"search": testKind, "expectedVersion": 1,
+			"update": map[string]any{"betrag": 500, "methode": MethodeBar},
+		}, http.StatusCreated},
+		{"PUT", http.MethodPut, map[string]any{
+			"search": testKind, "expectedVersion": 2,
+			"update": map[string]any{"vorName": "Anna", "nachName": "Muster", "jahrgang": 2015, "geschlecht": "w"},
+		}, http.StatusOK},
+		{"PUT zurück", http.MethodPut, map[string]any{
+			"search": strukturen.Kind{VorName: "Anna", NachName: "Muster", Jahrgang: 2015, Geschlecht: "w"}, "expectedVersion": 3,
+			"update": map[string]any{"vorName": "Anna", "nachName": "Muster", "jahrgang": 2014, "geschlecht": "w"},
+		}, http.StatusOK},
+		{"DELETE", http.MethodDelete, KindAbmeldungRequest{Search: testKind, ExpectedVersion: 4, Grund: "umgezogen"}, http.StatusOK},
+		// beide abgemeldet: die Version wählt den alten Datensatz
+		{"DELETE endgültig, alter Datensatz", http.MethodDelete, KindAbmeldungRequest{
+			Search: testKind, ExpectedVersion: altStand.Int("version"), Grund: "umgezogen", Endgueltig: true,
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.KindRouter, tt.method, &testAdmin, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+	if _, err := mem.Get(context.Background(), "Kind", alt); err == nil {
+		t.Error("alter Datensatz nicht gelöscht")
+	}
+	if kind, err := mem.Get(context.Background(), "Kind", neu); err != nil || !kind.Bool("abgemeldet") {
+		t.Errorf("neuer Datensatz: %v %v", kind, err)
+	}
+}
+
+// ======  PUT /kind – Update mit Versionsprüfung  ======
+
+// Umbenennen prüft den neuen Namen wie eine Anmeldung auf Duplikate
+func TestUpdateKindDuplikat(t *testing.T) {
+	h, _ := testHandler(t)
+	kindRegistrieren(t, h, &testEltern, testKind)
+	lena := strukturen.Kind{VorName: "Lena", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"}
+	kindRegistrieren(t, h, &testEltern, lena)
+
+	umbenennen := func(search strukturen.Kind, version int, vorName string, bestaetigt bool) map[string]any {
+		return map[string]any{
+			"search":             search,
+			"expectedVersion":    version,
+			"duplikatBestaetigt": bestaetigt,
+			"update": map[string]any{
+				"vorName": vorName, "nachName": search.NachName,
+				"jahrgang": search.Jahrgang, "geschlecht": search.Geschlecht,
+			},
+		}
+	}
+
+	// Reihenfolge ist wichtig: jeder Schritt baut auf dem vorigen auf
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+	}{
+		{"gleicher Name", umbenennen(lena, 1, " anna", false), http.StatusConflict},
+		{"ähnlicher Name", umbenennen(lena, 1, "Ana", false), statusDuplikatWarnung},
+		{"ähnlicher Name bestätigt", umbenennen(lena, 1, "Ana", true), http.StatusOK},
+		// das Kind selbst ist kein Duplikat
+		{"eigene Schreibweise", umbenennen(testKind, 1, "Annah", true), http.StatusOK},
+		{"search wird bereinigt", umbenennen(strukturen.Kind{VorName: "Annah ", NachName: " Muster", Jahrgang: 2014, Geschlecht: "w"}, 2, "Greta", false), http.StatusOK},
+		{"search mit Leerzeichen", umbenennen(strukturen.Kind{VorName: "Greta ", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"}, 3, "Frieda", false), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.KindRouter, http.MethodPut, &testEltern, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateKindVersion(t *testing.T) {
+	h, mem := testHandler(t)
+	objectID := kindRegistrieren(t, h, &testEltern, testKind)
+
+	update := func(version int, vorName string) map[string]any {
+		return map[string]any{
+			"search":          testKind,
+			"expectedVersion": version,
+			"update": map[string]any{
+				"vorName": vorName, "nachName": testKind.NachName,
+				"jahrgang": testKind.Jahrgang, "geschlecht": testKind.Geschlecht,
+			},
+		}
+	}
+
+	// Reihenfolge ist wichtig: jeder Schritt baut auf dem vorigen auf
+	tests := []struct {
+		name        string
+		id          *Identitaet
+		body        any
+		wantStatus  int
+		wantVersion int // gespeicherte Version danach
+	}{
+		{"fremde Eltern", &testFremd, update(1, "Annika"), http.StatusForbidden, 1},
+		{"ohne Änderung", &testEltern, update(1, "Anna"), http.StatusConflict, 1},
+		{"unbekanntes Feld", &testEltern, map[string]any{
+			"search": testKind, "expectedVersion": 1, "update": map[string]any{"bezahlt": true},
+		}, http.StatusBadRequest, 1},
+		{"ohne Version", &testEltern, update(0, "Annika"), http.StatusBadRequest, 1},
+		{"Version passt", &testEltern, update(1, "Annika"), http.StatusOK, 2},
+		// search zeigt noch auf den alten Namen
+		{"Kind nicht gefunden", &testEltern, update(2, "Annika"), http.StatusNotFound, 2},
+		{"Version veraltet", &testAdmin, map[string]any{
+			"search":          strukturen.Kind{VorName: "Annika", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"},
+			"expectedVersion": 1,
+			"update":          map[string]any{"vorName": "Anni", "nachName": "Muster", "jahrgang": 2014, "geschlecht": "w"},
+		}, http.StatusConflict, 2},
+		{"Admin mit aktueller Version", &testAdmin, map[string]any{
+			"search":          strukturen.Kind{VorName: "Annika", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"},
+			"expectedVersion": 2,
+			"update":          map[string]any{"vorName": "Anni", "nachName": "Muster", "jahrgang": 2014, "geschlecht": "w"},
+		}, http.StatusOK, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.KindRouter, http.MethodPut, tt.id, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				if got := antwort(t, w)["newVersion"]; got != float64(tt.wantVersion) {
+					t.Errorf("newVersion = %v, want %d", got, tt.wantVersion)
+				}
+			}
+			kind, err := mem.Get(context.Background(), "Kind", objectID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind.Int("version") != tt.wantVersion {
+				t.Errorf("gespeicherte version = %d, want %d", kind.Int("version"), tt.wantVersion)
+			}
+		})
+	}
+
+	// Änderungen landen im Audit-Log
+	out, err := mem.Query(context.Background(), "auditLog", parse.NewQuery().EqualTo("aktion", "aendern"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 2 {
+		t.Errorf("%d Audit-Einträge für aendern, want 2", len(out.Results))
+	}
+}
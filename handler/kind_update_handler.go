@@ -2,10 +2,15 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log"
+	"maps"
 	"net/http"
-	"net/url"
+	"sporttag/ereignisse"
+	"sporttag/mailer"
+	"sporttag/namen"
+	"sporttag/parse"
 	"sporttag/strukturen"
 	"strconv"
 )
@@ -28,12 +33,14 @@ type KindUpdateFull struct {
 	NachName   string `json:"nachName"`
 	Jahrgang   int    `json:"jahrgang"`
 	Geschlecht string `json:"geschlecht"`
-	Bezahlt    bool   `json:"bezahlt"`
 }
 
-// PATCH – nur bezahlt=true
+// PATCH – Zahlung ins Kassenbuch buchen (siehe zahlung_handler.go);
+// bezahlt wird daraus abgeleitet und kann nicht direkt gesetzt werden
 type KindUpdatePaid struct {
-	Bezahlt bool `json:"bezahlt"`
+	Betrag    int    `json:"betrag"` // Cent
+	Methode   string `json:"methode"`
+	Bemerkung string `json:"bemerkung,omitempty"`
 }
 
 // Root-Request
@@ -41,6 +48,8 @@ type KindUpdateRequest struct {
 	Search          strukturen.Kind `json:"search"`
 	Update          json.RawMessage `json:"update"`
 	ExpectedVersion int             `json:"expectedVersion"`
+	// PUT: Warnung "moeglichesDuplikat" für den neuen Namen bestätigt (duplikat.go)
+	DuplikatBestaetigt bool `json:"duplikatBestaetigt,omitempty"`
 }
 
 //
@@ -61,13 +70,17 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 		"nachName":   true,
 		"jahrgang":   true,
 		"geschlecht": true,
-		"bezahlt":    true,
+	}
+	if r.Method == http.MethodPatch {
+		allowedUpdateKeys = map[string]bool{
+			"betrag":    true,
+			"methode":   true,
+			"bemerkung": true,
+		}
 	}
 
 	// ---- CORS ----
-	w.Header().Set("Access-Control-Allow-Origin", "https://sporttag.b4a.app")
-	w.Header().Set("Access-Control-Allow-Methods", "PUT, PATCH, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	setCORSHeaders(w, "PUT, PATCH, OPTIONS")
 	//---- OPTIONS ----
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -80,6 +93,17 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// ---- Rolle prüfen: Eltern (eigene Kinder) oder Admin ----
+	// PATCH bucht eine Zahlung ins Kassenbuch, also nur ein Admin
+	rollen := []Rolle{RolleEltern, RolleAdmin}
+	if r.Method == http.MethodPatch {
+		rollen = []Rolle{RolleAdmin}
+	}
+	id, ok := h.requireRolle(w, r, rollen...)
+	if !ok {
+		return
+	}
+
 	// ---- Decode Root ----
 	var req KindUpdateRequest
 	dec := json.NewDecoder(r.Body)
@@ -91,6 +115,8 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 	}
 
 	// ---- Validate Search ----
+	// wie beim Anlegen bereinigt, damit "Anna " das gespeicherte "Anna" findet
+	req.Search = bereinigeKind(req.Search)
 	s := req.Search
 	if s.VorName == "" || s.NachName == "" || s.Geschlecht == "" || s.Jahrgang == 0 {
 		http.Error(w, "Pflichtfeld in search fehlt", http.StatusBadRequest)
@@ -103,6 +129,12 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// ---- Deadline prüfen (Superuser darf auch danach) ----
+	override, ok := h.checkDeadline(w, id)
+	if !ok {
+		return
+	}
+
 	// ---- GLOBALER LOCK für KIND im Such-Request----
 	key := kindBusinessKey(req.Search)
 	lock := h.lockForKey(key)
@@ -121,7 +153,7 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 	}
 
 	// ---- Find Kind ----
-	kinder, err := h.findKindBySearch(s)
+	kinder, err := h.findKindBySearch(r.Context(), s)
 	if err != nil {
 		http.Error(w, "Fehler bei der Suche", http.StatusInternalServerError)
 		return
@@ -138,6 +170,11 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 
 	obj := kinder[0]
 
+	if !id.darfKind(obj) {
+		http.Error(w, "Keine Berechtigung für dieses Kind", http.StatusForbidden)
+		return
+	}
+
 	objectId, ok := obj["objectId"].(string)
 	if !ok {
 		http.Error(w, "objectId fehlt", http.StatusInternalServerError)
@@ -164,7 +201,7 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	// ---- PATCH: bezahlt=true ----
+	// ---- PATCH: Zahlung buchen ----
 	if r.Method == http.MethodPatch {
 
 		var upd KindUpdatePaid
@@ -176,28 +213,23 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		if upd.Bezahlt != true {
-			http.Error(w, "PATCH erlaubt nur bezahlt=true", http.StatusBadRequest)
-			return
+		ok := h.bucheZahlung(w, r, id, obj, req.ExpectedVersion, buchung{
+			art:       ArtZahlung,
+			betrag:    upd.Betrag,
+			methode:   upd.Methode,
+			bemerkung: upd.Bemerkung,
+		})
+		if ok && override != "" {
+			h.logOverride(r, override, "zahlung", "Kind", objectId)
 		}
-
-		if obj["bezahlt"] == true {
-			http.Error(w, "Kind bereits bezahlt", http.StatusConflict)
-			return
-		}
-
-		h.doConditionalUpdateWithVersion(
-			w,
-			objectId,
-			req.ExpectedVersion,
-			map[string]interface{}{
-				"bezahlt": true,
-			},
-		)
 		return
 	}
 
 	// ---- PUT: kompletter Datensatz ----
+	if obj.Bool("abgemeldet") {
+		http.Error(w, "Kind ist abgemeldet", http.StatusConflict)
+		return
+	}
 	var upd KindUpdateFull
 	dec = json.NewDecoder(bytes.NewReader(req.Update))
 	dec.DisallowUnknownFields()
@@ -206,7 +238,9 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Ungültiges PUT-Update", http.StatusBadRequest)
 		return
 	}
-	// alle Attribute, außer 'bezahlt' müssen angegeben sein
+	upd.VorName = namen.Bereinigen(upd.VorName)
+	upd.NachName = namen.Bereinigen(upd.NachName)
+	// alle Attribute müssen angegeben sein (bezahlt kommt aus dem Kassenbuch)
 	if upd.VorName == "" || upd.NachName == "" || upd.Geschlecht == "" || upd.Jahrgang == 0 {
 		http.Error(w, "Pflichtfeld im Update fehlt", http.StatusBadRequest)
 		return
@@ -216,126 +250,157 @@ func (h *KindHandler) UpdateKindByCriteria(w http.ResponseWriter, r *http.Reques
 	if obj["vorName"] == upd.VorName &&
 		obj["nachName"] == upd.NachName &&
 		int(obj["jahrgang"].(float64)) == upd.Jahrgang &&
-		obj["geschlecht"] == upd.Geschlecht &&
-		obj["bezahlt"] == upd.Bezahlt {
+		obj["geschlecht"] == upd.Geschlecht {
 
 		http.Error(w, "Update hätte keine Änderung bewirkt", http.StatusConflict)
 		return
 	}
 
-	h.doConditionalUpdateWithVersion(
+	// ---- neuer Business-Key: sperren und auf Duplikate prüfen ----
+	// (der alte ist oben gesperrt; gleicher Key bei reiner Schreibweise)
+	neuesKind := strukturen.Kind{VorName: upd.VorName, NachName: upd.NachName, Jahrgang: upd.Jahrgang, Geschlecht: upd.Geschlecht}
+	if neuerKey := kindBusinessKey(neuesKind); neuerKey != key {
+		unlockNeu, ok := h.lockAll(neuerKey)
+		if !ok {
+			http.Error(w, "Konflikt: Kind wird bereits bearbeitet", http.StatusConflict)
+			return
+		}
+		defer unlockNeu()
+	}
+	if !h.pruefeDuplikate(w, r, id, []strukturen.Kind{neuesKind}, req.DuplikatBestaetigt, objectId) {
+		return
+	}
+
+	neu := map[string]interface{}{
+		"vorName":    upd.VorName,
+		"nachName":   upd.NachName,
+		"jahrgang":   upd.Jahrgang,
+		"geschlecht": upd.Geschlecht,
+	}
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	ok = h.doConditionalUpdateWithVersion(
 		w,
+		r,
 		objectId,
 		req.ExpectedVersion,
-		map[string]interface{}{
+		maps.Clone(neu),
+	)
+	if ok && override != "" {
+		h.logOverride(r, override, "aktualisieren", "Kind", objectId)
+	}
+	if ok {
+		alt := map[string]any{
+			"vorName":    obj["vorName"],
+			"nachName":   obj["nachName"],
+			"jahrgang":   obj["jahrgang"],
+			"geschlecht": obj["geschlecht"],
+		}
+		h.audit(r, id, "aendern", "Kind", objectId, alt, neu, req.ExpectedVersion+1)
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+			"aktion":     "geaendert",
+			"objectId":   objectId,
 			"vorName":    upd.VorName,
 			"nachName":   upd.NachName,
 			"jahrgang":   upd.Jahrgang,
 			"geschlecht": upd.Geschlecht,
-			"bezahlt":    upd.Bezahlt,
-		},
-	)
+			"version":    req.ExpectedVersion + 1,
+		})
+		h.mailAenderung(r.Context(), obj, upd)
+	}
+}
+
+// Änderungsmitteilung an die Familie: welche Felder von → nach
+func (h *KindHandler) mailAenderung(ctx context.Context, vorher parse.Object, upd KindUpdateFull) {
+	if h.Mailer == nil {
+		return
+	}
+	var aenderungen []mailer.Aenderung
+	vergleiche := func(feld, alt, neu string) {
+		if alt != neu {
+			aenderungen = append(aenderungen, mailer.Aenderung{Feld: feld, Alt: alt, Neu: neu})
+		}
+	}
+	vergleiche("Vorname", vorher.String("vorName"), upd.VorName)
+	vergleiche("Nachname", vorher.String("nachName"), upd.NachName)
+	vergleiche("Jahrgang", strconv.Itoa(vorher.Int("jahrgang")), strconv.Itoa(upd.Jahrgang))
+	vergleiche("Geschlecht", vorher.String("geschlecht"), upd.Geschlecht)
+
+	an, name := h.mailEmpfaenger(ctx, vorher)
+	h.mailEinreihen(ctx, mailer.VorlageAenderung, an, mailer.Daten{
+		Name:        name,
+		Kinder:      []mailer.Kind{{VorName: upd.VorName, NachName: upd.NachName, Jahrgang: upd.Jahrgang}},
+		Aenderungen: aenderungen,
+	})
 }
 
 //
 // ===== Hilfsfunktionen =====
 //
 
-func (h *KindHandler) findKindBySearch(s strukturen.Kind) ([]map[string]interface{}, error) {
-	query := `{"vorName":"` + s.VorName +
-		`","nachName":"` + s.NachName +
-		`","jahrgang":` + strconv.Itoa(s.Jahrgang) +
-		`,"geschlecht":"` + s.Geschlecht + `"}`
-
-	url := h.ParseServerURL + "/classes/Kind?where=" + url.QueryEscape(query)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// Angemeldete Kinder zum Business-Key. Ein abgemeldetes Kind kann erneut
+// angemeldet werden (duplikat.go); der alte Datensatz bleibt dann stehen
+// und darf die Suche nicht mehrdeutig machen.
+func (h *KindHandler) findKindBySearch(ctx context.Context, s strukturen.Kind) ([]parse.Object, error) {
+	out, err := h.Parse.Query(ctx, "Kind", kindSearchQuery(s).NotEqualTo("abgemeldet", true))
 	if err != nil {
 		return nil, err
 	}
+	return out.Results, nil
+}
 
-	req.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	req.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var out struct {
-		Results []map[string]interface{} `json:"results"`
-	}
-	err = json.NewDecoder(resp.Body).Decode(&out)
-	return out.Results, err
+// Abgemeldete Kinder zum Business-Key (Abmeldung fortsetzen, endgültig löschen)
+func (h *KindHandler) findAbgemeldetBySearch(ctx context.Context, s strukturen.Kind) ([]parse.Object, error) {
+	return parse.QueryAll(ctx, h.Parse, "Kind", kindSearchQuery(s).EqualTo("abgemeldet", true))
 }
 
 //
 // ===== Conditional PUT mit Version-Locking =====
 //
 
-func (h *KindHandler) doConditionalUpdateWithVersion(
-	w http.ResponseWriter,
+// Ändert ein Objekt nur, wenn seine version noch expectedVersion ist,
+// und erhöht sie dabei atomar. parse.ErrNotFound = Versionskonflikt.
+// Wird auch für Riege, Station usw. verwendet.
+func (h *KindHandler) updateWithVersion(
+	ctx context.Context,
+	className string,
 	objectId string,
 	expectedVersion int,
 	update map[string]interface{},
-) {
+) (parse.Object, error) {
 	// 🔐 atomare Versionserhöhung
 	update["version"] = map[string]interface{}{
 		"__op":   "Increment",
 		"amount": 1,
 	}
 
-	body, err := json.Marshal(update)
-	if err != nil {
-		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
-		return
-	}
-
 	// ⚠️ ENTSCHEIDEND:
 	// objectId IM PFAD + where NUR für version
-	where := map[string]interface{}{
-		"version": expectedVersion,
-	}
-	whereJSON, _ := json.Marshal(where)
+	where := parse.NewQuery().EqualTo("version", expectedVersion)
 
-	req, err := http.NewRequest(
-		http.MethodPut,
-		h.ParseServerURL+
-			"/classes/Kind/"+objectId+
-			"?where="+url.QueryEscape(string(whereJSON)),
-		bytes.NewBuffer(body),
-	)
-	if err != nil {
-		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
-		return
-	}
-
-	req.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	req.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
-		return
-	}
-	defer resp.Body.Close()
-
-	var out map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		http.Error(w, "Ungültige Parse-Antwort", http.StatusInternalServerError)
-		return
-	}
+	return h.Parse.Update(ctx, className, objectId, update, where.Where())
+}
 
-	// ✅ KEIN updatedAt = KEIN Update
-	if _, ok := out["updatedAt"]; !ok {
+// Schreibt die Antwort selbst; liefert true, wenn das Update durchgeführt wurde
+func (h *KindHandler) doConditionalUpdateWithVersion(
+	w http.ResponseWriter,
+	r *http.Request,
+	objectId string,
+	expectedVersion int,
+	update map[string]interface{},
+) bool {
+	out, err := h.updateWithVersion(r.Context(), "Kind", objectId, expectedVersion, update)
+	if parse.IsNotFound(err) {
+		// ✅ KEIN updatedAt = KEIN Update
 		http.Error(
 			w,
 			"Konflikt: Datensatz wurde zwischenzeitlich geändert",
 			http.StatusConflict,
 		)
-		return
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return false
 	}
 
 	// ✅ echter Erfolg
@@ -345,4 +410,5 @@ func (h *KindHandler) doConditionalUpdateWithVersion(
 		"newVersion": expectedVersion + 1,
 		"updatedAt":  out["updatedAt"],
 	})
+	return true
 }
@@ -0,0 +1,405 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"sort"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  POST /kind/zusammenfuehren – Duplikate zusammenführen (nur admin)  ======
+//
+// Das Duplikat geht im Ziel auf: Riegen-Zuordnung, Resultate und
+// Kassenbuch werden auf das Ziel umgehängt (urspruenglichKindID hält das
+// Duplikat fest), fehlende Angaben (Eltern, Erziehungsberechtigter,
+// Befreiung) übernommen, bezahlt neu abgeleitet und das Duplikat gelöscht.
+//
+// Beide Kinder werden über expectedVersion geprüft. Parse kennt keine
+// Transaktionen: das Duplikat wird zuerst mit "zusammengefuehrtIn"
+// markiert. Scheitert ein späterer Schritt, bleibt die Markierung stehen
+// und derselbe Aufruf (mit der aktuellen Version) setzt die
+// Zusammenführung fort.
+
+type KindVersion struct {
+	ObjectID        string `json:"objectId"`
+	ExpectedVersion int    `json:"expectedVersion"`
+}
+
+type KindZusammenfuehrenRequest struct {
+	Ziel     KindVersion `json:"ziel"`     // bleibt erhalten
+	Duplikat KindVersion `json:"duplikat"` // wird gelöscht
+}
+
+// Felder, die das Ziel vom Duplikat übernimmt, wenn es sie selbst nicht hat
+var zusammenfuehrenFelder = []string{"elternID", "erziehungsberechtigterID", "befreiung"}
+
+func (h *KindHandler) KindZusammenfuehrenRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req KindZusammenfuehrenRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Ziel.ObjectID == "" || req.Duplikat.ObjectID == "" {
+		http.Error(w, "ziel.objectId und duplikat.objectId erforderlich", http.StatusBadRequest)
+		return
+	}
+	if req.Ziel.ObjectID == req.Duplikat.ObjectID {
+		http.Error(w, "Ziel und Duplikat sind dasselbe Kind", http.StatusBadRequest)
+		return
+	}
+	if req.Ziel.ExpectedVersion <= 0 || req.Duplikat.ExpectedVersion <= 0 {
+		http.Error(w, "expectedVersion fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+
+	override, ok := h.checkDeadline(w, id)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	ziel, dup, ok := h.zusammenfuehrenLaden(w, ctx, req)
+	if !ok {
+		return
+	}
+
+	// ---- Bezüge des Duplikats (für die Sperren) ----
+	dupZuordnungen, err := h.zuordnungenVonKind(ctx, dup.ObjectID())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zielZuordnungen, err := h.zuordnungenVonKind(ctx, ziel.ObjectID())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	dupResultate, err := h.resultateVonKind(ctx, dup.ObjectID())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zielResultate, err := h.resultateVonKind(ctx, ziel.ObjectID())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	keys := []string{
+		kindBusinessKey(kindAusObjekt(ziel)),
+		kindBusinessKey(kindAusObjekt(dup)),
+		zuordnungKindLockKey(ziel.ObjectID()),
+		zuordnungKindLockKey(dup.ObjectID()),
+	}
+	for _, z := range append(dupZuordnungen, zielZuordnungen...) {
+		keys = append(keys, riegeLockKey(zuordnungRiege(z)))
+	}
+	for stationID := range dupResultate {
+		keys = append(keys, resultateKey(dup.ObjectID(), stationID), resultateKey(ziel.ObjectID(), stationID))
+	}
+	unlock, ok := h.lockAll(keys...)
+	if !ok {
+		http.Error(w, "Kind wird gerade bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	// ---- Resultate an derselben Station müssen vorher bereinigt werden ----
+	var konflikte []string
+	for stationID := range dupResultate {
+		if _, ok := zielResultate[stationID]; ok {
+			konflikte = append(konflikte, stationID)
+		}
+	}
+	if len(konflikte) > 0 {
+		sort.Strings(konflikte)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":          "Beide Kinder haben Resultate an denselben Stationen – zuerst ein Resultat löschen",
+			"stationObjectIds": konflikte,
+		})
+		return
+	}
+
+	// ---- 1. Duplikat markieren (Versionsprüfung) ----
+	markierung := map[string]any{"zusammengefuehrtIn": strukturen.NewParsePointer("Kind", ziel.ObjectID())}
+	_, err = h.updateWithVersion(ctx, "Kind", dup.ObjectID(), req.Duplikat.ExpectedVersion, markierung)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Duplikat wurde zwischenzeitlich geändert", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+
+	// ---- 2. Ziel ergänzen, bezahlt aus beiden Kassenbüchern ----
+	zielNeu := map[string]any{}
+	for _, feld := range zusammenfuehrenFelder {
+		if _, vorhanden := ziel[feld]; !vorhanden && dup[feld] != nil {
+			zielNeu[feld] = dup[feld]
+		}
+	}
+	bezahlt, err := h.bezahltNachZusammenfuehren(ctx, ziel, dup, zielNeu)
+	if err != nil {
+		h.markierungAufheben(ctx, dup.ObjectID())
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zielNeu["bezahlt"] = bezahlt
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	zielOut, err := h.updateWithVersion(ctx, "Kind", ziel.ObjectID(), req.Ziel.ExpectedVersion, maps.Clone(zielNeu))
+	if err != nil {
+		h.markierungAufheben(ctx, dup.ObjectID())
+		if parse.IsNotFound(err) {
+			http.Error(w, "Konflikt: Ziel wurde zwischenzeitlich geändert", http.StatusConflict)
+		} else {
+			http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		}
+		return
+	}
+
+	// ---- 3. Bezüge umhängen ----
+	unvollstaendig := func() {
+		http.Error(w, "Zusammenführung unvollständig – Duplikat ist markiert, bitte mit aktueller Version erneut ausführen", http.StatusBadGateway)
+	}
+	zielPointer := strukturen.NewParsePointer("Kind", ziel.ObjectID())
+	umhaengen := func(klasse string, o parse.Object) parse.BatchOp {
+		body := map[string]any{"kindID": zielPointer}
+		// ursprüngliches Kind bleibt nachvollziehbar, auch über mehrere Zusammenführungen
+		if o["urspruenglichKindID"] == nil {
+			body["urspruenglichKindID"] = strukturen.NewParsePointer("Kind", dup.ObjectID())
+		}
+		return parse.BatchOp{Method: http.MethodPut, ClassName: klasse, ObjectID: o.ObjectID(), Body: body}
+	}
+	var ops []parse.BatchOp
+	for _, res := range dupResultate {
+		ops = append(ops, umhaengen("resultate", res))
+	}
+	zahlungen, err := h.zahlungenVon(ctx, dup.ObjectID())
+	if err != nil {
+		unvollstaendig()
+		return
+	}
+	for _, z := range zahlungen {
+		ops = append(ops, umhaengen("zahlung", z))
+	}
+	if err := h.batchAusfuehren(ctx, ops); err != nil {
+		log.Println("Zusammenführen:", err)
+		unvollstaendig()
+		return
+	}
+
+	// Riege: hat das Ziel keine, übernimmt es die Zuordnung des Duplikats;
+	// sonst fällt die des Duplikats weg und seine Riege rückt auf
+	for i, z := range dupZuordnungen {
+		if i == 0 && len(zielZuordnungen) == 0 {
+			if _, err := h.Parse.Update(ctx, "kinderDerRiege", z.ObjectID(), map[string]any{"kindID": zielPointer}, nil); err != nil {
+				unvollstaendig()
+				return
+			}
+			continue
+		}
+		if err := h.ausRiegeNehmen(ctx, z); err != nil {
+			unvollstaendig()
+			return
+		}
+	}
+
+	// ---- 4. Duplikat löschen ----
+	if err := h.Parse.Delete(ctx, "Kind", dup.ObjectID()); err != nil && !parse.IsNotFound(err) {
+		unvollstaendig()
+		return
+	}
+
+	// ---- Protokoll, Ereignis, Antwort ----
+	neueVersion := req.Ziel.ExpectedVersion + 1
+	umgehaengt := map[string]any{
+		"resultate":   len(dupResultate),
+		"zahlungen":   len(zahlungen),
+		"zuordnungen": len(dupZuordnungen),
+	}
+	h.audit(r, id, "zusammenfuehren", "Kind", ziel.ObjectID(),
+		map[string]any{"ziel": ziel, "duplikat": dup},
+		map[string]any{"ziel": zielNeu, "umgehaengt": umgehaengt},
+		neueVersion)
+	if override != "" {
+		h.logOverride(r, override, "zusammenfuehren", "Kind", ziel.ObjectID())
+	}
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+		"aktion":           "zusammengefuehrt",
+		"objectId":         ziel.ObjectID(),
+		"duplikatObjectId": dup.ObjectID(),
+		"version":          neueVersion,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Kinder erfolgreich zusammengeführt",
+		"objectId":   ziel.ObjectID(),
+		"newVersion": neueVersion,
+		"updatedAt":  zielOut["updatedAt"],
+		"bezahlt":    bezahlt,
+		"umgehaengt": umgehaengt,
+	})
+}
+
+// Lädt Ziel und Duplikat und prüft die erwarteten Versionen.
+// Schreibt bei Fehlschlag die Antwort selbst.
+func (h *KindHandler) zusammenfuehrenLaden(w http.ResponseWriter, ctx context.Context, req KindZusammenfuehrenRequest) (ziel, dup parse.Object, ok bool) {
+	ziel, err := h.Parse.Get(ctx, "Kind", req.Ziel.ObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Ziel nicht gefunden", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return nil, nil, false
+	}
+	dup, err = h.Parse.Get(ctx, "Kind", req.Duplikat.ObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Duplikat nicht gefunden", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return nil, nil, false
+	}
+
+	if ziel.Int("version") != req.Ziel.ExpectedVersion || dup.Int("version") != req.Duplikat.ExpectedVersion {
+		http.Error(w, "Konflikt: Version veraltet", http.StatusConflict)
+		return nil, nil, false
+	}
+	// ein Duplikat, das schon in ein anderes Kind aufgeht, bleibt dabei
+	var markiert struct {
+		In *strukturen.ParsePointer `json:"zusammengefuehrtIn"`
+	}
+	if err := dup.Decode(&markiert); err == nil && markiert.In != nil && markiert.In.ObjectID != ziel.ObjectID() {
+		http.Error(w, "Duplikat wird bereits mit "+markiert.In.ObjectID+" zusammengeführt", http.StatusConflict)
+		return nil, nil, false
+	}
+	if _, ok := ziel["zusammengefuehrtIn"]; ok {
+		http.Error(w, "Ziel ist selbst als Duplikat markiert", http.StatusConflict)
+		return nil, nil, false
+	}
+	return ziel, dup, true
+}
+
+// bezahlt des Ziels nach der Zusammenführung: beide Kassenbücher zählen,
+// die Gebühr gilt für das Ziel mit den übernommenen Angaben (ohne das
+// Duplikat als Geschwister)
+func (h *KindHandler) bezahltNachZusammenfuehren(ctx context.Context, ziel, dup parse.Object, zielNeu map[string]any) (bool, error) {
+	zielZahlungen, err := h.zahlungenVon(ctx, ziel.ObjectID())
+	if err != nil {
+		return false, err
+	}
+	dupZahlungen, err := h.zahlungenVon(ctx, dup.ObjectID())
+	if err != nil {
+		return false, err
+	}
+	saldo, _ := kassenstand(append(zielZahlungen, dupZahlungen...))
+
+	kind := parse.Object{}
+	for k, v := range ziel {
+		kind[k] = v
+	}
+	for k, v := range zielNeu {
+		kind[k] = v
+	}
+	familie := []parse.Object{kind}
+	if h.Gebuehren.Aktiv() {
+		geschwister, err := h.familieVon(ctx, kind)
+		if err != nil {
+			return false, err
+		}
+		for _, g := range geschwister {
+			if g.ObjectID() != ziel.ObjectID() && g.ObjectID() != dup.ObjectID() {
+				familie = append(familie, g)
+			}
+		}
+	}
+	return h.istBezahlt(saldo, h.gebuehrenFuer(familie)[ziel.ObjectID()]), nil
+}
+
+// Nimmt die Markierung des Duplikats zurück (Kompensation); die Version
+// bleibt erhöht. Fehler werden nur geloggt.
+func (h *KindHandler) markierungAufheben(ctx context.Context, dupObjectID string) {
+	_, err := h.Parse.Update(ctx, "Kind", dupObjectID, map[string]any{"zusammengefuehrtIn": map[string]any{"__op": "Delete"}}, nil)
+	if err != nil {
+		log.Printf("Markierung von %s konnte nicht aufgehoben werden: %v", dupObjectID, err)
+	}
+}
+
+// Zuordnungen (kinderDerRiege) eines Kindes
+func (h *KindHandler) zuordnungenVonKind(ctx context.Context, kindObjectID string) ([]parse.Object, error) {
+	return parse.QueryAll(ctx, h.Parse, "kinderDerRiege", parse.NewQuery().PointerTo("kindID", "Kind", kindObjectID))
+}
+
+func zuordnungRiege(z parse.Object) string {
+	var d strukturen.KinderDerRiege
+	if err := z.Decode(&d); err != nil || d.RiegenID == nil {
+		return ""
+	}
+	return d.RiegenID.ObjectID
+}
+
+// Resultate eines Kindes je Station
+func (h *KindHandler) resultateVonKind(ctx context.Context, kindObjectID string) (map[string]parse.Object, error) {
+	alle, err := parse.QueryAll(ctx, h.Parse, "resultate", parse.NewQuery().PointerTo("kindID", "Kind", kindObjectID))
+	if err != nil {
+		return nil, err
+	}
+	jeStation := make(map[string]parse.Object, len(alle))
+	for _, res := range alle {
+		var d strukturen.Resultate
+		if err := res.Decode(&d); err != nil || d.StationsID == nil {
+			continue
+		}
+		jeStation[d.StationsID.ObjectID] = res
+	}
+	return jeStation, nil
+}
+
+// Business-Key-Felder eines Kind-Objekts
+func kindAusObjekt(o parse.Object) strukturen.Kind {
+	return strukturen.Kind{
+		VorName:    o.String("vorName"),
+		NachName:   o.String("nachName"),
+		Jahrgang:   o.Int("jahrgang"),
+		Geschlecht: o.String("geschlecht"),
+	}
+}
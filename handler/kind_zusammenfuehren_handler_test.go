@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// Umgehängte Buchungen und Resultate behalten das ursprüngliche Kind
+func TestZusammenfuehrenUrspruenglichesKind(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+
+	ziel := kindRegistrieren(t, h, &testEltern, testKind)
+	dup, err := mem.Create(ctx, "Kind", map[string]any{
+		"vorName": "Ana", "nachName": "Muster", "jahrgang": 2014, "geschlecht": "w", "version": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := anfrage(t, h, h.BucheZahlung, http.MethodPost, &testAdmin, ZahlungRequest{
+		KindObjectID: dup.ObjectID(), ExpectedVersion: 1, Betrag: 500, Methode: MethodeBar,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Zahlung: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := mem.Create(ctx, "resultate", map[string]any{
+		"kindID":     strukturen.NewParsePointer("Kind", dup.ObjectID()),
+		"stationsID": strukturen.NewParsePointer("Station", "s1"),
+		"punkte":     3,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w = anfrage(t, h, h.KindZusammenfuehrenRouter, http.MethodPost, &testAdmin, KindZusammenfuehrenRequest{
+		Ziel:     KindVersion{ObjectID: ziel, ExpectedVersion: 1},
+		Duplikat: KindVersion{ObjectID: dup.ObjectID(), ExpectedVersion: 2},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Zusammenführen: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, klasse := range []string{"zahlung", "resultate"} {
+		out, err := mem.Query(ctx, klasse, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Results) != 1 {
+			t.Fatalf("%s: %d Einträge, want 1", klasse, len(out.Results))
+		}
+		var e struct {
+			KindID              *strukturen.ParsePointer `json:"kindID"`
+			UrspruenglichKindID *strukturen.ParsePointer `json:"urspruenglichKindID"`
+		}
+		if err := out.Results[0].Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if zeigerID(e.KindID) != ziel || zeigerID(e.UrspruenglichKindID) != dup.ObjectID() {
+			t.Errorf("%s: kindID %q, urspruenglichKindID %q; want %q, %q",
+				klasse, zeigerID(e.KindID), zeigerID(e.UrspruenglichKindID), ziel, dup.ObjectID())
+		}
+	}
+	if _, err := mem.Get(ctx, "Kind", dup.ObjectID()); !parse.IsNotFound(err) {
+		t.Errorf("Duplikat nicht gelöscht: %v", err)
+	}
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
-	"net/url"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
 )
 
 // Create / Update / Delete
@@ -14,12 +16,106 @@ type KinderDerRiegeRequest struct {
 	Position      int    `json:"position,omitempty"`
 }
 
-// Hilfsfunktion für Lock-Key
-func kinderDerRiegeKey(kindID, riegeID string) string {
-	return kindID + "|" + riegeID
+// Antwort für GET: Zuordnung mit eingebettetem Kind
+type RiegenZuordnung struct {
+	ObjectID string          `json:"objectId"`
+	Position int             `json:"position"`
+	Kind     json.RawMessage `json:"kind"`
+}
+
+// ===== Router für /riege-zuordnung =====
+func (h *KindHandler) KinderDerRiegeRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetKinderDerRiege(w, r)
+	case http.MethodPost:
+		h.AssignKindToRiege(w, r)
+	case http.MethodPut:
+		h.UpdateKindRiegePosition(w, r)
+	case http.MethodDelete:
+		h.RemoveKindFromRiege(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+// Liefert alle Zuordnungen einer Riege sortiert nach Position,
+// die Daten des Kindes werden per include mitgeliefert.
+// Aufruf: GET /riege-zuordnung?riegeObjectId=<objectId>
+func (h *KindHandler) GetKinderDerRiege(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleStationshelfer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	riegeObjectID := r.URL.Query().Get("riegeObjectId")
+	if riegeObjectID == "" {
+		http.Error(w, "riegeObjectId fehlt", http.StatusBadRequest)
+		return
+	}
+
+	// Riegenführer sehen nur die eigene Riege
+	if id.Rolle == RolleRiegenfuehrer && !id.darfRiege(riegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// alle Seiten laden (Parse liefert ohne limit nur 100); objectId als
+	// letztes Kriterium, damit das Blättern stabil ist
+	query := parse.NewQuery().
+		PointerTo("riegenID", "Riege", riegeObjectID).
+		Order("position", "objectId").
+		Include("kindID")
+
+	alle, err := parse.QueryAll(r.Context(), h.Parse, "kinderDerRiege", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	zuordnungen := make([]RiegenZuordnung, 0, len(alle))
+	for _, obj := range alle {
+		var z strukturen.KinderDerRiege
+		if err := obj.Decode(&z); err != nil {
+			http.Error(w, "Antwort ungültig", http.StatusInternalServerError)
+			return
+		}
+		if !z.KindID.Expanded() {
+			// Kind wurde zwischenzeitlich gelöscht → Zuordnung überspringen
+			continue
+		}
+		zuordnungen = append(zuordnungen, RiegenZuordnung{
+			ObjectID: obj.ObjectID(),
+			Position: z.Position,
+			Kind:     z.KindID.Object,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": zuordnungen,
+	})
 }
 
 // ===== CREATE =====
+// Ohne position wird das Kind ans Ende gesetzt, sonst eingefügt; die
+// nachfolgenden Kinder rücken eine Position nach hinten.
 func (h *KindHandler) AssignKindToRiege(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
@@ -27,103 +123,109 @@ func (h *KindHandler) AssignKindToRiege(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
 	var req KinderDerRiegeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
 		return
 	}
 
-	if req.KindObjectID == "" || req.RiegeObjectID == "" || req.Position <= 0 {
+	if req.KindObjectID == "" || req.RiegeObjectID == "" || req.Position < 0 {
 		http.Error(w, "Pflichtfelder fehlen", http.StatusBadRequest)
 		return
 	}
 
-	lockKey := kinderDerRiegeKey(req.KindObjectID, req.RiegeObjectID)
-	lock := h.lockForKey(lockKey)
+	if !id.darfRiege(req.RiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
 
-	select {
-	case lock <- struct{}{}:
-		defer func() { <-lock }()
-	default:
+	// ---- Lock auf Riege und Kind ----
+	unlock, ok := h.lockAll(riegeLockKey(req.RiegeObjectID), zuordnungKindLockKey(req.KindObjectID))
+	if !ok {
 		http.Error(w, "Zuordnung wird bereits verarbeitet", http.StatusConflict)
 		return
 	}
+	defer unlock()
 
-	// ---- Duplikatprüfung ----
-	where := map[string]any{
-		"kindID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Kind",
-			"objectId":  req.KindObjectID,
-		},
+	// ---- abgemeldete Kinder bekommen keine Riege ----
+	kind, err := h.Parse.Get(r.Context(), "Kind", req.KindObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+		return
 	}
-
-	whereJSON, _ := json.Marshal(where)
-	url := h.ParseServerURL + "/classes/kinderDerRiege?where=" +
-		url.QueryEscape(string(whereJSON))
-
-	checkReq, _ := http.NewRequest(http.MethodGet, url, nil)
-	checkReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	checkReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-
-	resp, err := http.DefaultClient.Do(checkReq)
 	if err != nil {
 		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
+	if kind.Bool("abgemeldet") {
+		http.Error(w, "Kind ist abgemeldet", http.StatusConflict)
+		return
+	}
+
+	// ---- Duplikatprüfung ----
+	query := parse.NewQuery().PointerTo("kindID", "Kind", req.KindObjectID)
 
-	var existing struct {
-		Results []any `json:"results"`
+	existing, err := h.Parse.Query(r.Context(), "kinderDerRiege", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
 	}
-	json.NewDecoder(resp.Body).Decode(&existing)
 
 	if len(existing.Results) > 0 {
 		http.Error(w, "Kind ist bereits einer Riege zugeordnet", http.StatusConflict)
 		return
 	}
 
-	// ---- Insert ----
-	payload := map[string]any{
-		"kindID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Kind",
-			"objectId":  req.KindObjectID,
-		},
-		"riegenID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Riege",
-			"objectId":  req.RiegeObjectID,
-		},
-		"position": req.Position,
+	// ---- Position prüfen ----
+	liste, err := h.zuordnungenDerRiege(r.Context(), req.RiegeObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if req.Position > len(liste)+1 {
+		http.Error(w, "position liegt hinter dem Ende der Riege", http.StatusBadRequest)
+		return
+	}
+	position := req.Position
+	if position == 0 {
+		position = len(liste) + 1
 	}
 
-	body, _ := json.Marshal(payload)
-
-	createReq, _ := http.NewRequest(
-		http.MethodPost,
-		h.ParseServerURL+"/classes/kinderDerRiege",
-		bytes.NewBuffer(body),
-	)
-
-	createReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	createReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-	createReq.Header.Set("Content-Type", "application/json")
-
-	resp, err = http.DefaultClient.Do(createReq)
+	// ---- Insert + neu nummerieren ----
+	aenderungen := &zuordnungsAenderungen{h: h}
+	neu, err := aenderungen.anlegen(r.Context(), strukturen.KinderDerRiege{
+		KindID:   strukturen.NewParsePointer("Kind", req.KindObjectID),
+		RiegenID: strukturen.NewParsePointer("Riege", req.RiegeObjectID),
+		Position: position,
+	})
 	if err != nil {
 		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
+	if err := aenderungen.nummerieren(r.Context(), req.RiegeObjectID, einfuegen(liste, neu, position)); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Speichern fehlgeschlagen – Änderungen zurückgesetzt", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "zuordnen", "kinderDerRiege", neu.ObjectID(), nil,
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.RiegeObjectID, "position": position},
+		0)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
-		"message": "Kind erfolgreich Riege zugeordnet",
+		"message":  "Kind erfolgreich Riege zugeordnet",
+		"objectId": neu.ObjectID(),
+		"position": position,
 	})
 }
 
 // ===== UPDATE =====
+// Verschiebt das Kind innerhalb der Riege an die neue Position.
 func (h *KindHandler) UpdateKindRiegePosition(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPut {
@@ -131,6 +233,11 @@ func (h *KindHandler) UpdateKindRiegePosition(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
 	var req KinderDerRiegeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
@@ -142,85 +249,47 @@ func (h *KindHandler) UpdateKindRiegePosition(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	lock := h.lockForKey(kinderDerRiegeKey(req.KindObjectID, req.RiegeObjectID))
-	select {
-	case lock <- struct{}{}:
-		defer func() { <-lock }()
-	default:
+	if !id.darfRiege(req.RiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock auf die Riege ----
+	unlock, ok := h.lockAll(riegeLockKey(req.RiegeObjectID))
+	if !ok {
 		http.Error(w, "Zuordnung wird bearbeitet", http.StatusConflict)
 		return
 	}
+	defer unlock()
 
 	// ---- Suche Zuordnung ----
-	where := map[string]any{
-		"kindID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Kind",
-			"objectId":  req.KindObjectID,
-		},
-		"riegenID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Riege",
-			"objectId":  req.RiegeObjectID,
-		},
-	}
-
-	whereJSON, _ := json.Marshal(where)
-	searchURL := h.ParseServerURL + "/classes/kinderDerRiege?where=" +
-		url.QueryEscape(string(whereJSON))
-
-	searchReq, _ := http.NewRequest(http.MethodGet, searchURL, nil)
-	searchReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	searchReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-
-	resp, err := http.DefaultClient.Do(searchReq)
+	liste, err := h.zuordnungenDerRiege(r.Context(), req.RiegeObjectID)
 	if err != nil {
 		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Results []struct {
-			ObjectID string `json:"objectId"`
-		} `json:"results"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		http.Error(w, "Antwort ungültig", http.StatusInternalServerError)
+	rest, z := herausnehmen(liste, req.KindObjectID)
+	if z == nil {
+		http.Error(w, "Zuordnung nicht gefunden", http.StatusNotFound)
 		return
 	}
-
-	if len(result.Results) == 0 {
-		http.Error(w, "Zuordnung nicht gefunden", http.StatusNotFound)
+	if req.Position > len(liste) {
+		http.Error(w, "position liegt hinter dem Ende der Riege", http.StatusBadRequest)
 		return
 	}
 
-	objectID := result.Results[0].ObjectID
-
 	// ---- Update ----
-	payload := map[string]any{
-		"position": req.Position,
-	}
-
-	body, _ := json.Marshal(payload)
-
-	updateReq, _ := http.NewRequest(
-		http.MethodPut,
-		h.ParseServerURL+"/classes/kinderDerRiege/"+objectID,
-		bytes.NewBuffer(body),
-	)
-
-	updateReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	updateReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-	updateReq.Header.Set("Content-Type", "application/json")
-
-	resp, err = http.DefaultClient.Do(updateReq)
-	if err != nil {
-		http.Error(w, "Update fehlgeschlagen", http.StatusInternalServerError)
+	aenderungen := &zuordnungsAenderungen{h: h}
+	if err := aenderungen.nummerieren(r.Context(), req.RiegeObjectID, einfuegen(rest, z, req.Position)); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Update fehlgeschlagen – Änderungen zurückgesetzt", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
+	h.audit(r, id, "position", "kinderDerRiege", z.ObjectID(),
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.RiegeObjectID, "position": z.Int("position")},
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.RiegeObjectID, "position": req.Position},
+		0)
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"message": "Position erfolgreich aktualisiert",
@@ -228,6 +297,7 @@ func (h *KindHandler) UpdateKindRiegePosition(w http.ResponseWriter, r *http.Req
 }
 
 // ===== DELETE =====
+// Die nachfolgenden Kinder rücken auf, damit keine Lücke entsteht.
 func (h *KindHandler) RemoveKindFromRiege(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodDelete {
@@ -235,6 +305,11 @@ func (h *KindHandler) RemoveKindFromRiege(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
 	var req KinderDerRiegeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
@@ -246,80 +321,75 @@ func (h *KindHandler) RemoveKindFromRiege(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	lock := h.lockForKey(kinderDerRiegeKey(req.KindObjectID, req.RiegeObjectID))
-	select {
-	case lock <- struct{}{}:
-		defer func() { <-lock }()
-	default:
+	if !id.darfRiege(req.RiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock auf Riege und Kind ----
+	unlock, ok := h.lockAll(riegeLockKey(req.RiegeObjectID), zuordnungKindLockKey(req.KindObjectID))
+	if !ok {
 		http.Error(w, "Zuordnung wird bearbeitet", http.StatusConflict)
 		return
 	}
+	defer unlock()
 
 	// ---- Suche Zuordnung ----
-	where := map[string]any{
-		"kindID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Kind",
-			"objectId":  req.KindObjectID,
-		},
-		"riegenID": map[string]any{
-			"__type":    "Pointer",
-			"className": "Riege",
-			"objectId":  req.RiegeObjectID,
-		},
-	}
-
-	whereJSON, _ := json.Marshal(where)
-	searchURL := h.ParseServerURL + "/classes/kinderDerRiege?where=" +
-		url.QueryEscape(string(whereJSON))
-
-	searchReq, _ := http.NewRequest(http.MethodGet, searchURL, nil)
-	searchReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	searchReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-
-	resp, err := http.DefaultClient.Do(searchReq)
+	liste, err := h.zuordnungenDerRiege(r.Context(), req.RiegeObjectID)
 	if err != nil {
 		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Results []struct {
-			ObjectID string `json:"objectId"`
-		} `json:"results"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		http.Error(w, "Antwort ungültig", http.StatusInternalServerError)
-		return
-	}
 
-	if len(result.Results) == 0 {
+	rest, z := herausnehmen(liste, req.KindObjectID)
+	if z == nil {
 		http.Error(w, "Zuordnung nicht gefunden", http.StatusNotFound)
 		return
 	}
 
-	objectID := result.Results[0].ObjectID
-
-	// ---- Delete ----
-	deleteReq, _ := http.NewRequest(
-		http.MethodDelete,
-		h.ParseServerURL+"/classes/kinderDerRiege/"+objectID,
-		nil,
-	)
-
-	deleteReq.Header.Set("X-Parse-Application-Id", h.ParseAppID)
-	deleteReq.Header.Set("X-Parse-Javascript-Key", h.ParseJSKey)
-
-	resp, err = http.DefaultClient.Do(deleteReq)
-	if err != nil {
+	// ---- Delete + Lücke schließen ----
+	aenderungen := &zuordnungsAenderungen{h: h}
+	if err := aenderungen.loeschen(r.Context(), z); err != nil {
 		http.Error(w, "Löschen fehlgeschlagen", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
+	if err := aenderungen.nummerieren(r.Context(), req.RiegeObjectID, rest); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Löschen fehlgeschlagen – Änderungen zurückgesetzt", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, id, "entfernen", "kinderDerRiege", z.ObjectID(),
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.RiegeObjectID, "position": z.Int("position")},
+		nil, 0)
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"message": "Kind erfolgreich aus Riege entfernt",
 	})
 }
+
+// Liefert die Riege, der das Kind zugeordnet ist (nil, wenn keine)
+func (h *KindHandler) riegeVonKind(ctx context.Context, kindObjectID string) (parse.Object, error) {
+	query := parse.NewQuery().
+		PointerTo("kindID", "Kind", kindObjectID).
+		Include("riegenID").
+		Limit(1)
+
+	out, err := h.Parse.Query(ctx, "kinderDerRiege", query)
+	if err != nil || len(out.Results) == 0 {
+		return nil, err
+	}
+
+	var z strukturen.KinderDerRiege
+	if err := out.Results[0].Decode(&z); err != nil {
+		return nil, err
+	}
+	if !z.RiegenID.Expanded() {
+		// Riege wurde gelöscht, Zuordnung verwaist
+		return nil, nil
+	}
+	var riege parse.Object
+	if err := z.RiegenID.Decode(&riege); err != nil {
+		return nil, err
+	}
+	return riege, nil
+}
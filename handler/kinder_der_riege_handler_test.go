@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// Legt eine Riege direkt in Parse an und liefert ihre objectId
+func riegeAnlegen(t *testing.T, mem *parse.MemoryClient, nummer int, beendet bool) string {
+	t.Helper()
+	out, err := mem.Create(context.Background(), "Riege", map[string]any{
+		"riegenNummer":      nummer,
+		"version":           1,
+		"wetttkampfBeendet": beendet,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.ObjectID()
+}
+
+// Kinder der Riege in Positionsreihenfolge; prüft, dass die Positionen lückenlos sind
+func riegenKinder(t *testing.T, h *KindHandler, riegeID string) []string {
+	t.Helper()
+	liste, err := h.zuordnungenDerRiege(context.Background(), riegeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, z := range liste {
+		if z.Int("position") != i+1 {
+			t.Fatalf("Riege %s: Position %d an Stelle %d", riegeID, z.Int("position"), i+1)
+		}
+	}
+	return kinderReihenfolge(liste)
+}
+
+// ======  GET /riege-zuordnung  ======
+
+// Große Riegen kommen vollständig und in Positionsreihenfolge zurück,
+// obwohl Parse ohne limit nur 100 Objekte liefert
+func TestGetKinderDerRiegeAlle(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	riege := riegeAnlegen(t, mem, 1, false)
+
+	const anzahl = 150
+	for pos := anzahl; pos >= 1; pos-- {
+		kind, err := mem.Create(ctx, "Kind", map[string]any{"vorName": "Kind" + strconv.Itoa(pos), "nachName": "Muster", "version": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mem.Create(ctx, "kinderDerRiege", map[string]any{
+			"riegenID": strukturen.NewParsePointer("Riege", riege),
+			"kindID":   strukturen.NewParsePointer("Kind", kind.ObjectID()),
+			"position": pos,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?riegeObjectId="+riege, nil)
+	tid := testAdmin
+	tid.Ablauf = time.Now().Add(time.Hour).Unix()
+	token, err := h.signToken(tid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.KinderDerRiegeRouter(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+
+	var out struct {
+		Results []RiegenZuordnung `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != anzahl {
+		t.Fatalf("%d Zuordnungen, want %d", len(out.Results), anzahl)
+	}
+	for i, z := range out.Results {
+		if z.Position != i+1 || len(z.Kind) == 0 {
+			t.Fatalf("Stelle %d: Position %d, Kind %s", i+1, z.Position, z.Kind)
+		}
+	}
+}
+
+// ======  POST /riege-zuordnung  ======
+
+func TestAssignKindToRiege(t *testing.T) {
+	h, mem := testHandler(t)
+	r1 := riegeAnlegen(t, mem, 1, false)
+	r2 := riegeAnlegen(t, mem, 2, false)
+
+	k1 := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Anna", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"})
+	k2 := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Ben", NachName: "Muster", Jahrgang: 2015, Geschlecht: "m"})
+	k3 := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Cleo", NachName: "Beispiel", Jahrgang: 2014, Geschlecht: "w"})
+	k4 := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Dora", NachName: "Beispiel", Jahrgang: 2013, Geschlecht: "w"})
+	k5 := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Emil", NachName: "Beispiel", Jahrgang: 2013, Geschlecht: "m"})
+	if _, err := mem.Update(context.Background(), "Kind", k4, map[string]any{"abgemeldet": true}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fuehrer1 := &Identitaet{UserID: "rf1", Rolle: RolleRiegenfuehrer, RiegeID: r1}
+	fuehrer2 := &Identitaet{UserID: "rf2", Rolle: RolleRiegenfuehrer, RiegeID: r2}
+
+	// Reihenfolge ist wichtig: jeder Schritt baut auf dem vorigen auf
+	tests := []struct {
+		name         string
+		id           *Identitaet
+		req          KinderDerRiegeRequest
+		wantStatus   int
+		wantPosition int
+		wantRiege    []string // Kinder von r1 danach
+	}{
+		{"ans Ende", fuehrer1, KinderDerRiegeRequest{KindObjectID: k1, RiegeObjectID: r1}, http.StatusCreated, 1, []string{k1}},
+		{"Admin ans Ende", &testAdmin, KinderDerRiegeRequest{KindObjectID: k2, RiegeObjectID: r1}, http.StatusCreated, 2, []string{k1, k2}},
+		{"einfügen am Anfang", fuehrer1, KinderDerRiegeRequest{KindObjectID: k3, RiegeObjectID: r1, Position: 1}, http.StatusCreated, 1, []string{k3, k1, k2}},
+		{"bereits zugeordnet", &testAdmin, KinderDerRiegeRequest{KindObjectID: k1, RiegeObjectID: r2}, http.StatusConflict, 0, []string{k3, k1, k2}},
+		{"fremde Riege", fuehrer2, KinderDerRiegeRequest{KindObjectID: k4, RiegeObjectID: r1}, http.StatusForbidden, 0, []string{k3, k1, k2}},
+		{"Eltern", &testEltern, KinderDerRiegeRequest{KindObjectID: k4, RiegeObjectID: r1}, http.StatusForbidden, 0, []string{k3, k1, k2}},
+		{"abgemeldet", &testAdmin, KinderDerRiegeRequest{KindObjectID: k4, RiegeObjectID: r1}, http.StatusConflict, 0, []string{k3, k1, k2}},
+		{"Kind fehlt", &testAdmin, KinderDerRiegeRequest{KindObjectID: "gibtsnicht", RiegeObjectID: r1}, http.StatusNotFound, 0, []string{k3, k1, k2}},
+		{"Position hinter dem Ende", &testAdmin, KinderDerRiegeRequest{KindObjectID: k5, RiegeObjectID: r1, Position: 5}, http.StatusBadRequest, 0, []string{k3, k1, k2}},
+		{"Position direkt hinter dem Ende", &testAdmin, KinderDerRiegeRequest{KindObjectID: k5, RiegeObjectID: r1, Position: 4}, http.StatusCreated, 4, []string{k3, k1, k2, k5}},
+		{"Pflichtfeld fehlt", &testAdmin, KinderDerRiegeRequest{RiegeObjectID: r1}, http.StatusBadRequest, 0, []string{k3, k1, k2, k5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.KinderDerRiegeRouter, http.MethodPost, tt.id, tt.req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code == http.StatusCreated {
+				if got := antwort(t, w)["position"]; got != float64(tt.wantPosition) {
+					t.Errorf("position = %v, want %d", got, tt.wantPosition)
+				}
+			}
+			if got := riegenKinder(t, h, r1); !slices.Equal(got, tt.wantRiege) {
+				t.Errorf("Riege = %v, want %v", got, tt.wantRiege)
+			}
+		})
+	}
+}
+
+// ======  POST /riege-zuordnung/verschieben  ======
+
+func TestMoveKindToRiege(t *testing.T) {
+	h, mem := testHandler(t)
+	r1 := riegeAnlegen(t, mem, 1, false)
+	r2 := riegeAnlegen(t, mem, 2, false)
+	beendet := riegeAnlegen(t, mem, 3, true)
+
+	var kinder []string
+	for _, vorName := range []string{"Anna", "Ben", "Cleo", "Dora"} {
+		k := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: vorName, NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"})
+		kinder = append(kinder, k)
+	}
+	// Anna, Ben, Cleo → r1; Dora → r2
+	for i, k := range kinder {
+		riege := r1
+		if i == 3 {
+			riege = r2
+		}
+		w := anfrage(t, h, h.KinderDerRiegeRouter, http.MethodPost, &testAdmin, KinderDerRiegeRequest{KindObjectID: k, RiegeObjectID: riege})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Zuordnen: %d %s", w.Code, w.Body.String())
+		}
+	}
+	anna, ben, cleo, dora := kinder[0], kinder[1], kinder[2], kinder[3]
+
+	fuehrer1 := &Identitaet{UserID: "rf1", Rolle: RolleRiegenfuehrer, RiegeID: r1}
+
+	// Reihenfolge ist wichtig: jeder Schritt baut auf dem vorigen auf
+	tests := []struct {
+		name         string
+		id           *Identitaet
+		req          KindVerschiebenRequest
+		wantStatus   int
+		wantPosition int
+		wantR1       []string
+		wantR2       []string
+	}{
+		{"Riegenführer nur einer Riege", fuehrer1, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: r2},
+			http.StatusForbidden, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"gleiche Riege", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: r1},
+			http.StatusBadRequest, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"Zielriege beendet", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: beendet},
+			http.StatusConflict, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"Zielriege fehlt", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: "gibtsnicht"},
+			http.StatusNotFound, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"nicht in der Quellriege", &testAdmin, KindVerschiebenRequest{KindObjectID: dora, VonRiegeObjectID: r1, NachRiegeObjectID: r2},
+			http.StatusNotFound, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"Position hinter dem Ende", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: r2, Position: 3},
+			http.StatusBadRequest, 0, []string{anna, ben, cleo}, []string{dora}},
+		{"ans Ende", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r1, NachRiegeObjectID: r2},
+			http.StatusOK, 2, []string{anna, cleo}, []string{dora, ben}},
+		{"an den Anfang", &testAdmin, KindVerschiebenRequest{KindObjectID: cleo, VonRiegeObjectID: r1, NachRiegeObjectID: r2, Position: 1},
+			http.StatusOK, 1, []string{anna}, []string{cleo, dora, ben}},
+		{"zurück in die Mitte", &testAdmin, KindVerschiebenRequest{KindObjectID: ben, VonRiegeObjectID: r2, NachRiegeObjectID: r1, Position: 1},
+			http.StatusOK, 1, []string{ben, anna}, []string{cleo, dora}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, h.MoveKindToRiege, http.MethodPost, tt.id, tt.req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				if got := antwort(t, w)["position"]; got != float64(tt.wantPosition) {
+					t.Errorf("position = %v, want %d", got, tt.wantPosition)
+				}
+			}
+			if got := riegenKinder(t, h, r1); !slices.Equal(got, tt.wantR1) {
+				t.Errorf("Riege 1 = %v, want %v", got, tt.wantR1)
+			}
+			if got := riegenKinder(t, h, r2); !slices.Equal(got, tt.wantR2) {
+				t.Errorf("Riege 2 = %v, want %v", got, tt.wantR2)
+			}
+		})
+	}
+}
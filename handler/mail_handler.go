@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"sporttag/mailer"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  E-Mail-Ausgang  ======
+//
+// E-Mails werden nie direkt aus einer Anfrage verschickt: mailEinreihen
+// legt sie in der Klasse "mailAusgang" ab, der Hintergrund-Ausgang
+// (MailAusgangStarten) schickt sie über h.Mailer und wiederholt
+// Fehlschläge mit wachsendem Abstand. Pro Server läuft genau ein Ausgang.
+
+// Status im mailAusgang
+const (
+	MailOffen          = "offen"
+	MailGesendet       = "gesendet"
+	MailFehlgeschlagen = "fehlgeschlagen"
+)
+
+const (
+	mailMaxVersuche = 6
+	mailTimeout     = 30 * time.Second
+	mailJeDurchlauf = 50
+)
+
+// Abstand vor dem nächsten Versuch: 1, 2, 4, … Minuten, höchstens 1 Stunde
+func mailWartezeit(versuche int) time.Duration {
+	return min(time.Minute<<max(versuche-1, 0), time.Hour)
+}
+
+// Legt eine E-Mail im Ausgang ab. Fehler werden nur geloggt: die
+// eigentliche Änderung ist zu diesem Zeitpunkt bereits gespeichert.
+func (h *KindHandler) mailEinreihen(ctx context.Context, vorlage, an string, daten mailer.Daten) {
+	if h.Mailer == nil || h.MailVorlagen == nil || an == "" {
+		return
+	}
+	n, err := h.MailVorlagen.Erzeugen(vorlage, an, daten)
+	if err != nil {
+		log.Printf("E-Mail %s an %s: %v", vorlage, an, err)
+		return
+	}
+	eintrag := strukturen.MailAusgang{
+		An:               n.An,
+		Betreff:          n.Betreff,
+		Text:             n.Text,
+		Vorlage:          vorlage,
+		Status:           MailOffen,
+		NaechsterVersuch: strukturen.NewParseDate(time.Now()),
+	}
+	if _, err := h.Parse.Create(ctx, "mailAusgang", eintrag); err != nil {
+		log.Printf("E-Mail %s an %s nicht eingereiht: %v", vorlage, an, err)
+		return
+	}
+	// Ausgang wecken, ohne zu warten
+	select {
+	case h.mailWecker <- struct{}{}:
+	default:
+	}
+}
+
+// MailAusgangStarten verschickt im Hintergrund alle fälligen E-Mails,
+// spätestens alle intervall und sofort nach mailEinreihen. Auch E-Mails,
+// die vor einem Neustart eingereiht wurden, werden nachgeholt.
+// Muss vor dem Start des HTTP-Servers aufgerufen werden.
+func (h *KindHandler) MailAusgangStarten(ctx context.Context, intervall time.Duration) {
+	h.mailWecker = make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(intervall)
+		defer ticker.Stop()
+		for {
+			// volle Seite → gleich weiter
+			if h.mailAusgangAbarbeiten(ctx) == mailJeDurchlauf && ctx.Err() == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-h.mailWecker:
+			}
+		}
+	}()
+}
+
+// Ein Durchlauf des Ausgangs; liefert die Anzahl E-Mails, deren Status
+// gespeichert werden konnte
+func (h *KindHandler) mailAusgangAbarbeiten(ctx context.Context) int {
+	jetzt := time.Now()
+	query := parse.NewQuery().
+		EqualTo("status", MailOffen).
+		LessThanOrEqualTo("naechsterVersuch", strukturen.NewParseDate(jetzt)).
+		Order("naechsterVersuch").
+		Limit(mailJeDurchlauf)
+	out, err := h.Parse.Query(ctx, "mailAusgang", query)
+	if err != nil {
+		log.Println("E-Mail-Ausgang:", err)
+		return 0
+	}
+
+	bearbeitet := 0
+	for _, obj := range out.Results {
+		var m strukturen.MailAusgang
+		if err := obj.Decode(&m); err != nil {
+			log.Printf("E-Mail-Ausgang %s: %v", obj.ObjectID(), err)
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, mailTimeout)
+		err := h.Mailer.Senden(sendCtx, mailer.Nachricht{An: m.An, Betreff: m.Betreff, Text: m.Text})
+		cancel()
+
+		update := map[string]any{"versuche": m.Versuche + 1}
+		switch {
+		case err == nil:
+			update["status"] = MailGesendet
+			update["gesendetAm"] = strukturen.NewParseDate(time.Now())
+			update["naechsterVersuch"] = map[string]any{"__op": "Delete"}
+		case m.Versuche+1 >= mailMaxVersuche:
+			log.Printf("E-Mail an %s endgültig fehlgeschlagen: %v", m.An, err)
+			update["status"] = MailFehlgeschlagen
+			update["fehler"] = err.Error()
+			update["naechsterVersuch"] = map[string]any{"__op": "Delete"}
+		default:
+			update["fehler"] = err.Error()
+			update["naechsterVersuch"] = strukturen.NewParseDate(time.Now().Add(mailWartezeit(m.Versuche + 1)))
+		}
+		if _, err := h.Parse.Update(ctx, "mailAusgang", obj.ObjectID(), update, nil); err != nil {
+			// wird im nächsten Durchlauf erneut versucht (ggf. doppelt zugestellt)
+			log.Printf("E-Mail-Ausgang %s: %v", obj.ObjectID(), err)
+			continue
+		}
+		bearbeitet++
+	}
+	return bearbeitet
+}
+
+// Empfänger für E-Mails zu einem Kind: der Erziehungsberechtigte,
+// sonst das Eltern-Konto, das das Kind angemeldet hat.
+// Liefert Adresse und Anrede; "" = keine Adresse bekannt.
+//
+// Parse gibt die email eines fremden _User nur mit dem Master-Key heraus.
+// Der RESTClient sendet ihn bei allen Anfragen ohne Session-Token
+// (parse/rest.go, setKeys); main.go startet nicht ohne parse_master_key.
+func (h *KindHandler) mailEmpfaenger(ctx context.Context, kind parse.Object) (an, name string) {
+	if ebID := erziehungsberechtigterVon(kind); ebID != "" {
+		eb, err := h.Parse.Get(ctx, "Erziehungsberechtigter", ebID)
+		if err != nil && !parse.IsNotFound(err) {
+			log.Printf("Mail-Empfänger (Erziehungsberechtigter %s): %v", ebID, err)
+		}
+		if err == nil && eb.String("email") != "" {
+			return eb.String("email"), eb.String("vorName") + " " + eb.String("nachName")
+		}
+	}
+	if elternID := kind.String("elternID"); elternID != "" {
+		user, err := h.Parse.Get(ctx, "_User", elternID)
+		if err != nil && !parse.IsNotFound(err) {
+			log.Printf("Mail-Empfänger (_User %s): %v", elternID, err)
+		}
+		if err == nil && user.String("email") != "" {
+			return user.String("email"), user.String("name")
+		}
+	}
+	return "", ""
+}
+
+// Vorlagendaten aus Kind-Objekten und ihren Gebühren (nil = ohne Beträge)
+func mailKinder(kinder []parse.Object, zeilen map[string]gebuehrZeile) []mailer.Kind {
+	liste := make([]mailer.Kind, 0, len(kinder))
+	for _, k := range kinder {
+		z := zeilen[k.ObjectID()]
+		liste = append(liste, mailer.Kind{
+			VorName:  k.String("vorName"),
+			NachName: k.String("nachName"),
+			Jahrgang: k.Int("jahrgang"),
+			Gebuehr:  z.Gebuehr,
+			Offen:    z.Offen,
+		})
+	}
+	return liste
+}
+
+// Anmeldebestätigung für frisch angelegte Kinder (gleicher Empfänger)
+func (h *KindHandler) mailBestaetigung(ctx context.Context, kinder []parse.Object, gebuehren map[string]int) {
+	if h.Mailer == nil || len(kinder) == 0 {
+		return
+	}
+	an, name := h.mailEmpfaenger(ctx, kinder[0])
+	zeilen := map[string]gebuehrZeile{}
+	summe := 0
+	for _, k := range kinder {
+		zeilen[k.ObjectID()] = gebuehrZeile{Gebuehr: gebuehren[k.ObjectID()]}
+		summe += gebuehren[k.ObjectID()]
+	}
+	h.mailEinreihen(ctx, mailer.VorlageBestaetigung, an, mailer.Daten{
+		Name:    name,
+		Kinder:  mailKinder(kinder, zeilen),
+		Gebuehr: summe,
+	})
+}
+
+// ===== POST /gebuehren/erinnerung =====
+// Reiht je Familie mit offenem Betrag eine Zahlungserinnerung ein (nur admin).
+// Kinder ohne bekannte E-Mail-Adresse werden in der Antwort gemeldet.
+func (h *KindHandler) ZahlungserinnerungRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+	if h.Mailer == nil {
+		http.Error(w, "Kein E-Mail-Versand konfiguriert", http.StatusServiceUnavailable)
+		return
+	}
+	if !h.Gebuehren.Aktiv() {
+		http.Error(w, "Keine Gebühren konfiguriert", http.StatusConflict)
+		return
+	}
+
+	kinder, err := parse.QueryAll(r.Context(), h.Parse, "Kind", parse.NewQuery())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zahlungen, err := h.zahlungenJeKind(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- offene Kinder je Familie ----
+	zeilen := map[string]gebuehrZeile{}
+	familien := map[string][]parse.Object{}
+	for _, z := range h.gebuehrZeilen(kinder, zahlungen) {
+		zeilen[z.KindObjectID] = z
+	}
+	for _, k := range kinder {
+		z := zeilen[k.ObjectID()]
+		if z.Offen <= 0 || k.Bool("abgemeldet") {
+			continue
+		}
+		familie := z.Familie
+		if familie == "" {
+			familie = k.ObjectID()
+		}
+		familien[familie] = append(familien[familie], k)
+	}
+	schluessel := make([]string, 0, len(familien))
+	for f := range familien {
+		schluessel = append(schluessel, f)
+	}
+	sort.Strings(schluessel)
+
+	eingereiht := 0
+	ohneAdresse := []string{}
+	for _, f := range schluessel {
+		geschwister := familien[f]
+		an, name := h.mailEmpfaenger(r.Context(), geschwister[0])
+		if an == "" {
+			for _, k := range geschwister {
+				ohneAdresse = append(ohneAdresse, k.ObjectID())
+			}
+			continue
+		}
+		daten := mailer.Daten{Name: name, Kinder: mailKinder(geschwister, zeilen)}
+		for _, k := range daten.Kinder {
+			daten.Gebuehr += k.Gebuehr
+			daten.Offen += k.Offen
+		}
+		h.mailEinreihen(r.Context(), mailer.VorlageZahlungserinnerung, an, daten)
+		eingereiht++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eingereiht":  eingereiht,
+		"ohneAdresse": ohneAdresse,
+	})
+}
+
+// ===== GET /mail-ausgang =====
+// Einträge des Ausgangs, neueste zuerst (nur admin); ?status=offen|gesendet|fehlgeschlagen
+func (h *KindHandler) MailAusgangRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	query := parse.NewQuery().Order("-createdAt").Limit(1000)
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case MailOffen, MailGesendet, MailFehlgeschlagen:
+		query.EqualTo("status", status)
+	default:
+		http.Error(w, "status ungültig", http.StatusBadRequest)
+		return
+	}
+	out, err := h.Parse.Query(r.Context(), "mailAusgang", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"sporttag/auswertung"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ===== GET /rangliste[?klasse=<name>][&geschlecht=m|w] =====
+// Summiert die Resultate je Kind und liefert die Ranglisten je
+// Altersklasse und Geschlecht (Regeln aus config.json "rangliste").
+// alleRiegenBeendet zeigt, ob die Liste für die Siegerehrung endgültig ist.
+func (h *KindHandler) RanglisteRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	if _, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleStationshelfer, RolleAdmin); !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// ---- Daten laden ----
+	// abgemeldete Kinder werden nicht gewertet
+	kinder, err := parse.QueryAll(ctx, h.Parse, "Kind", parse.NewQuery().NotEqualTo("abgemeldet", true))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	zuordnungen, err := parse.QueryAll(ctx, h.Parse, "kinderDerRiege", parse.NewQuery().Include("riegenID"))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	resultate, err := parse.QueryAll(ctx, h.Parse, "resultate",
+		parse.NewQuery().Keys("kindID", "stationsID", "punkte"))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	stationen, err := h.alleStationen(ctx)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	offen, err := h.Parse.Query(ctx, "Riege",
+		parse.NewQuery().NotEqualTo("wetttkampfBeendet", true).Count().Limit(0))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Pflichtstationen je Kind (über die Riege) ----
+	stationsNummer := make(map[string]int, len(stationen))
+	for _, s := range stationen {
+		stationsNummer[s.ObjectID()] = s.Int("stationsNummer")
+	}
+	pflicht := map[bool][]int{}
+	for _, fuenfKampf := range []bool{false, true} {
+		for _, s := range pflichtStationen(stationen, fuenfKampf) {
+			pflicht[fuenfKampf] = append(pflicht[fuenfKampf], s.Int("stationsNummer"))
+		}
+	}
+
+	fuenfKampfVon := map[string]bool{}
+	for _, z := range zuordnungen {
+		var zd strukturen.KinderDerRiege
+		if err := z.Decode(&zd); err != nil || zd.KindID == nil || !zd.RiegenID.Expanded() {
+			continue
+		}
+		var riege strukturen.Riege
+		if err := zd.RiegenID.Decode(&riege); err == nil {
+			fuenfKampfVon[zd.KindID.ObjectID] = riege.FuenfKampf
+		}
+	}
+
+	teilnehmer := make([]auswertung.Teilnehmer, 0, len(kinder))
+	for _, k := range kinder {
+		teilnehmer = append(teilnehmer, auswertung.Teilnehmer{
+			KindObjectID: k.ObjectID(),
+			VorName:      k.String("vorName"),
+			NachName:     k.String("nachName"),
+			Jahrgang:     k.Int("jahrgang"),
+			Geschlecht:   k.String("geschlecht"),
+			// ohne Riege: alle Stationen (Zehnkampf)
+			Pflicht: pflicht[fuenfKampfVon[k.ObjectID()]],
+		})
+	}
+
+	ergebnisse := make([]auswertung.Ergebnis, 0, len(resultate))
+	for _, res := range resultate {
+		var rd strukturen.Resultate
+		if err := res.Decode(&rd); err != nil || rd.KindID == nil || rd.StationsID == nil {
+			continue
+		}
+		nr, ok := stationsNummer[rd.StationsID.ObjectID]
+		if !ok {
+			// Station gelöscht
+			continue
+		}
+		ergebnisse = append(ergebnisse, auswertung.Ergebnis{
+			KindObjectID:   rd.KindID.ObjectID,
+			StationsNummer: nr,
+			Punkte:         rd.Punkte,
+		})
+	}
+
+	// ---- Rangliste berechnen ----
+	gruppen, err := auswertung.Rangliste(teilnehmer, ergebnisse, h.Rangliste)
+	if err != nil {
+		http.Error(w, "Rangliste nicht möglich: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	klasse := r.URL.Query().Get("klasse")
+	geschlecht := r.URL.Query().Get("geschlecht")
+	gefiltert := make([]auswertung.Wertungsgruppe, 0, len(gruppen))
+	for _, g := range gruppen {
+		if (klasse == "" || g.Klasse == klasse) && (geschlecht == "" || g.Geschlecht == geschlecht) {
+			gefiltert = append(gefiltert, g)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"alleRiegenBeendet": offen.Count == 0,
+		"offeneRiegen":      offen.Count,
+		"gruppen":           gefiltert,
+	})
+}
@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"time"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+	"sporttag/wertung"
+)
+
+// POST – Resultat erfassen bzw. korrigieren
+type ResultatRequest struct {
+	KindObjectID    string `json:"kindObjectId"`
+	StationObjectID string `json:"stationObjectId"`
+	Punkte          int    `json:"punkte"`
+	// Rohwert; bei Stationen mit Punktetabelle Pflicht, punkte wird berechnet
+	Messwert *float64 `json:"messwert,omitempty"`
+	// Korrektur eines bereits erfassten Resultats (mit expectedVersion)
+	Korrektur       bool `json:"korrektur,omitempty"`
+	ExpectedVersion int  `json:"expectedVersion,omitempty"`
+}
+
+// Lock-Key für Resultate: Kombination der beteiligten objectIds
+// (siehe strukturen/informationen.txt)
+func resultateKey(kindID, stationID string) string {
+	return kindID + "|" + stationID
+}
+
+// ===== Router für /resultat =====
+func (h *KindHandler) ResultatRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetResultate(w, r)
+	case http.MethodPost:
+		h.SubmitResultat(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+// GET /resultat?kindObjectId=<id> und/oder ?stationObjectId=<id>
+// Stationshelfer sehen nur die Resultate ihrer Station.
+func (h *KindHandler) GetResultate(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleStationshelfer, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	kindObjectID := r.URL.Query().Get("kindObjectId")
+	stationObjectID := r.URL.Query().Get("stationObjectId")
+	if id.Rolle == RolleStationshelfer {
+		if stationObjectID == "" {
+			stationObjectID = id.StationID
+		}
+		if !id.darfStation(stationObjectID) {
+			http.Error(w, "Keine Berechtigung für diese Station", http.StatusForbidden)
+			return
+		}
+	}
+
+	query := parse.NewQuery().Order("erreichtUm").Limit(1000)
+	if kindObjectID != "" {
+		query.PointerTo("kindID", "Kind", kindObjectID)
+	}
+	if stationObjectID != "" {
+		query.PointerTo("stationsID", "Station", stationObjectID)
+	}
+
+	out, err := h.Parse.Query(r.Context(), "resultate", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// ===== CREATE / KORREKTUR =====
+func (h *KindHandler) SubmitResultat(w http.ResponseWriter, r *http.Request) {
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleStationshelfer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req ResultatRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ---- Validierung ----
+	if req.KindObjectID == "" || req.StationObjectID == "" {
+		http.Error(w, "Pflichtfelder fehlen", http.StatusBadRequest)
+		return
+	}
+	if req.Punkte < 0 {
+		http.Error(w, "punkte darf nicht negativ sein", http.StatusBadRequest)
+		return
+	}
+	if req.Messwert != nil && *req.Messwert < 0 {
+		http.Error(w, "messwert darf nicht negativ sein", http.StatusBadRequest)
+		return
+	}
+	// messwert 0 bei kleinerBesser lehnt die Wertung mit ErrMesswert ab
+	if req.Korrektur && req.ExpectedVersion <= 0 {
+		http.Error(w, "Korrektur erfordert expectedVersion", http.StatusBadRequest)
+		return
+	}
+	if !id.darfStation(req.StationObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Station", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock Kind|Station ----
+	lock := h.lockForKey(resultateKey(req.KindObjectID, req.StationObjectID))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Resultat wird bereits erfasst", http.StatusConflict)
+		return
+	}
+
+	// ---- Station prüfen ----
+	station, err := h.Parse.Get(r.Context(), "Station", req.StationObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Station nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Riege des Kindes prüfen ----
+	riege, err := h.riegeVonKind(r.Context(), req.KindObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if riege == nil {
+		http.Error(w, "Kind ist keiner Riege zugeordnet", http.StatusConflict)
+		return
+	}
+	if riege.Bool("wetttkampfBeendet") {
+		http.Error(w, "Wettkampf der Riege ist beendet", http.StatusConflict)
+		return
+	}
+	if riege.Bool("fuenfKampf") && station.Bool("nurZehnKampf") {
+		http.Error(w, "Station gehört nicht zum Fünfkampf der Riege", http.StatusConflict)
+		return
+	}
+
+	// ---- Punkte aus dem Messwert berechnen ----
+	punkte := req.Punkte
+	tabellenVersion := ""
+	if tabelle := station.String("tabelle"); tabelle != "" {
+		if req.Messwert == nil {
+			http.Error(w, "messwert erforderlich – die Punkte werden berechnet", http.StatusBadRequest)
+			return
+		}
+		if req.Punkte != 0 {
+			http.Error(w, "punkte wird aus messwert berechnet und darf nicht angegeben werden", http.StatusBadRequest)
+			return
+		}
+
+		kind, err := h.Parse.Get(r.Context(), "Kind", req.KindObjectID)
+		if parse.IsNotFound(err) {
+			http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+
+		punkte, err = h.Wertung.Punkte(
+			tabelle,
+			wertung.Disziplin(station.String("disziplin")),
+			wertung.Richtung(station.String("richtung")),
+			kind.Int("jahrgang"),
+			kind.String("geschlecht"),
+			*req.Messwert,
+		)
+		switch {
+		case errors.Is(err, wertung.ErrMesswert):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, wertung.ErrKeineWertung):
+			http.Error(w, "Punktetabelle "+tabelle+": "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			log.Printf("Wertung Station %s: %v", req.StationObjectID, err)
+			http.Error(w, "Punktetabelle "+tabelle+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tabellenVersion = h.Wertung.Version
+	}
+
+	// ---- vorhandenes Resultat suchen ----
+	query := parse.NewQuery().
+		PointerTo("kindID", "Kind", req.KindObjectID).
+		PointerTo("stationsID", "Station", req.StationObjectID)
+	existing, err := h.Parse.Query(r.Context(), "resultate", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	jetzt := strukturen.NewParseDate(time.Now())
+
+	// ---- Neues Resultat ----
+	if !req.Korrektur {
+		if len(existing.Results) > 0 {
+			http.Error(
+				w,
+				"Resultat existiert bereits – Änderung nur als Korrektur (korrektur=true)",
+				http.StatusConflict,
+			)
+			return
+		}
+
+		payload := map[string]any{
+			"kindID":     strukturen.NewParsePointer("Kind", req.KindObjectID),
+			"stationsID": strukturen.NewParsePointer("Station", req.StationObjectID),
+			"punkte":     punkte,
+			"erreichtUm": jetzt,
+			"erfasstVon": id.Name,
+			"version":    1,
+		}
+		if req.Messwert != nil {
+			payload["messwert"] = *req.Messwert
+		}
+		if tabellenVersion != "" {
+			payload["tabellenVersion"] = tabellenVersion
+		}
+		out, err := h.Parse.Create(r.Context(), "resultate", payload)
+		if err != nil {
+			http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, id, "anlegen", "resultate", out.ObjectID(), nil, payload, 1)
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypResultat, riege.ObjectID(), req.StationObjectID, map[string]any{
+			"objectId":        out.ObjectID(),
+			"kindObjectId":    req.KindObjectID,
+			"stationObjectId": req.StationObjectID,
+			"riegeObjectId":   riege.ObjectID(),
+			"punkte":          punkte,
+			"messwert":        req.Messwert,
+			"erreichtUm":      jetzt,
+			"korrektur":       false,
+			"version":         1,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":    "Resultat erfolgreich gespeichert",
+			"objectId":   out.ObjectID(),
+			"punkte":     punkte,
+			"erreichtUm": jetzt,
+		})
+		return
+	}
+
+	// ---- Korrektur ----
+	if len(existing.Results) == 0 {
+		http.Error(w, "Kein Resultat zum Korrigieren vorhanden", http.StatusNotFound)
+		return
+	}
+	if len(existing.Results) > 1 {
+		http.Error(w, "Dateninkonsistenz: mehrere Resultate für Kind und Station", http.StatusConflict)
+		return
+	}
+	alt := existing.Results[0]
+
+	update := map[string]interface{}{
+		"punkte":     punkte,
+		"erreichtUm": jetzt,
+		"erfasstVon": id.Name,
+	}
+	if req.Messwert != nil {
+		update["messwert"] = *req.Messwert
+	}
+	if tabellenVersion != "" {
+		update["tabellenVersion"] = tabellenVersion
+	}
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	out, err := h.updateWithVersion(r.Context(), "resultate", alt.ObjectID(), req.ExpectedVersion, maps.Clone(update))
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Resultat wurde zwischenzeitlich geändert", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	vorher := map[string]any{}
+	for feld := range update {
+		if v, ok := alt[feld]; ok {
+			vorher[feld] = v
+		}
+	}
+	h.audit(r, id, "korrektur", "resultate", alt.ObjectID(), vorher, update, req.ExpectedVersion+1)
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypResultat, riege.ObjectID(), req.StationObjectID, map[string]any{
+		"objectId":        alt.ObjectID(),
+		"kindObjectId":    req.KindObjectID,
+		"stationObjectId": req.StationObjectID,
+		"riegeObjectId":   riege.ObjectID(),
+		"punkte":          punkte,
+		"alterWert":       alt.Int("punkte"),
+		"messwert":        req.Messwert,
+		"erreichtUm":      jetzt,
+		"korrektur":       true,
+		"version":         req.ExpectedVersion + 1,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Resultat erfolgreich korrigiert",
+		"objectId":   alt.ObjectID(),
+		"punkte":     punkte,
+		"alterWert":  alt.Int("punkte"),
+		"newVersion": req.ExpectedVersion + 1,
+		"updatedAt":  out["updatedAt"],
+		"erreichtUm": jetzt,
+	})
+}
+
+// ===== GET /punktetabellen =====
+// Liefert die geladenen Tabellen samt Version (Nachvollziehbarkeit der Wertung)
+func (h *KindHandler) PunktetabellenRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r); !ok {
+		return
+	}
+	if h.Wertung == nil {
+		http.Error(w, "Keine Punktetabellen konfiguriert", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Wertung)
+}
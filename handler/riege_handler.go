@@ -0,0 +1,440 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+//
+// ===== Request-Strukturen =====
+//
+
+// PUT – Änderung einer Riege (nur die angegebenen Felder)
+type RiegeUpdateRequest struct {
+	ObjectID        string          `json:"objectId"`
+	Update          json.RawMessage `json:"update"`
+	ExpectedVersion int             `json:"expectedVersion"`
+}
+
+// DELETE – Löschen einer Riege
+type RiegeDeleteRequest struct {
+	ObjectID        string `json:"objectId"`
+	ExpectedVersion int    `json:"expectedVersion"`
+}
+
+// Lock-Keys: Riege über objectId, Eindeutigkeit über die Riegennummer
+func riegeLockKey(objectID string) string {
+	return "Riege|" + objectID
+}
+
+func riegenNummerLockKey(nummer int) string {
+	return "Riege#" + strconv.Itoa(nummer)
+}
+
+// Klassen mit Pointer riegenID, die beim Löschen einer Riege mit wegfallen
+var riegenBezuege = []string{"kinderDerRiege", "riegenLogging", "rotationsplan"}
+
+// ===== Router für /riege =====
+func (h *KindHandler) RiegeRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetRiegen(w, r)
+	case http.MethodPost:
+		h.CreateRiege(w, r)
+	case http.MethodPut:
+		h.UpdateRiege(w, r)
+	case http.MethodDelete:
+		h.DeleteRiege(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+func (h *KindHandler) GetRiegen(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r); !ok {
+		return
+	}
+
+	query := parse.NewQuery().Order("riegenNummer").Limit(1000)
+	out, err := h.Parse.Query(r.Context(), "Riege", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// ===== CREATE =====
+func (h *KindHandler) CreateRiege(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var riege strukturen.Riege
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&riege); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if riege.RiegenNummer <= 0 {
+		http.Error(w, "riegenNummer fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Lock auf die Riegennummer ----
+	lock := h.lockForKey(riegenNummerLockKey(riege.RiegenNummer))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Riegennummer wird bereits vergeben", http.StatusConflict)
+		return
+	}
+
+	// ---- Eindeutigkeit der Riegennummer ----
+	belegt, err := h.riegenNummerBelegt(r, riege.RiegenNummer, "")
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if belegt {
+		http.Error(w, "Riegennummer bereits vergeben", http.StatusConflict)
+		return
+	}
+
+	// ---- Riege anlegen ----
+	payload := map[string]any{
+		"riegenNummer":      riege.RiegenNummer,
+		"fuenfKampf":        riege.FuenfKampf,
+		"wetttkampfBeendet": riege.WettkampfBeendet,
+		"version":           1,
+	}
+	out, err := h.Parse.Create(r.Context(), "Riege", payload)
+	if err != nil {
+		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, id, "anlegen", "Riege", out.ObjectID(), nil, payload, 1)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  "Riege erfolgreich angelegt",
+		"objectId": out.ObjectID(),
+	})
+}
+
+// ===== UPDATE =====
+func (h *KindHandler) UpdateRiege(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	// ---- Erlaubte Update-Felder ----
+	// Riegenführer dürfen die Riegennummer nicht ändern
+	allowedUpdateKeys := map[string]bool{
+		"riegenNummer":      id.Rolle == RolleAdmin,
+		"fuenfKampf":        true,
+		"wetttkampfBeendet": true,
+	}
+
+	var req RiegeUpdateRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ObjectID == "" {
+		http.Error(w, "objectId fehlt", http.StatusBadRequest)
+		return
+	}
+	if req.ExpectedVersion <= 0 {
+		http.Error(w, "expectedVersion fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+	if !id.darfRiege(req.ObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// ---- Validate Update Keys ----
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(req.Update, &raw); err != nil || len(raw) == 0 {
+		http.Error(w, "Ungültiges Update-JSON", http.StatusBadRequest)
+		return
+	}
+	for k := range raw {
+		if !allowedUpdateKeys[k] {
+			http.Error(w, "Ungültiges Update-Feld: "+k, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var upd strukturen.Riege
+	if err := json.NewDecoder(bytes.NewReader(req.Update)).Decode(&upd); err != nil {
+		http.Error(w, "Ungültiges Update-JSON", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Lock auf die Riege ----
+	lock := h.lockForKey(riegeLockKey(req.ObjectID))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Konflikt: Riege wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+
+	update := map[string]interface{}{}
+	if _, ok := raw["fuenfKampf"]; ok {
+		update["fuenfKampf"] = upd.FuenfKampf
+	}
+	if _, ok := raw["wetttkampfBeendet"]; ok {
+		update["wetttkampfBeendet"] = upd.WettkampfBeendet
+	}
+
+	// ---- neue Riegennummer: zusätzlich Lock + Eindeutigkeit ----
+	if _, ok := raw["riegenNummer"]; ok {
+		if upd.RiegenNummer <= 0 {
+			http.Error(w, "riegenNummer ungültig", http.StatusBadRequest)
+			return
+		}
+
+		nummerLock := h.lockForKey(riegenNummerLockKey(upd.RiegenNummer))
+		select {
+		case nummerLock <- struct{}{}:
+			defer func() { <-nummerLock }()
+		default:
+			http.Error(w, "Riegennummer wird bereits vergeben", http.StatusConflict)
+			return
+		}
+
+		belegt, err := h.riegenNummerBelegt(r, upd.RiegenNummer, req.ObjectID)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		if belegt {
+			http.Error(w, "Riegennummer bereits vergeben", http.StatusConflict)
+			return
+		}
+		update["riegenNummer"] = upd.RiegenNummer
+	}
+
+	vorher := h.auditStand(r.Context(), "Riege", req.ObjectID, update)
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	out, err := h.updateWithVersion(r.Context(), "Riege", req.ObjectID, req.ExpectedVersion, maps.Clone(update))
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Riege nicht gefunden oder Version veraltet", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "aendern", "Riege", req.ObjectID, vorher, update, req.ExpectedVersion+1)
+	if update["wetttkampfBeendet"] == true {
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypRiegeBeendet, req.ObjectID, "", map[string]any{
+			"riegeObjectId": req.ObjectID,
+			"manuell":       true,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Riege erfolgreich aktualisiert",
+		"newVersion": req.ExpectedVersion + 1,
+		"updatedAt":  out["updatedAt"],
+	})
+}
+
+// ===== DELETE =====
+// Eine Riege mit zugeordneten Kindern, Fortschritt (riegenLogging) oder
+// Einsätzen im Rotationsplan wird nur mit ?cascade=true gelöscht, dann
+// werden diese Einträge mit entfernt. Sonst blieben sie mit einem Pointer
+// auf eine gelöschte Riege in /riegen-logging und /rotationsplan stehen.
+func (h *KindHandler) DeleteRiege(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req RiegeDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ObjectID == "" || req.ExpectedVersion <= 0 {
+		http.Error(w, "objectId oder expectedVersion fehlt", http.StatusBadRequest)
+		return
+	}
+	cascade := r.URL.Query().Get("cascade") == "true"
+
+	// ---- Lock auf die Riege und den Rotationsplan ----
+	unlock, ok := h.lockAll(riegeLockKey(req.ObjectID), rotationsplanLockKey)
+	if !ok {
+		http.Error(w, "Konflikt: Riege wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	// ---- abhängige Einträge ----
+	abhaengig := map[string][]string{}
+	anzahl := 0
+	for _, klasse := range riegenBezuege {
+		query := parse.NewQuery().PointerTo("riegenID", "Riege", req.ObjectID).Keys("objectId")
+		objs, err := parse.QueryAll(r.Context(), h.Parse, klasse, query)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		ids := make([]string, 0, len(objs))
+		for _, o := range objs {
+			ids = append(ids, o.ObjectID())
+		}
+		abhaengig[klasse] = ids
+		anzahl += len(ids)
+	}
+	if anzahl > 0 && !cascade {
+		http.Error(
+			w,
+			"Riege hat noch "+strconv.Itoa(len(abhaengig["kinderDerRiege"]))+" Kinder, "+
+				strconv.Itoa(len(abhaengig["riegenLogging"]))+" Fortschrittseinträge und "+
+				strconv.Itoa(len(abhaengig["rotationsplan"]))+" Einsätze im Rotationsplan – Löschen nur mit cascade=true",
+			http.StatusConflict,
+		)
+		return
+	}
+
+	// ---- Stand vor dem Löschen (Audit-Log) ----
+	vorher, err := h.Parse.Get(r.Context(), "Riege", req.ObjectID)
+	if err != nil && !parse.IsNotFound(err) {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Version prüfen ----
+	// DELETE kennt kein where, daher zuerst bedingte Versionserhöhung
+	if _, err := h.updateWithVersion(r.Context(), "Riege", req.ObjectID, req.ExpectedVersion, map[string]interface{}{}); err != nil {
+		if parse.IsNotFound(err) {
+			http.Error(w, "Konflikt: Riege nicht gefunden oder Version veraltet", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Cascade: abhängige Einträge in einem Batch entfernen ----
+	if anzahl > 0 {
+		ops := make([]parse.BatchOp, 0, anzahl)
+		for _, klasse := range riegenBezuege {
+			for _, objectID := range abhaengig[klasse] {
+				ops = append(ops, parse.BatchOp{
+					Method:    http.MethodDelete,
+					ClassName: klasse,
+					ObjectID:  objectID,
+				})
+			}
+		}
+		results, err := h.Parse.Batch(r.Context(), ops)
+		if err != nil {
+			http.Error(w, "Löschen der Zuordnungen fehlgeschlagen", http.StatusBadGateway)
+			return
+		}
+		for i, res := range results {
+			// bereits gelöscht ist kein Fehler
+			if res.Error != nil && res.Error.Code != parse.ErrNotFound.Code {
+				log.Printf("Cascade %s: %v", ops[i].ClassName, res.Error)
+				http.Error(w, "Löschen der Zuordnungen unvollständig", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// ---- Delete ----
+	if err := h.Parse.Delete(r.Context(), "Riege", req.ObjectID); err != nil {
+		http.Error(w, "Löschen fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, id, "loeschen", "Riege", req.ObjectID,
+		map[string]any{
+			"riege":         vorher,
+			"zuordnungen":   abhaengig["kinderDerRiege"],
+			"riegenLogging": abhaengig["riegenLogging"],
+			"rotationsplan": abhaengig["rotationsplan"],
+		}, nil,
+		req.ExpectedVersion+1)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":                "Riege erfolgreich gelöscht",
+		"entfernteZuordnungen":   len(abhaengig["kinderDerRiege"]),
+		"entfernteLogging":       len(abhaengig["riegenLogging"]),
+		"entferntePlanEintraege": len(abhaengig["rotationsplan"]),
+	})
+}
+
+// Prüft, ob eine andere Riege (≠ ausserObjectID) die Nummer bereits trägt
+func (h *KindHandler) riegenNummerBelegt(r *http.Request, nummer int, ausserObjectID string) (bool, error) {
+	query := parse.NewQuery().EqualTo("riegenNummer", nummer).Keys("objectId")
+	if ausserObjectID != "" {
+		query.NotEqualTo("objectId", ausserObjectID)
+	}
+	out, err := h.Parse.Query(r.Context(), "Riege", query)
+	if err != nil {
+		return false, err
+	}
+	return len(out.Results) > 0, nil
+}
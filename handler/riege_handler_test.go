@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// DELETE /riege entfernt mit cascade=true alle Einträge mit Pointer auf die Riege
+func TestDeleteRiegeCascade(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	riege := riegeAnlegen(t, mem, 1, false)
+	andere := riegeAnlegen(t, mem, 2, false)
+
+	for _, id := range []string{riege, andere} {
+		zeiger := strukturen.NewParsePointer("Riege", id)
+		eintraege := []struct {
+			klasse string
+			felder map[string]any
+		}{
+			{"kinderDerRiege", map[string]any{"riegenID": zeiger, "position": 1}},
+			{"riegenLogging", map[string]any{"riegenID": zeiger, "anzahlStationen": 0}},
+			{"rotationsplan", map[string]any{"riegenID": zeiger, "slot": 1}},
+		}
+		for _, e := range eintraege {
+			if _, err := mem.Create(ctx, e.klasse, e.felder); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	mitCascade := func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawQuery = "cascade=true"
+		h.DeleteRiege(w, r)
+	}
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		version    int
+		wantStatus int
+	}{
+		{"ohne cascade", h.DeleteRiege, 1, http.StatusConflict},
+		{"mit cascade", mitCascade, 1, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := anfrage(t, h, tt.handler, http.MethodDelete, &testAdmin, RiegeDeleteRequest{ObjectID: riege, ExpectedVersion: tt.version})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	// von der gelöschten Riege bleibt nichts, die andere ist unberührt
+	for _, klasse := range riegenBezuege {
+		for id, want := range map[string]int{riege: 0, andere: 1} {
+			out, err := mem.Query(ctx, klasse, parse.NewQuery().PointerTo("riegenID", "Riege", id))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Results) != want {
+				t.Errorf("%s für Riege %s: %d Einträge, want %d", klasse, id, len(out.Results), want)
+			}
+		}
+	}
+	if _, err := mem.Get(ctx, "Riege", riege); !parse.IsNotFound(err) {
+		t.Errorf("Riege noch vorhanden: %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/planung"
+	"sporttag/strukturen"
+)
+
+// POST – Riegen aus den angemeldeten Kindern bilden
+type RiegenBildungRequest struct {
+	// nil → Regeln aus config.json ("riegen_bildung")
+	Regeln *planung.Regeln `json:"regeln,omitempty"`
+	// ohne speichern=true nur Vorschau
+	Speichern bool `json:"speichern,omitempty"`
+}
+
+// Es darf immer nur eine Riegenbildung gleichzeitig laufen
+const riegenBildungLockKey = "riegen-bildung"
+
+// ===== POST /riegen-bildung – nur für Admins =====
+// Berücksichtigt werden alle Kinder, die noch keiner Riege zugeordnet sind;
+// ein zweiter Lauf verteilt also nur Nachmeldungen.
+func (h *KindHandler) RiegenBildungRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req RiegenBildungRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	regeln := h.RiegenBildung
+	if req.Regeln != nil {
+		regeln = *req.Regeln
+	}
+	if err := regeln.Validate(); err != nil {
+		http.Error(w, "Ungültige Regeln: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Speichern && !time.Now().UTC().After(h.Deadline) {
+		http.Error(w, "Anmeldung läuft noch – Riegenbildung erst nach der Deadline", http.StatusConflict)
+		return
+	}
+
+	// ---- Lock auf die Riegenbildung ----
+	lock := h.lockForKey(riegenBildungLockKey)
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Riegenbildung läuft bereits", http.StatusConflict)
+		return
+	}
+
+	// ---- noch nicht zugeordnete Kinder ----
+	kinder, err := h.kinderOhneRiege(r.Context())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	einteilung, err := planung.BildeRiegen(kinder, regeln)
+	if err != nil {
+		http.Error(w, "Riegenbildung nicht möglich: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// ---- Riegennummern fortlaufend nach der höchsten vorhandenen ----
+	letzte, err := h.Parse.Query(r.Context(), "Riege", parse.NewQuery().Order("-riegenNummer").Keys("riegenNummer").Limit(1))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	naechsteNummer := 1
+	if len(letzte.Results) > 0 {
+		naechsteNummer = letzte.Results[0].Int("riegenNummer") + 1
+	}
+
+	riegen := make([]map[string]any, 0, len(einteilung.Riegen))
+	for i, v := range einteilung.Riegen {
+		riegen = append(riegen, map[string]any{
+			"riegenNummer": naechsteNummer + i,
+			"bezeichnung":  v.Bezeichnung,
+			"fuenfKampf":   v.FuenfKampf,
+			"kinder":       v.Kinder,
+		})
+	}
+	antwort := map[string]any{
+		"riegen":          riegen,
+		"nichtZugeordnet": einteilung.NichtZugeordnet,
+		"warnungen":       einteilung.Warnungen,
+	}
+
+	if !req.Speichern {
+		antwort["vorschau"] = true
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(antwort)
+		return
+	}
+
+	// ---- Locks auf alle neuen Riegennummern ----
+	// wie bei POST /riege; eine dort zwischen Abfrage und Lock angelegte
+	// Riege fällt bei der Prüfung danach auf
+	nummern := make([]any, 0, len(einteilung.Riegen))
+	nummerKeys := make([]string, 0, len(einteilung.Riegen))
+	for i := range einteilung.Riegen {
+		nummern = append(nummern, naechsteNummer+i)
+		nummerKeys = append(nummerKeys, riegenNummerLockKey(naechsteNummer+i))
+	}
+	unlock, ok := h.lockAll(nummerKeys...)
+	if !ok {
+		http.Error(w, "Riegennummer wird bereits vergeben", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	if len(nummern) > 0 {
+		belegt, err := h.Parse.Query(r.Context(), "Riege", parse.NewQuery().In("riegenNummer", nummern...).Keys("riegenNummer").Limit(1))
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		if len(belegt.Results) > 0 {
+			http.Error(w, "Riegennummer "+strconv.Itoa(belegt.Results[0].Int("riegenNummer"))+" wurde zwischenzeitlich vergeben – bitte erneut ausführen", http.StatusConflict)
+			return
+		}
+	}
+
+	// ---- Riegen anlegen (Batch) ----
+	ops := make([]parse.BatchOp, 0, len(einteilung.Riegen))
+	for i, v := range einteilung.Riegen {
+		ops = append(ops, parse.BatchOp{
+			Method:    http.MethodPost,
+			ClassName: "Riege",
+			Body: map[string]any{
+				"riegenNummer":      naechsteNummer + i,
+				"fuenfKampf":        v.FuenfKampf,
+				"wetttkampfBeendet": false,
+				"version":           1,
+			},
+		})
+	}
+	riegenIDs, err := h.batchAnlegen(r.Context(), ops)
+	if err != nil {
+		log.Println("Riegenbildung (Riegen):", err)
+		h.rueckgaengig(r.Context(), "Riege", riegenIDs)
+		http.Error(w, "Riegen konnten nicht angelegt werden", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Zuordnungen anlegen (Batch), Positionen fortlaufend ----
+	ops = ops[:0]
+	for i, v := range einteilung.Riegen {
+		for pos, k := range v.Kinder {
+			ops = append(ops, parse.BatchOp{
+				Method:    http.MethodPost,
+				ClassName: "kinderDerRiege",
+				Body: strukturen.KinderDerRiege{
+					KindID:   strukturen.NewParsePointer("Kind", k.ObjectID),
+					RiegenID: strukturen.NewParsePointer("Riege", riegenIDs[i]),
+					Position: pos + 1,
+				},
+			})
+		}
+	}
+	zuordnungIDs, err := h.batchAnlegen(r.Context(), ops)
+	if err != nil {
+		log.Println("Riegenbildung (Zuordnungen):", err)
+		h.rueckgaengig(r.Context(), "kinderDerRiege", zuordnungIDs)
+		h.rueckgaengig(r.Context(), "Riege", riegenIDs)
+		http.Error(w, "Zuordnungen konnten nicht angelegt werden", http.StatusBadGateway)
+		return
+	}
+
+	for i := range riegen {
+		riegen[i]["objectId"] = riegenIDs[i]
+		h.audit(r, id, "riegenbildung", "Riege", riegenIDs[i], nil, riegen[i], 1)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(antwort)
+}
+
+// Alle Kinder, die in kinderDerRiege noch nicht vorkommen (ohne abgemeldete)
+func (h *KindHandler) kinderOhneRiege(ctx context.Context) ([]planung.KindInfo, error) {
+	zuordnungen, err := parse.QueryAll(ctx, h.Parse, "kinderDerRiege", parse.NewQuery().Keys("kindID"))
+	if err != nil {
+		return nil, err
+	}
+	zugeordnet := map[string]bool{}
+	for _, z := range zuordnungen {
+		var zd strukturen.KinderDerRiege
+		if err := z.Decode(&zd); err == nil && zd.KindID != nil {
+			zugeordnet[zd.KindID.ObjectID] = true
+		}
+	}
+
+	// objectId als letztes Kriterium, damit das Blättern stabil ist
+	query := parse.NewQuery().
+		NotEqualTo("abgemeldet", true).
+		Order("jahrgang", "nachName", "vorName", "objectId")
+	alle, err := parse.QueryAll(ctx, h.Parse, "Kind", query)
+	if err != nil {
+		return nil, err
+	}
+	kinder := make([]planung.KindInfo, 0, len(alle))
+	for _, k := range alle {
+		if zugeordnet[k.ObjectID()] {
+			continue
+		}
+		kinder = append(kinder, planung.KindInfo{
+			ObjectID:                 k.ObjectID(),
+			VorName:                  k.String("vorName"),
+			NachName:                 k.String("nachName"),
+			Jahrgang:                 k.Int("jahrgang"),
+			Geschlecht:               k.String("geschlecht"),
+			ElternID:                 k.String("elternID"),
+			ErziehungsberechtigterID: erziehungsberechtigterVon(k),
+		})
+	}
+	return kinder, nil
+}
+
+// Legt Objekte per Batch an und liefert ihre objectIds in Reihenfolge der ops.
+// Bei einem Fehler enthält die Liste die bereits angelegten Objekte,
+// damit der Aufrufer sie wieder entfernen kann.
+func (h *KindHandler) batchAnlegen(ctx context.Context, ops []parse.BatchOp) ([]string, error) {
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	// auch bei err enthält results die Ergebnisse der bereits
+	// ausgeführten Teil-Batches (RESTClient teilt in Blöcke auf)
+	results, err := h.Parse.Batch(ctx, ops)
+	ids := make([]string, 0, len(results))
+	erster := err
+	for _, res := range results {
+		if res.Error != nil {
+			if erster == nil {
+				erster = res.Error
+			}
+			continue
+		}
+		if objectID := res.Success.ObjectID(); objectID != "" {
+			ids = append(ids, objectID)
+		}
+	}
+	return ids, erster
+}
+
+// Kompensation: löscht angelegte Objekte wieder; Fehler werden nur geloggt
+func (h *KindHandler) rueckgaengig(ctx context.Context, klasse string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	ops := make([]parse.BatchOp, 0, len(ids))
+	for _, id := range ids {
+		ops = append(ops, parse.BatchOp{Method: http.MethodDelete, ClassName: klasse, ObjectID: id})
+	}
+	if err := h.batchAusfuehren(ctx, ops); err != nil {
+		log.Printf("Rückgängig machen (%s) fehlgeschlagen: %v", klasse, err)
+	}
+}
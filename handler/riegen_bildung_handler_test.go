@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"sporttag/parse"
+)
+
+// Parse, bei dem nach dem ersten Teil-Batch die Verbindung abbricht
+type batchAbbruch struct {
+	*parse.MemoryClient
+}
+
+func (b batchAbbruch) Batch(ctx context.Context, ops []parse.BatchOp) ([]parse.BatchResult, error) {
+	results, err := b.MemoryClient.Batch(ctx, ops[:1])
+	if err != nil {
+		return nil, err
+	}
+	return results, errors.New("connection reset by peer")
+}
+
+// Auch bei einem Abbruch mitten im Batch kommen die angelegten IDs zurück
+func TestBatchAnlegenTeilergebnis(t *testing.T) {
+	h, mem := testHandler(t)
+	h.Parse = batchAbbruch{mem}
+	ctx := context.Background()
+
+	ops := []parse.BatchOp{
+		{Method: http.MethodPost, ClassName: "Riege", Body: map[string]any{"riegenNummer": 1}},
+		{Method: http.MethodPost, ClassName: "Riege", Body: map[string]any{"riegenNummer": 2}},
+	}
+	ids, err := h.batchAnlegen(ctx, ops)
+	if err == nil {
+		t.Fatal("Fehler erwartet")
+	}
+	if len(ids) != 1 {
+		t.Fatalf("%d IDs, want 1", len(ids))
+	}
+
+	h.Parse = mem
+	h.rueckgaengig(ctx, "Riege", ids)
+	out, err := mem.Query(ctx, "Riege", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 0 {
+		t.Errorf("%d Riegen übrig, want 0", len(out.Results))
+	}
+}
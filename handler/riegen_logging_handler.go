@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// POST – Riegenführer meldet "Station erledigt"
+type RiegenLoggingRequest struct {
+	RiegeObjectID   string `json:"riegeObjectId"`
+	StationObjectID string `json:"stationObjectId"`
+}
+
+// ===== Router für /riegen-logging =====
+func (h *KindHandler) RiegenLoggingRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetRiegenLogging(w, r)
+	case http.MethodPost:
+		h.LogStationErledigt(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+// GET /riegen-logging[?riegeObjectId=<id>]
+func (h *KindHandler) GetRiegenLogging(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r); !ok {
+		return
+	}
+
+	query := parse.NewQuery().Limit(1000)
+	if riegeObjectID := r.URL.Query().Get("riegeObjectId"); riegeObjectID != "" {
+		query.PointerTo("riegenID", "Riege", riegeObjectID)
+	}
+
+	out, err := h.Parse.Query(r.Context(), "riegenLogging", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// ===== Station erledigt =====
+// Pro Riege gibt es genau einen Eintrag in "riegenLogging". Der Zähler wird
+// mit einem Increment-Op erhöht; das Update ist an den bisherigen Zählerstand
+// gebunden, damit parallele Meldungen nicht doppelt zählen. Scheitert nach
+// der letzten Pflichtstation das Beenden der Riege, bleibt der Fortschritt
+// gespeichert und die Antwort nennt den Fehler in "beendenFehler".
+func (h *KindHandler) LogStationErledigt(w http.ResponseWriter, r *http.Request) {
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req RiegenLoggingRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.RiegeObjectID == "" || req.StationObjectID == "" {
+		http.Error(w, "Pflichtfelder fehlen", http.StatusBadRequest)
+		return
+	}
+	if !id.darfRiege(req.RiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock auf die Riege ----
+	lock := h.lockForKey(riegeLockKey(req.RiegeObjectID))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Riege wird gerade bearbeitet", http.StatusConflict)
+		return
+	}
+
+	// ---- Riege und Station prüfen ----
+	riege, err := h.Parse.Get(r.Context(), "Riege", req.RiegeObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Riege nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if riege.Bool("wetttkampfBeendet") {
+		http.Error(w, "Wettkampf der Riege ist bereits beendet", http.StatusConflict)
+		return
+	}
+
+	station, err := h.Parse.Get(r.Context(), "Station", req.StationObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Station nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if riege.Bool("fuenfKampf") && station.Bool("nurZehnKampf") {
+		http.Error(w, "Station gehört nicht zum Fünfkampf der Riege", http.StatusConflict)
+		return
+	}
+
+	// ---- bisherigen Fortschritt laden ----
+	eintrag, err := h.riegenLoggingVon(r.Context(), req.RiegeObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	var bisher strukturen.RiegenLogging
+	if eintrag != nil {
+		if err := eintrag.Decode(&bisher); err != nil {
+			http.Error(w, "Fortschritt der Riege ist ungültig", http.StatusInternalServerError)
+			return
+		}
+	}
+	if slices.Contains(bisher.ErledigteStationen, req.StationObjectID) {
+		http.Error(w, "Station wurde für diese Riege bereits erfasst", http.StatusConflict)
+		return
+	}
+
+	jetzt := strukturen.NewParseDate(time.Now())
+	update := map[string]any{
+		"riegenID":                 strukturen.NewParsePointer("Riege", req.RiegeObjectID),
+		"stationsID":               strukturen.NewParsePointer("Station", req.StationObjectID),
+		"letzteStationUm":          jetzt,
+		"anzAbsolvierterStationen": map[string]any{"__op": "Increment", "amount": 1},
+		"erledigteStationen": map[string]any{
+			"__op":    "AddUnique",
+			"objects": []string{req.StationObjectID},
+		},
+	}
+
+	// ---- Speichern ----
+	var out parse.Object
+	if eintrag == nil {
+		out, err = h.Parse.Create(r.Context(), "riegenLogging", update)
+	} else {
+		where := map[string]any{"anzAbsolvierterStationen": bisher.AnzAbsolvierterStationen}
+		out, err = h.Parse.Update(r.Context(), "riegenLogging", eintrag.ObjectID(), update, where)
+	}
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Fortschritt wurde zwischenzeitlich geändert", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse liefert beim PUT nur updatedAt (und den neuen Zählerstand)
+	objectID := out.ObjectID()
+	if eintrag != nil {
+		objectID = eintrag.ObjectID()
+	}
+	anzahl := bisher.AnzAbsolvierterStationen + 1
+	if n := out.Int("anzAbsolvierterStationen"); n > 0 {
+		anzahl = n
+	}
+	erledigt := append(bisher.ErledigteStationen, req.StationObjectID)
+	aktion := "aendern"
+	if eintrag == nil {
+		aktion = "anlegen"
+	}
+	h.audit(r, id, aktion, "riegenLogging", objectID,
+		map[string]any{"anzAbsolvierterStationen": bisher.AnzAbsolvierterStationen, "erledigteStationen": bisher.ErledigteStationen},
+		map[string]any{"anzAbsolvierterStationen": anzahl, "erledigteStationen": erledigt, "stationObjectId": req.StationObjectID},
+		0)
+
+	// ---- alle Pflichtstationen erledigt? → Wettkampf beendet ----
+	// Der Fortschritt ist bereits gespeichert: scheitert das Laden der
+	// Stationen, gilt die Riege vorerst als nicht beendet (ein Fehler hier
+	// würde den Client zum erneuten Melden verleiten → 409)
+	stationen, err := h.alleStationen(r.Context())
+	if err != nil {
+		log.Printf("Stationen für Riege %s nicht geladen: %v", req.RiegeObjectID, err)
+	}
+	pflicht := pflichtStationen(stationen, riege.Bool("fuenfKampf"))
+
+	beendet := len(pflicht) > 0
+	for _, s := range pflicht {
+		if !slices.Contains(erledigt, s.ObjectID()) {
+			beendet = false
+			break
+		}
+	}
+
+	beendenFehler := ""
+	if beendet {
+		if err := h.riegeBeenden(r.Context(), riege); err != nil {
+			// Fortschritt ist gespeichert; nur das Beenden ist fehlgeschlagen
+			log.Printf("Riege %s konnte nicht beendet werden: %v", req.RiegeObjectID, err)
+			beendet = false
+			beendenFehler = "Alle Pflichtstationen erledigt, aber die Riege konnte nicht als beendet markiert werden"
+			if parse.IsNotFound(err) {
+				beendenFehler += " (Riege wurde zwischenzeitlich geändert)"
+			}
+		} else {
+			h.audit(r, id, "beenden", "Riege", req.RiegeObjectID,
+				map[string]any{"wetttkampfBeendet": false},
+				map[string]any{"wetttkampfBeendet": true},
+				riege.Int("version")+1)
+		}
+	}
+
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypRiegenLogging, req.RiegeObjectID, req.StationObjectID, map[string]any{
+		"objectId":                 objectID,
+		"riegeObjectId":            req.RiegeObjectID,
+		"stationObjectId":          req.StationObjectID,
+		"anzAbsolvierterStationen": anzahl,
+		"anzPflichtStationen":      len(pflicht),
+		"letzteStationUm":          jetzt,
+	})
+	if beendet {
+		h.Ereignisse.Veroeffentlichen(ereignisse.TypRiegeBeendet, req.RiegeObjectID, "", map[string]any{
+			"riegeObjectId": req.RiegeObjectID,
+			"riegenNummer":  riege.Int("riegenNummer"),
+		})
+	}
+
+	antwort := map[string]any{
+		"message":                  "Station erfolgreich erfasst",
+		"objectId":                 objectID,
+		"anzAbsolvierterStationen": anzahl,
+		"anzPflichtStationen":      len(pflicht),
+		"letzteStationUm":          jetzt,
+		"wettkampfBeendet":         beendet, // gespeichert als "wetttkampfBeendet", siehe strukturen/riege.go
+	}
+	if beendenFehler != "" {
+		antwort["beendenFehler"] = beendenFehler
+	}
+
+	// ---- Abgleich mit dem Rotationsplan ----
+	if plan, err := h.planAbgleich(r.Context(), req.RiegeObjectID, req.StationObjectID, jetzt.Time); err != nil {
+		log.Println("Rotationsplan-Abgleich:", err)
+	} else if plan != nil {
+		antwort["plan"] = plan
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if eintrag == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(antwort)
+}
+
+// Setzt wetttkampfBeendet mit Versionsprüfung. Riegen aus der Zeit vor
+// der Versionierung haben kein version-Feld (Int liefert 0, das nie passt);
+// sie werden nur beendet, solange es fehlt, und erhalten dabei version 1.
+func (h *KindHandler) riegeBeenden(ctx context.Context, riege parse.Object) error {
+	update := map[string]any{"wetttkampfBeendet": true}
+	if _, ok := riege["version"]; ok {
+		_, err := h.updateWithVersion(ctx, "Riege", riege.ObjectID(), riege.Int("version"), update)
+		return err
+	}
+	update["version"] = map[string]any{"__op": "Increment", "amount": 1}
+	where := parse.NewQuery().Exists("version", false)
+	_, err := h.Parse.Update(ctx, "Riege", riege.ObjectID(), update, where.Where())
+	return err
+}
+
+// Liefert den riegenLogging-Eintrag der Riege oder nil
+func (h *KindHandler) riegenLoggingVon(ctx context.Context, riegeObjectID string) (parse.Object, error) {
+	query := parse.NewQuery().PointerTo("riegenID", "Riege", riegeObjectID).Limit(1)
+	out, err := h.Parse.Query(ctx, "riegenLogging", query)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Results) == 0 {
+		return nil, nil
+	}
+	return out.Results[0], nil
+}
+
+// Alle Stationen, sortiert nach Stationsnummer
+func (h *KindHandler) alleStationen(ctx context.Context) ([]parse.Object, error) {
+	query := parse.NewQuery().Order("stationsNummer").Limit(1000)
+	out, err := h.Parse.Query(ctx, "Station", query)
+	if err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// Stationen, die eine Riege absolvieren muss:
+// Fünfkampf-Riegen lassen die Zehnkampf-Stationen aus.
+func pflichtStationen(stationen []parse.Object, fuenfKampf bool) []parse.Object {
+	var pflicht []parse.Object
+	for _, s := range stationen {
+		if fuenfKampf && s.Bool("nurZehnKampf") {
+			continue
+		}
+		pflicht = append(pflicht, s)
+	}
+	return pflicht
+}
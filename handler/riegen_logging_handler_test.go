@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"sporttag/parse"
+)
+
+// Parse, bei dem jedes Update einer Riege an der Versionsprüfung scheitert
+type riegeKonflikt struct {
+	*parse.MemoryClient
+}
+
+func (c riegeKonflikt) Update(ctx context.Context, className, objectID string, data any, where map[string]any) (parse.Object, error) {
+	if className == "Riege" {
+		return nil, parse.ErrNotFound
+	}
+	return c.MemoryClient.Update(ctx, className, objectID, data, where)
+}
+
+// Nach der letzten Pflichtstation ist die Riege beendet – auch eine alte
+// Riege ohne version; scheitert das Beenden, steht es in der Antwort
+func TestLogStationErledigtBeendet(t *testing.T) {
+	tests := []struct {
+		name        string
+		riege       map[string]any
+		konflikt    bool
+		wantBeendet bool
+		wantVersion int
+	}{
+		{"mit Version", map[string]any{"riegenNummer": 1, "version": 3}, false, true, 4},
+		{"ohne Version", map[string]any{"riegenNummer": 1}, false, true, 1},
+		{"Konflikt", map[string]any{"riegenNummer": 1, "version": 3}, true, false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mem := testHandler(t)
+			ctx := context.Background()
+			riege, err := mem.Create(ctx, "Riege", tt.riege)
+			if err != nil {
+				t.Fatal(err)
+			}
+			station, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": 1, "version": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.konflikt {
+				h.Parse = riegeKonflikt{mem}
+			}
+
+			w := anfrage(t, h, h.RiegenLoggingRouter, http.MethodPost, &testAdmin, RiegenLoggingRequest{
+				RiegeObjectID: riege.ObjectID(), StationObjectID: station.ObjectID(),
+			})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+			}
+			a := antwort(t, w)
+			if a["wettkampfBeendet"] != tt.wantBeendet {
+				t.Errorf("wettkampfBeendet %v, want %v", a["wettkampfBeendet"], tt.wantBeendet)
+			}
+			if _, ok := a["beendenFehler"]; ok == tt.wantBeendet {
+				t.Errorf("beendenFehler %v bei wettkampfBeendet %v", a["beendenFehler"], tt.wantBeendet)
+			}
+
+			gespeichert, err := mem.Get(ctx, "Riege", riege.ObjectID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gespeichert.Bool("wetttkampfBeendet") != tt.wantBeendet || gespeichert.Int("version") != tt.wantVersion {
+				t.Errorf("Riege: beendet %v, version %d; want %v, %d",
+					gespeichert.Bool("wetttkampfBeendet"), gespeichert.Int("version"), tt.wantBeendet, tt.wantVersion)
+			}
+		})
+	}
+}
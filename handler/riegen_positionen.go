@@ -0,0 +1,439 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Positionen innerhalb einer Riege  ======
+//
+// Die Positionen einer Riege sind immer 1..n – eindeutig und ohne Lücken.
+// Alle Änderungen an kinderDerRiege sperren deshalb die betroffene(n)
+// Riege(n) (riegeLockKey) und nummerieren danach neu. Parse kennt keine
+// Transaktionen: schlägt ein Schritt fehl, werden die bereits geänderten
+// Zuordnungen auf ihren alten Stand zurückgesetzt (Kompensation).
+
+// POST /riege-zuordnung/verschieben – Kind in eine andere Riege verschieben
+type KindVerschiebenRequest struct {
+	KindObjectID      string `json:"kindObjectId"`
+	VonRiegeObjectID  string `json:"vonRiegeObjectId"`
+	NachRiegeObjectID string `json:"nachRiegeObjectId"`
+	Position          int    `json:"position,omitempty"` // 0 → ans Ende
+}
+
+// PUT /riege-zuordnung/reihenfolge – komplette Reihenfolge einer Riege
+type RiegenReihenfolgeRequest struct {
+	RiegeObjectID string   `json:"riegeObjectId"`
+	KindObjectIDs []string `json:"kindObjectIds"`
+}
+
+// Lock-Key je Kind, damit ein Kind nicht parallel zwei Riegen zugeordnet wird
+func zuordnungKindLockKey(kindObjectID string) string {
+	return "kinderDerRiege|" + kindObjectID
+}
+
+// Sperrt alle Keys (sortiert, ohne Duplikate) ohne zu warten.
+// ok = false, wenn einer bereits gesperrt ist – dann ist nichts gesperrt.
+func (h *KindHandler) lockAll(keys ...string) (unlock func(), ok bool) {
+	keys = slices.Clone(keys)
+	sort.Strings(keys)
+	keys = slices.Compact(keys)
+
+	var gesperrt []chan struct{}
+	unlock = func() {
+		for _, l := range gesperrt {
+			<-l
+		}
+	}
+	for _, key := range keys {
+		lock := h.lockForKey(key)
+		select {
+		case lock <- struct{}{}:
+			gesperrt = append(gesperrt, lock)
+		default:
+			unlock()
+			return nil, false
+		}
+	}
+	return unlock, true
+}
+
+// Alle Zuordnungen einer Riege in Positionsreihenfolge
+func (h *KindHandler) zuordnungenDerRiege(ctx context.Context, riegeObjectID string) ([]parse.Object, error) {
+	query := parse.NewQuery().
+		PointerTo("riegenID", "Riege", riegeObjectID).
+		Order("position", "createdAt").
+		Limit(1000)
+	out, err := h.Parse.Query(ctx, "kinderDerRiege", query)
+	if err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+func zuordnungKind(z parse.Object) string {
+	var d strukturen.KinderDerRiege
+	if err := z.Decode(&d); err != nil || d.KindID == nil {
+		return ""
+	}
+	return d.KindID.ObjectID
+}
+
+// Kinder (objectIds) in der Reihenfolge der Zuordnungen
+func kinderReihenfolge(liste []parse.Object) []string {
+	ids := make([]string, 0, len(liste))
+	for _, z := range liste {
+		ids = append(ids, zuordnungKind(z))
+	}
+	return ids
+}
+
+// Fügt z an Position pos (1-basiert) ein; pos außerhalb → ans Ende
+func einfuegen(liste []parse.Object, z parse.Object, pos int) []parse.Object {
+	if pos <= 0 || pos > len(liste) {
+		return append(liste, z)
+	}
+	return slices.Insert(liste, pos-1, z)
+}
+
+// Entfernt die Zuordnung des Kindes; nil, wenn nicht enthalten
+func herausnehmen(liste []parse.Object, kindObjectID string) ([]parse.Object, parse.Object) {
+	for i, z := range liste {
+		if zuordnungKind(z) == kindObjectID {
+			return slices.Delete(slices.Clone(liste), i, i+1), z
+		}
+	}
+	return liste, nil
+}
+
+// Löscht die Zuordnung z eines Kindes; die übrigen Kinder der Riege
+// rücken auf. Scheitert das Nummerieren, wird alles zurückgesetzt.
+// Die Riege muss gesperrt sein.
+func (h *KindHandler) ausRiegeNehmen(ctx context.Context, z parse.Object) error {
+	riegeID := zuordnungRiege(z)
+	liste, err := h.zuordnungenDerRiege(ctx, riegeID)
+	if err != nil {
+		return err
+	}
+	rest, _ := herausnehmen(liste, zuordnungKind(z))
+	aenderungen := &zuordnungsAenderungen{h: h}
+	if err := aenderungen.loeschen(ctx, z); err != nil {
+		return err
+	}
+	if err := aenderungen.nummerieren(ctx, riegeID, rest); err != nil {
+		aenderungen.rueckgaengig(ctx)
+		return err
+	}
+	return nil
+}
+
+// ======  Änderungsprotokoll für die Kompensation  ======
+
+type zuordnungsStand struct {
+	objectID  string
+	werte     map[string]any // nil → Objekt wurde neu angelegt
+	geloescht map[string]any
+}
+
+type zuordnungsAenderungen struct {
+	h       *KindHandler
+	staende []zuordnungsStand
+}
+
+// Setzt die Positionen 1..n (und ggf. die Riege) gemäß der Reihenfolge
+func (a *zuordnungsAenderungen) nummerieren(ctx context.Context, riegeObjectID string, liste []parse.Object) error {
+	for i, z := range liste {
+		var d strukturen.KinderDerRiege
+		if err := z.Decode(&d); err != nil {
+			return err
+		}
+		neu := map[string]any{}
+		if d.Position != i+1 {
+			neu["position"] = i + 1
+		}
+		if d.RiegenID == nil || d.RiegenID.ObjectID != riegeObjectID {
+			neu["riegenID"] = strukturen.NewParsePointer("Riege", riegeObjectID)
+		}
+		if len(neu) == 0 {
+			continue
+		}
+		if _, err := a.h.Parse.Update(ctx, "kinderDerRiege", z.ObjectID(), neu, nil); err != nil {
+			return err
+		}
+		a.staende = append(a.staende, zuordnungsStand{
+			objectID: z.ObjectID(),
+			werte: map[string]any{
+				"position": d.Position,
+				"riegenID": d.RiegenID,
+			},
+		})
+	}
+	return nil
+}
+
+// Legt eine Zuordnung an und merkt sie sich für die Kompensation
+func (a *zuordnungsAenderungen) anlegen(ctx context.Context, z strukturen.KinderDerRiege) (parse.Object, error) {
+	out, err := a.h.Parse.Create(ctx, "kinderDerRiege", z)
+	if err != nil {
+		return nil, err
+	}
+	a.staende = append(a.staende, zuordnungsStand{objectID: out.ObjectID()})
+
+	obj := parse.Object{"objectId": out.ObjectID()}
+	b, _ := json.Marshal(z)
+	json.Unmarshal(b, &obj)
+	return obj, nil
+}
+
+// Löscht eine Zuordnung und merkt sie sich für die Kompensation
+func (a *zuordnungsAenderungen) loeschen(ctx context.Context, z parse.Object) error {
+	if err := a.h.Parse.Delete(ctx, "kinderDerRiege", z.ObjectID()); err != nil {
+		return err
+	}
+	var d strukturen.KinderDerRiege
+	z.Decode(&d)
+	a.staende = append(a.staende, zuordnungsStand{
+		objectID: z.ObjectID(),
+		geloescht: map[string]any{
+			"kindID":   d.KindID,
+			"riegenID": d.RiegenID,
+			"position": d.Position,
+		},
+	})
+	return nil
+}
+
+// Macht alle protokollierten Änderungen in umgekehrter Reihenfolge rückgängig.
+// Fehler dabei werden nur geloggt.
+func (a *zuordnungsAenderungen) rueckgaengig(ctx context.Context) {
+	for i := len(a.staende) - 1; i >= 0; i-- {
+		s := a.staende[i]
+		var err error
+		switch {
+		case s.geloescht != nil:
+			_, err = a.h.Parse.Create(ctx, "kinderDerRiege", s.geloescht)
+		case s.werte == nil:
+			err = a.h.Parse.Delete(ctx, "kinderDerRiege", s.objectID)
+		default:
+			_, err = a.h.Parse.Update(ctx, "kinderDerRiege", s.objectID, s.werte, nil)
+		}
+		if err != nil {
+			log.Printf("Kompensation kinderDerRiege %s fehlgeschlagen: %v", s.objectID, err)
+		}
+	}
+	a.staende = nil
+}
+
+// ===== POST /riege-zuordnung/verschieben =====
+func (h *KindHandler) MoveKindToRiege(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req KindVerschiebenRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.KindObjectID == "" || req.VonRiegeObjectID == "" || req.NachRiegeObjectID == "" || req.Position < 0 {
+		http.Error(w, "Pflichtfelder fehlen", http.StatusBadRequest)
+		return
+	}
+	if req.VonRiegeObjectID == req.NachRiegeObjectID {
+		http.Error(w, "Quell- und Zielriege sind gleich – Position per PUT ändern", http.StatusBadRequest)
+		return
+	}
+	// beide Riegen müssen verwaltet werden dürfen
+	if !id.darfRiege(req.VonRiegeObjectID) || !id.darfRiege(req.NachRiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riegen", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock auf beide Riegen und das Kind ----
+	unlock, ok := h.lockAll(
+		riegeLockKey(req.VonRiegeObjectID),
+		riegeLockKey(req.NachRiegeObjectID),
+		zuordnungKindLockKey(req.KindObjectID),
+	)
+	if !ok {
+		http.Error(w, "Riege wird gerade bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	// ---- Zielriege prüfen ----
+	ziel, err := h.Parse.Get(r.Context(), "Riege", req.NachRiegeObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Zielriege nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if ziel.Bool("wetttkampfBeendet") {
+		http.Error(w, "Wettkampf der Zielriege ist beendet", http.StatusConflict)
+		return
+	}
+
+	// ---- aktuelle Zuordnungen beider Riegen ----
+	von, err := h.zuordnungenDerRiege(r.Context(), req.VonRiegeObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	nach, err := h.zuordnungenDerRiege(r.Context(), req.NachRiegeObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	von, z := herausnehmen(von, req.KindObjectID)
+	if z == nil {
+		http.Error(w, "Zuordnung nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if req.Position > len(nach)+1 {
+		http.Error(w, "position liegt hinter dem Ende der Zielriege", http.StatusBadRequest)
+		return
+	}
+	nach = einfuegen(nach, z, req.Position)
+	position := req.Position
+	if position == 0 {
+		position = len(nach)
+	}
+
+	// ---- Zielriege zuerst (enthält das Kind), dann Lücke schließen ----
+	aenderungen := &zuordnungsAenderungen{h: h}
+	if err := aenderungen.nummerieren(r.Context(), req.NachRiegeObjectID, nach); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Verschieben fehlgeschlagen – Änderungen zurückgesetzt", http.StatusBadGateway)
+		return
+	}
+	if err := aenderungen.nummerieren(r.Context(), req.VonRiegeObjectID, von); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Verschieben fehlgeschlagen – Änderungen zurückgesetzt", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "verschieben", "kinderDerRiege", z.ObjectID(),
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.VonRiegeObjectID, "position": z.Int("position")},
+		map[string]any{"kindObjectId": req.KindObjectID, "riegeObjectId": req.NachRiegeObjectID, "position": position},
+		0)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  "Kind erfolgreich verschoben",
+		"position": position,
+	})
+}
+
+// ===== PUT /riege-zuordnung/reihenfolge =====
+// kindObjectIds muss genau die Kinder der Riege enthalten.
+func (h *KindHandler) ReorderRiege(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "PUT, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req RiegenReihenfolgeRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.RiegeObjectID == "" {
+		http.Error(w, "riegeObjectId fehlt", http.StatusBadRequest)
+		return
+	}
+	if !id.darfRiege(req.RiegeObjectID) {
+		http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+		return
+	}
+
+	// ---- Lock auf die Riege ----
+	unlock, ok := h.lockAll(riegeLockKey(req.RiegeObjectID))
+	if !ok {
+		http.Error(w, "Riege wird gerade bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	liste, err := h.zuordnungenDerRiege(r.Context(), req.RiegeObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- neue Reihenfolge muss genau die Kinder der Riege enthalten ----
+	nachKind := make(map[string]parse.Object, len(liste))
+	for _, z := range liste {
+		nachKind[zuordnungKind(z)] = z
+	}
+	if len(req.KindObjectIDs) != len(liste) {
+		http.Error(w, "kindObjectIds muss alle Kinder der Riege genau einmal enthalten", http.StatusBadRequest)
+		return
+	}
+	neu := make([]parse.Object, 0, len(liste))
+	for _, kindID := range req.KindObjectIDs {
+		z, ok := nachKind[kindID]
+		if !ok {
+			http.Error(w, "kindObjectIds muss alle Kinder der Riege genau einmal enthalten", http.StatusBadRequest)
+			return
+		}
+		delete(nachKind, kindID)
+		neu = append(neu, z)
+	}
+
+	aenderungen := &zuordnungsAenderungen{h: h}
+	if err := aenderungen.nummerieren(r.Context(), req.RiegeObjectID, neu); err != nil {
+		aenderungen.rueckgaengig(r.Context())
+		http.Error(w, "Neu nummerieren fehlgeschlagen – Änderungen zurückgesetzt", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "reihenfolge", "Riege", req.RiegeObjectID,
+		map[string]any{"kindObjectIds": kinderReihenfolge(liste)},
+		map[string]any{"kindObjectIds": req.KindObjectIDs},
+		0)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":       "Reihenfolge erfolgreich gespeichert",
+		"anzGeaendert":  len(aenderungen.staende),
+		"kindObjectIds": req.KindObjectIDs,
+	})
+}
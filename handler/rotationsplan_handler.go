@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/planung"
+	"sporttag/strukturen"
+)
+
+// POST – Rotationsplan berechnen (und optional speichern)
+type RotationsplanRequest struct {
+	Start             time.Time `json:"start"`
+	MinutenProStation int       `json:"minutenProStation"`
+	Speichern         bool      `json:"speichern,omitempty"`
+}
+
+// Der gespeicherte Plan wird immer komplett ersetzt
+const rotationsplanLockKey = "rotationsplan"
+
+// ===== Router für /rotationsplan =====
+func (h *KindHandler) RotationsplanRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetRotationsplan(w, r)
+	case http.MethodPost:
+		h.CreateRotationsplan(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+// GET /rotationsplan[?riegeObjectId=<id>][&stationObjectId=<id>]
+func (h *KindHandler) GetRotationsplan(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r); !ok {
+		return
+	}
+
+	planID, err := h.aktuellePlanID(r.Context())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	query := parse.NewQuery().Order("slot").Limit(1000)
+	if planID != "" {
+		query.EqualTo("planID", planID)
+	}
+	if riegeObjectID := r.URL.Query().Get("riegeObjectId"); riegeObjectID != "" {
+		query.PointerTo("riegenID", "Riege", riegeObjectID)
+	}
+	if stationObjectID := r.URL.Query().Get("stationObjectId"); stationObjectID != "" {
+		query.PointerTo("stationsID", "Station", stationObjectID)
+	}
+
+	out, err := h.Parse.Query(r.Context(), "rotationsplan", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// ===== Plan berechnen =====
+// Ohne speichern=true ist das nur eine Vorschau.
+func (h *KindHandler) CreateRotationsplan(w http.ResponseWriter, r *http.Request) {
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req RotationsplanRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Start.IsZero() || req.MinutenProStation <= 0 {
+		http.Error(w, "start und minutenProStation erforderlich", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Riegen und Stationen laden ----
+	riegenOut, err := h.Parse.Query(r.Context(), "Riege", parse.NewQuery().Limit(1000))
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	stationen, err := h.alleStationen(r.Context())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	riegen := make([]planung.RiegeInfo, 0, len(riegenOut.Results))
+	for _, rg := range riegenOut.Results {
+		riegen = append(riegen, planung.RiegeInfo{
+			ObjectID:     rg.ObjectID(),
+			RiegenNummer: rg.Int("riegenNummer"),
+			FuenfKampf:   rg.Bool("fuenfKampf"),
+		})
+	}
+	stationsInfos := make([]planung.StationInfo, 0, len(stationen))
+	for _, s := range stationen {
+		stationsInfos = append(stationsInfos, planung.StationInfo{
+			ObjectID:       s.ObjectID(),
+			StationsNummer: s.Int("stationsNummer"),
+			NurZehnKampf:   s.Bool("nurZehnKampf"),
+		})
+	}
+
+	plan, err := planung.Rotation(riegen, stationsInfos, req.Start.UTC(), req.MinutenProStation)
+	if err != nil {
+		http.Error(w, "Plan nicht möglich: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !req.Speichern {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(plan)
+		return
+	}
+
+	// ---- Lock auf den Plan ----
+	lock := h.lockForKey(rotationsplanLockKey)
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Rotationsplan wird bereits gespeichert", http.StatusConflict)
+		return
+	}
+
+	// ---- neuen Plan neben dem alten speichern ----
+	// Erst wenn alle neuen Einsätze stehen, wird der alte Plan entfernt;
+	// bis dahin liefern GET und der Abgleich weiter den alten Plan.
+	planID := neueRequestID()
+	ops := make([]parse.BatchOp, 0, len(plan.Einsaetze))
+	for _, e := range plan.Einsaetze {
+		ops = append(ops, parse.BatchOp{
+			Method:    http.MethodPost,
+			ClassName: "rotationsplan",
+			Body: strukturen.Rotationsplan{
+				RiegenID:   strukturen.NewParsePointer("Riege", e.RiegeObjectID),
+				StationsID: strukturen.NewParsePointer("Station", e.StationObjectID),
+				Slot:       e.Slot,
+				Beginn:     strukturen.NewParseDate(e.Beginn),
+				Ende:       strukturen.NewParseDate(e.Ende),
+				PlanID:     planID,
+			},
+		})
+	}
+	if err := h.batchAusfuehren(r.Context(), ops); err != nil {
+		log.Println("Rotationsplan speichern:", err)
+		// schon angelegte Einsätze des neuen Plans wieder entfernen
+		if err := h.rotationsplanEntfernen(r.Context(), parse.NewQuery().EqualTo("planID", planID)); err != nil {
+			log.Println("Rotationsplan aufräumen:", err)
+		}
+		http.Error(w, "Rotationsplan konnte nicht gespeichert werden", http.StatusBadGateway)
+		return
+	}
+
+	// ---- alten Plan entfernen ----
+	// Reste bleiben bei einem Fehler stehen, werden aber nicht mehr
+	// gelesen und beim nächsten Speichern mit entfernt.
+	alt, err := parse.QueryAll(r.Context(), h.Parse, "rotationsplan",
+		parse.NewQuery().NotEqualTo("planID", planID).Keys("objectId"))
+	if err != nil {
+		log.Println("Alten Rotationsplan laden:", err)
+	} else if err := h.rotationsplanLoeschen(r.Context(), alt); err != nil {
+		log.Println("Alten Rotationsplan löschen:", err)
+	}
+
+	// ein Eintrag für den ganzen Plan (keine einzelne objectId)
+	h.audit(r, id, "speichern", "rotationsplan", "",
+		map[string]any{"eintraege": len(alt)},
+		map[string]any{"planID": planID, "start": req.Start, "minutenProStation": req.MinutenProStation, "einsaetze": plan.Einsaetze},
+		0)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// Vergleicht eine RiegenLogging-Meldung mit dem gespeicherten Plan.
+// nil, wenn für Riege und Station nichts geplant ist.
+func (h *KindHandler) planAbgleich(ctx context.Context, riegeObjectID, stationObjectID string, um time.Time) (map[string]any, error) {
+	planID, err := h.aktuellePlanID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := parse.NewQuery().
+		PointerTo("riegenID", "Riege", riegeObjectID).
+		PointerTo("stationsID", "Station", stationObjectID).
+		Limit(1)
+	if planID != "" {
+		query.EqualTo("planID", planID)
+	}
+	out, err := h.Parse.Query(ctx, "rotationsplan", query)
+	if err != nil || len(out.Results) == 0 {
+		return nil, err
+	}
+
+	var geplant strukturen.Rotationsplan
+	if err := out.Results[0].Decode(&geplant); err != nil || geplant.Ende == nil {
+		return nil, err
+	}
+
+	// positiv = später als geplant
+	return map[string]any{
+		"slot":              geplant.Slot,
+		"geplantBis":        geplant.Ende,
+		"abweichungMinuten": int(math.Round(um.Sub(geplant.Ende.Time).Minutes())),
+	}, nil
+}
+
+// planID des zuletzt gespeicherten Plans; "" für Pläne ohne planID
+// (vor Einführung gespeichert) oder wenn kein Plan existiert
+func (h *KindHandler) aktuellePlanID(ctx context.Context) (string, error) {
+	query := parse.NewQuery().Order("-createdAt").Keys("planID").Limit(1)
+	out, err := h.Parse.Query(ctx, "rotationsplan", query)
+	if err != nil || len(out.Results) == 0 {
+		return "", err
+	}
+	return out.Results[0].String("planID"), nil
+}
+
+// Löscht alle Einsätze zur Abfrage
+func (h *KindHandler) rotationsplanEntfernen(ctx context.Context, query *parse.Query) error {
+	eintraege, err := parse.QueryAll(ctx, h.Parse, "rotationsplan", query.Keys("objectId"))
+	if err != nil {
+		return err
+	}
+	return h.rotationsplanLoeschen(ctx, eintraege)
+}
+
+func (h *KindHandler) rotationsplanLoeschen(ctx context.Context, eintraege []parse.Object) error {
+	ops := make([]parse.BatchOp, 0, len(eintraege))
+	for _, e := range eintraege {
+		ops = append(ops, parse.BatchOp{
+			Method:    http.MethodDelete,
+			ClassName: "rotationsplan",
+			ObjectID:  e.ObjectID(),
+		})
+	}
+	return h.batchAusfuehren(ctx, ops)
+}
+
+// Führt Batch-Operationen aus; der erste Einzelfehler wird zurückgegeben.
+// Parse-Batches sind nicht transaktional – bereits ausgeführte Operationen
+// bleiben bestehen.
+func (h *KindHandler) batchAusfuehren(ctx context.Context, ops []parse.BatchOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	results, err := h.Parse.Batch(ctx, ops)
+	if err != nil {
+		return err
+	}
+	for i, res := range results {
+		if res.Error != nil {
+			return fmt.Errorf("Operation %d: %w", i, res.Error)
+		}
+	}
+	return nil
+}
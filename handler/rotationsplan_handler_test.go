@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"sporttag/parse"
+)
+
+// Parse, bei dem im Batch jedes Anlegen nach dem ersten scheitert
+type anlegenFehler struct {
+	*parse.MemoryClient
+}
+
+func (a anlegenFehler) Batch(ctx context.Context, ops []parse.BatchOp) ([]parse.BatchResult, error) {
+	results := make([]parse.BatchResult, 0, len(ops))
+	angelegt := 0
+	for _, op := range ops {
+		if op.Method == http.MethodPost && angelegt > 0 {
+			results = append(results, parse.BatchResult{Error: &parse.Error{Code: 155, Message: "Request limit exceeded"}})
+			continue
+		}
+		res, err := a.MemoryClient.Batch(ctx, []parse.BatchOp{op})
+		if err != nil {
+			return results, err
+		}
+		if op.Method == http.MethodPost {
+			angelegt++
+		}
+		results = append(results, res...)
+	}
+	return results, nil
+}
+
+// Ein gescheitertes Speichern lässt den bisherigen Plan unverändert
+func TestRotationsplanErsetzen(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	riegeAnlegen(t, mem, 1, false)
+	riegeAnlegen(t, mem, 2, false)
+	for n := 1; n <= 2; n++ {
+		if _, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": n, "version": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// planID je Eintrag des gespeicherten Plans
+	planIDs := func() map[string]int {
+		t.Helper()
+		alle, err := parse.QueryAll(ctx, mem, "rotationsplan", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]int{}
+		for _, e := range alle {
+			ids[e.String("planID")]++
+		}
+		return ids
+	}
+	speichern := func(client parse.Client, wantStatus int) {
+		t.Helper()
+		h.Parse = client
+		req := RotationsplanRequest{Start: time.Date(2026, 6, 20, 9, 0, 0, 0, time.UTC), MinutenProStation: 15, Speichern: true}
+		w := anfrage(t, h, h.CreateRotationsplan, http.MethodPost, &testAdmin, req)
+		if w.Code != wantStatus {
+			t.Fatalf("Status %d, want %d: %s", w.Code, wantStatus, w.Body.String())
+		}
+		h.Parse = mem
+	}
+
+	speichern(mem, http.StatusCreated)
+	erster := planIDs()
+	if len(erster) != 1 {
+		t.Fatalf("Plan-IDs %v, want genau eine", erster)
+	}
+	alt, err := h.aktuellePlanID(ctx)
+	if err != nil || erster[alt] == 0 {
+		t.Fatalf("aktuelle planID %q (%v), want aus %v", alt, err, erster)
+	}
+
+	speichern(anlegenFehler{mem}, http.StatusBadGateway)
+	if got := planIDs(); len(got) != 1 || got[alt] != erster[alt] {
+		t.Errorf("nach Fehler %v, want unverändert %v", got, erster)
+	}
+
+	speichern(mem, http.StatusCreated)
+	if got := planIDs(); len(got) != 1 || got[alt] != 0 {
+		t.Errorf("nach erneutem Speichern %v, want nur den neuen Plan", got)
+	}
+}
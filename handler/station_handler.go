@@ -0,0 +1,659 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+	"sporttag/wertung"
+)
+
+// Maximale Größe der Stationsbeschreibung (PDF oder Bild)
+const maxBeschreibungBytes = 10 << 20
+
+// Erlaubte Dateitypen der Stationsbeschreibung (per Content-Sniffing geprüft)
+var beschreibungTypen = map[string]string{
+	"application/pdf": ".pdf",
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/webp":      ".webp",
+}
+
+//
+// ===== Request-Strukturen =====
+//
+
+// PUT – Änderung einer Station (nur die angegebenen Felder)
+type StationUpdateRequest struct {
+	ObjectID        string          `json:"objectId"`
+	Update          json.RawMessage `json:"update"`
+	ExpectedVersion int             `json:"expectedVersion"`
+}
+
+// DELETE – Löschen einer Station
+type StationDeleteRequest struct {
+	ObjectID        string `json:"objectId"`
+	ExpectedVersion int    `json:"expectedVersion"`
+}
+
+// Lock-Keys: Station über objectId, Eindeutigkeit über die Stationsnummer
+func stationLockKey(objectID string) string {
+	return "Station|" + objectID
+}
+
+func stationsNummerLockKey(nummer int) string {
+	return "Station#" + strconv.Itoa(nummer)
+}
+
+// ===== Router für /station =====
+func (h *KindHandler) StationRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, PUT, DELETE, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Routen zu den jeweiligen Methoden
+	switch r.Method {
+	case http.MethodGet:
+		h.GetStationen(w, r)
+	case http.MethodPost:
+		h.CreateStation(w, r)
+	case http.MethodPut:
+		h.UpdateStation(w, r)
+	case http.MethodDelete:
+		h.DeleteStation(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== READ =====
+// Stationshelfer holen sich hier auch die Beschreibung (beschreibung.url)
+func (h *KindHandler) GetStationen(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r); !ok {
+		return
+	}
+
+	query := parse.NewQuery().Order("stationsNummer").Limit(1000)
+	out, err := h.Parse.Query(r.Context(), "Station", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
+
+// ===== CREATE =====
+func (h *KindHandler) CreateStation(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var station strukturen.Station
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&station); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if station.StationsName == "" || station.StationsNummer <= 0 {
+		http.Error(w, "Pflichtfelder fehlen", http.StatusBadRequest)
+		return
+	}
+	if station.Beschreibung != nil {
+		http.Error(w, "beschreibung nur per Upload (/station/beschreibung)", http.StatusBadRequest)
+		return
+	}
+	if err := h.pruefeStationsWertung(&station); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ---- Lock auf die Stationsnummer ----
+	lock := h.lockForKey(stationsNummerLockKey(station.StationsNummer))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Stationsnummer wird bereits vergeben", http.StatusConflict)
+		return
+	}
+
+	// ---- Eindeutigkeit der Stationsnummer ----
+	belegt, err := h.stationsNummerBelegt(r, station.StationsNummer, "")
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if belegt {
+		http.Error(w, "Stationsnummer bereits vergeben", http.StatusConflict)
+		return
+	}
+
+	// ---- Station anlegen ----
+	payload := map[string]any{
+		"stationsName":   station.StationsName,
+		"stationsNummer": station.StationsNummer,
+		"nurZehnKampf":   station.NurZehnKampf,
+		"disziplin":      station.Disziplin,
+		"richtung":       station.Richtung,
+		"tabelle":        station.Tabelle,
+		"version":        1,
+	}
+	out, err := h.Parse.Create(r.Context(), "Station", payload)
+	if err != nil {
+		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, id, "anlegen", "Station", out.ObjectID(), nil, payload, 1)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  "Station erfolgreich angelegt",
+		"objectId": out.ObjectID(),
+	})
+}
+
+// ===== UPDATE =====
+func (h *KindHandler) UpdateStation(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	// ---- Erlaubte Update-Felder ----
+	allowedUpdateKeys := map[string]bool{
+		"stationsName":   true,
+		"stationsNummer": true,
+		"nurZehnKampf":   true,
+		"disziplin":      true,
+		"richtung":       true,
+		"tabelle":        true,
+	}
+
+	var req StationUpdateRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ObjectID == "" {
+		http.Error(w, "objectId fehlt", http.StatusBadRequest)
+		return
+	}
+	if req.ExpectedVersion <= 0 {
+		http.Error(w, "expectedVersion fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Validate Update Keys ----
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(req.Update, &raw); err != nil || len(raw) == 0 {
+		http.Error(w, "Ungültiges Update-JSON", http.StatusBadRequest)
+		return
+	}
+	for k := range raw {
+		if !allowedUpdateKeys[k] {
+			http.Error(w, "Ungültiges Update-Feld: "+k, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var upd strukturen.Station
+	if err := json.NewDecoder(bytes.NewReader(req.Update)).Decode(&upd); err != nil {
+		http.Error(w, "Ungültiges Update-JSON", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Lock auf die Station ----
+	lock := h.lockForKey(stationLockKey(req.ObjectID))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Konflikt: Station wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+
+	update := map[string]interface{}{}
+	if _, ok := raw["stationsName"]; ok {
+		if upd.StationsName == "" {
+			http.Error(w, "stationsName darf nicht leer sein", http.StatusBadRequest)
+			return
+		}
+		update["stationsName"] = upd.StationsName
+	}
+	if _, ok := raw["nurZehnKampf"]; ok {
+		update["nurZehnKampf"] = upd.NurZehnKampf
+	}
+
+	// ---- Wertung: nur als Ganzes mit dem aktuellen Stand prüfen ----
+	_, d := raw["disziplin"]
+	_, ri := raw["richtung"]
+	_, t := raw["tabelle"]
+	if d || ri || t {
+		aktuell, err := h.Parse.Get(r.Context(), "Station", req.ObjectID)
+		if parse.IsNotFound(err) {
+			http.Error(w, "Station nicht gefunden", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		neu := strukturen.Station{
+			Disziplin: aktuell.String("disziplin"),
+			Richtung:  aktuell.String("richtung"),
+			Tabelle:   aktuell.String("tabelle"),
+		}
+		if d {
+			neu.Disziplin = upd.Disziplin
+			if !ri {
+				// neue Disziplin → Standardrichtung, sofern nicht angegeben
+				neu.Richtung = ""
+			}
+		}
+		if ri {
+			neu.Richtung = upd.Richtung
+		}
+		if t {
+			neu.Tabelle = upd.Tabelle
+		}
+		if err := h.pruefeStationsWertung(&neu); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		update["disziplin"] = neu.Disziplin
+		update["richtung"] = neu.Richtung
+		update["tabelle"] = neu.Tabelle
+	}
+
+	// ---- neue Stationsnummer: zusätzlich Lock + Eindeutigkeit ----
+	if _, ok := raw["stationsNummer"]; ok {
+		if upd.StationsNummer <= 0 {
+			http.Error(w, "stationsNummer ungültig", http.StatusBadRequest)
+			return
+		}
+
+		nummerLock := h.lockForKey(stationsNummerLockKey(upd.StationsNummer))
+		select {
+		case nummerLock <- struct{}{}:
+			defer func() { <-nummerLock }()
+		default:
+			http.Error(w, "Stationsnummer wird bereits vergeben", http.StatusConflict)
+			return
+		}
+
+		belegt, err := h.stationsNummerBelegt(r, upd.StationsNummer, req.ObjectID)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		if belegt {
+			http.Error(w, "Stationsnummer bereits vergeben", http.StatusConflict)
+			return
+		}
+		update["stationsNummer"] = upd.StationsNummer
+	}
+
+	vorher := h.auditStand(r.Context(), "Station", req.ObjectID, update)
+	// Kopie: updateWithVersion ergänzt die Versionserhöhung
+	out, err := h.updateWithVersion(r.Context(), "Station", req.ObjectID, req.ExpectedVersion, maps.Clone(update))
+	if parse.IsNotFound(err) {
+		http.Error(w, "Konflikt: Station nicht gefunden oder Version veraltet", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "aendern", "Station", req.ObjectID, vorher, update, req.ExpectedVersion+1)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Station erfolgreich aktualisiert",
+		"newVersion": req.ExpectedVersion + 1,
+		"updatedAt":  out["updatedAt"],
+	})
+}
+
+// Einträge, die auf eine Station verweisen
+type stationsBezug struct {
+	klasse string
+	query  *parse.Query
+	was    string // für die Fehlermeldung
+}
+
+func stationsBezuege(stationID string) []stationsBezug {
+	return []stationsBezug{
+		{"resultate", parse.NewQuery().PointerTo("stationsID", "Station", stationID), "Resultate"},
+		{"rotationsplan", parse.NewQuery().PointerTo("stationsID", "Station", stationID), "Einsätze im Rotationsplan"},
+		{"riegenLogging", parse.NewQuery().Or(
+			parse.NewQuery().PointerTo("stationsID", "Station", stationID),
+			parse.NewQuery().EqualTo("erledigteStationen", stationID),
+		), "Fortschrittseinträge"},
+		{"Benutzerrolle", parse.NewQuery().PointerTo("stationsID", "Station", stationID), "Stationshelfer"},
+	}
+}
+
+// ===== DELETE =====
+// Stationen, auf die noch Resultate, Rotationsplan, Riegenfortschritt oder
+// Stationshelfer verweisen, werden nicht gelöscht
+func (h *KindHandler) DeleteStation(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req StationDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ObjectID == "" || req.ExpectedVersion <= 0 {
+		http.Error(w, "objectId oder expectedVersion fehlt", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Lock auf die Station und den Rotationsplan ----
+	unlock, ok := h.lockAll(stationLockKey(req.ObjectID), rotationsplanLockKey)
+	if !ok {
+		http.Error(w, "Konflikt: Station wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+	defer unlock()
+
+	// ---- noch verwendet? ----
+	for _, bezug := range stationsBezuege(req.ObjectID) {
+		out, err := h.Parse.Query(r.Context(), bezug.klasse, bezug.query.Limit(0).Count())
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		if out.Count > 0 {
+			http.Error(w, "Station hat noch "+strconv.Itoa(out.Count)+" "+bezug.was+" und kann nicht gelöscht werden", http.StatusConflict)
+			return
+		}
+	}
+
+	// ---- Stand vor dem Löschen (Audit-Log) ----
+	vorher, err := h.Parse.Get(r.Context(), "Station", req.ObjectID)
+	if err != nil && !parse.IsNotFound(err) {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Version prüfen ----
+	// DELETE kennt kein where, daher zuerst bedingte Versionserhöhung
+	if _, err := h.updateWithVersion(r.Context(), "Station", req.ObjectID, req.ExpectedVersion, map[string]interface{}{}); err != nil {
+		if parse.IsNotFound(err) {
+			http.Error(w, "Konflikt: Station nicht gefunden oder Version veraltet", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Delete ----
+	if err := h.Parse.Delete(r.Context(), "Station", req.ObjectID); err != nil {
+		http.Error(w, "Löschen fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, id, "loeschen", "Station", req.ObjectID, vorher, nil, req.ExpectedVersion+1)
+	if alt := beschreibungVon(vorher); alt != "" {
+		if err := h.Parse.DeleteFile(r.Context(), alt); err != nil {
+			log.Printf("Beschreibung %s nicht gelöscht: %v", alt, err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Station erfolgreich gelöscht",
+	})
+}
+
+// ===== Upload der Beschreibung =====
+// POST /station/beschreibung?objectId=<id>&expectedVersion=<n>
+// multipart/form-data, Feld "datei" (PDF oder Bild).
+// Die Datei wird über die Parse Files API gespeichert und in
+// Station.beschreibung verlinkt. Scheitert das Verknüpfen, wird die
+// Datei wieder gelöscht, sonst die bisherige Beschreibung.
+func (h *KindHandler) UploadStationBeschreibung(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "POST, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	objectID := r.URL.Query().Get("objectId")
+	expectedVersion, _ := strconv.Atoi(r.URL.Query().Get("expectedVersion"))
+	if objectID == "" || expectedVersion <= 0 {
+		http.Error(w, "objectId oder expectedVersion fehlt", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Datei einlesen ----
+	r.Body = http.MaxBytesReader(w, r.Body, maxBeschreibungBytes+1<<20)
+	if err := r.ParseMultipartForm(maxBeschreibungBytes); err != nil {
+		http.Error(w, "Ungültiger Upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	datei, kopf, err := r.FormFile("datei")
+	if err != nil {
+		http.Error(w, "Feld 'datei' fehlt", http.StatusBadRequest)
+		return
+	}
+	defer datei.Close()
+
+	if kopf.Size > maxBeschreibungBytes {
+		http.Error(w, "Datei zu groß (max. 10 MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	inhalt, err := io.ReadAll(datei)
+	if err != nil {
+		http.Error(w, "Datei konnte nicht gelesen werden", http.StatusBadRequest)
+		return
+	}
+
+	// ---- Typ anhand des Inhalts prüfen, nicht anhand des Namens ----
+	contentType := http.DetectContentType(inhalt)
+	endung, erlaubt := beschreibungTypen[contentType]
+	if !erlaubt {
+		http.Error(w, "Nur PDF oder Bild erlaubt, erkannt: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// ---- Lock auf die Station ----
+	lock := h.lockForKey(stationLockKey(objectID))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Konflikt: Station wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+
+	// ---- Station vorhanden? ----
+	station, err := h.Parse.Get(r.Context(), "Station", objectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Station nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if station.Int("version") != expectedVersion {
+		http.Error(w, "Konflikt: Version veraltet", http.StatusConflict)
+		return
+	}
+
+	// ---- Upload über Parse Files API ----
+	name := beschreibungDateiname(kopf.Filename, station.Int("stationsNummer"), endung)
+	file, err := h.Parse.UploadFile(r.Context(), name, contentType, bytes.NewReader(inhalt))
+	if err != nil {
+		http.Error(w, "Upload fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+
+	// ---- mit Station verknüpfen ----
+	update := map[string]interface{}{
+		"beschreibung": file,
+	}
+	out, err := h.updateWithVersion(r.Context(), "Station", objectID, expectedVersion, update)
+	if err != nil {
+		// nicht verknüpfte Datei wieder entfernen, sonst bleibt sie verwaist liegen
+		if derr := h.Parse.DeleteFile(r.Context(), file.Name); derr != nil {
+			log.Printf("Beschreibung %s nicht gelöscht: %v", file.Name, derr)
+		}
+		if parse.IsNotFound(err) {
+			http.Error(w, "Konflikt: Datensatz wurde zwischenzeitlich geändert", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		return
+	}
+	h.audit(r, id, "beschreibung", "Station", objectID,
+		map[string]any{"beschreibung": station["beschreibung"]},
+		map[string]any{"beschreibung": file},
+		expectedVersion+1)
+
+	// ---- ersetzte Datei entfernen ----
+	if alt := beschreibungVon(station); alt != "" {
+		if err := h.Parse.DeleteFile(r.Context(), alt); err != nil {
+			log.Printf("Alte Beschreibung %s nicht gelöscht: %v", alt, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":      "Beschreibung erfolgreich gespeichert",
+		"beschreibung": file,
+		"newVersion":   expectedVersion + 1,
+		"updatedAt":    out["updatedAt"],
+	})
+}
+
+// Dateiname der verknüpften Beschreibung, "" wenn keine
+func beschreibungVon(station parse.Object) string {
+	var s struct {
+		Beschreibung *strukturen.ParseFile `json:"beschreibung"`
+	}
+	if station == nil || station.Decode(&s) != nil || s.Beschreibung == nil {
+		return ""
+	}
+	return s.Beschreibung.Name
+}
+
+// Parse erlaubt in Dateinamen nur Buchstaben, Ziffern, Punkt, Unterstrich und Bindestrich
+var unerlaubteZeichen = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func beschreibungDateiname(original string, stationsNummer int, endung string) string {
+	basis := unerlaubteZeichen.ReplaceAllString(filepath.Base(original), "_")
+	basis = basis[:len(basis)-len(filepath.Ext(basis))]
+	if basis == "" || basis == "_" {
+		basis = "beschreibung"
+	}
+	return "station" + strconv.Itoa(stationsNummer) + "_" + basis + endung
+}
+
+// Prüft, ob eine andere Station (≠ ausserObjectID) die Nummer bereits trägt
+func (h *KindHandler) stationsNummerBelegt(r *http.Request, nummer int, ausserObjectID string) (bool, error) {
+	query := parse.NewQuery().EqualTo("stationsNummer", nummer).Keys("objectId")
+	if ausserObjectID != "" {
+		query.NotEqualTo("objectId", ausserObjectID)
+	}
+	out, err := h.Parse.Query(r.Context(), "Station", query)
+	if err != nil {
+		return false, err
+	}
+	return len(out.Results) > 0, nil
+}
+
+// Prüft Disziplin, Richtung und Punktetabelle einer Station.
+// Ohne richtung gilt die übliche Richtung der Disziplin.
+func (h *KindHandler) pruefeStationsWertung(s *strukturen.Station) error {
+	if s.Disziplin == "" {
+		if s.Richtung != "" || s.Tabelle != "" {
+			return errors.New("richtung und tabelle erfordern eine disziplin")
+		}
+		return nil
+	}
+
+	d := wertung.Disziplin(s.Disziplin)
+	if !d.Gueltig() {
+		return errors.New("disziplin muss zeit, weite oder anzahl sein")
+	}
+	if s.Richtung == "" {
+		s.Richtung = string(wertung.StandardRichtung(d))
+	}
+	if !wertung.Richtung(s.Richtung).Gueltig() {
+		return errors.New("richtung muss kleinerBesser oder groesserBesser sein")
+	}
+
+	if s.Tabelle != "" {
+		tab, ok := h.Wertung.Tabelle(s.Tabelle)
+		if !ok {
+			return errors.New("Punktetabelle " + s.Tabelle + " ist nicht vorhanden")
+		}
+		if tab.Disziplin != d {
+			return errors.New("Punktetabelle " + s.Tabelle + " gehört zur Disziplin " + string(tab.Disziplin))
+		}
+	}
+	return nil
+}
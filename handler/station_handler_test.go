@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// Parse, bei dem jedes Update scheitert
+type updateFehler struct {
+	*parse.MemoryClient
+}
+
+func (updateFehler) Update(ctx context.Context, className, objectID string, data any, where map[string]any) (parse.Object, error) {
+	return nil, &parse.Error{Code: 500, Message: "Internal Server Error"}
+}
+
+// POST /station/beschreibung mit einer kleinen PDF-Datei als Admin
+func beschreibungHochladen(t *testing.T, h *KindHandler, objectID string, expectedVersion int) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("datei", "lauf.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("%PDF-1.4\n"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/?objectId="+objectID+"&expectedVersion="+strconv.Itoa(expectedVersion), &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	tid := testAdmin
+	tid.Ablauf = time.Now().Add(time.Hour).Unix()
+	token, err := h.signToken(tid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.UploadStationBeschreibung(w, r)
+	return w
+}
+
+// Scheitert das Verknüpfen mit der Station, wird die Datei wieder gelöscht
+func TestUploadStationBeschreibungAufraeumen(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	station, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": 3, "version": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Parse = updateFehler{mem}
+
+	w := beschreibungHochladen(t, h, station.ObjectID(), 1)
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Status %d, want %d: %s", w.Code, http.StatusBadGateway, w.Body.String())
+	}
+	if n := mem.AnzahlDateien(); n != 0 {
+		t.Errorf("%d Dateien übrig, want 0", n)
+	}
+}
+
+// Eine neue Beschreibung ersetzt die alte Datei, Löschen der Station entfernt sie
+func TestUploadStationBeschreibungErsetzen(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	station, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": 3, "version": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for version := 1; version <= 2; version++ {
+		if w := beschreibungHochladen(t, h, station.ObjectID(), version); w.Code != http.StatusOK {
+			t.Fatalf("Upload %d: Status %d: %s", version, w.Code, w.Body.String())
+		}
+		if n := mem.AnzahlDateien(); n != 1 {
+			t.Errorf("nach Upload %d: %d Dateien, want 1", version, n)
+		}
+	}
+
+	w := anfrage(t, h, h.DeleteStation, http.MethodDelete, &testAdmin, StationDeleteRequest{ObjectID: station.ObjectID(), ExpectedVersion: 3})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Löschen: Status %d: %s", w.Code, w.Body.String())
+	}
+	if n := mem.AnzahlDateien(); n != 0 {
+		t.Errorf("nach dem Löschen %d Dateien, want 0", n)
+	}
+}
+
+// Solange etwas auf die Station verweist, wird sie nicht gelöscht
+func TestDeleteStationBezuege(t *testing.T) {
+	tests := []struct {
+		name    string
+		klasse  string
+		eintrag func(stationID string) map[string]any
+	}{
+		{"Resultat", "resultate", func(s string) map[string]any {
+			return map[string]any{"stationsID": strukturen.NewParsePointer("Station", s)}
+		}},
+		{"Rotationsplan", "rotationsplan", func(s string) map[string]any {
+			return map[string]any{"stationsID": strukturen.NewParsePointer("Station", s), "slot": 1}
+		}},
+		{"zuletzt absolviert", "riegenLogging", func(s string) map[string]any {
+			return map[string]any{"stationsID": strukturen.NewParsePointer("Station", s)}
+		}},
+		{"erledigt", "riegenLogging", func(s string) map[string]any {
+			return map[string]any{"erledigteStationen": []string{"andere", s}}
+		}},
+		{"Stationshelfer", "Benutzerrolle", func(s string) map[string]any {
+			return map[string]any{"rolle": "stationshelfer", "stationsID": strukturen.NewParsePointer("Station", s)}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mem := testHandler(t)
+			ctx := context.Background()
+			station, err := mem.Create(ctx, "Station", map[string]any{"stationsNummer": 1, "version": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			eintrag, err := mem.Create(ctx, tt.klasse, tt.eintrag(station.ObjectID()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := StationDeleteRequest{ObjectID: station.ObjectID(), ExpectedVersion: 1}
+
+			if w := anfrage(t, h, h.DeleteStation, http.MethodDelete, &testAdmin, req); w.Code != http.StatusConflict {
+				t.Fatalf("Status %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+			}
+			if err := mem.Delete(ctx, tt.klasse, eintrag.ObjectID()); err != nil {
+				t.Fatal(err)
+			}
+			if w := anfrage(t, h, h.DeleteStation, http.MethodDelete, &testAdmin, req); w.Code != http.StatusOK {
+				t.Fatalf("ohne Bezug: Status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+		})
+	}
+}
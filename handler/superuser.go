@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Superuser  ======
+//
+// Nach der Anmeldefrist (Deadline) dürfen nur noch Superuser (bzw. Admins,
+// siehe auth.go) Kinder registrieren, ändern oder löschen. Die Anmeldung
+// erfolgt per HTTP Basic Auth mit Name und eigenem Passwort des
+// Organisators. Hinterlegt sind nur Hashes (config.json "superuser":
+// Name → Hash, erzeugt mit `go run . passwort-hash`), daher ist der
+// protokollierte Name immer der des angemeldeten Organisators.
+// Jede Änderung nach der Frist landet in der Klasse "superuserProtokoll".
+
+// Format der Hashes: pbkdf2-sha256$<Iterationen>$<Salt>$<Schlüssel> (base64)
+const (
+	passwortHashVerfahren   = "pbkdf2-sha256"
+	passwortHashIterationen = 600_000
+	passwortHashLaenge      = 32
+)
+
+// Vergleichshash für unbekannte Namen, damit die Antwortzeit nicht
+// verrät, welche Organisatoren es gibt
+var unbekannterSuperuser = sync.OnceValue(func() string {
+	hash, _ := PasswortHash("")
+	return hash
+})
+
+// PasswortHash erzeugt den Hash eines Superuser-Passworts für config.json
+func PasswortHash(passwort string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	schluessel, err := pbkdf2.Key(sha256.New, passwort, salt, passwortHashIterationen, passwortHashLaenge)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		passwortHashVerfahren,
+		strconv.Itoa(passwortHashIterationen),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(schluessel),
+	}, "$"), nil
+}
+
+// Prüft ein Passwort gegen einen Hash aus PasswortHash
+func passwortPasst(hash, passwort string) bool {
+	teile := strings.Split(hash, "$")
+	if len(teile) != 4 || teile[0] != passwortHashVerfahren {
+		return false
+	}
+	iterationen, err := strconv.Atoi(teile[1])
+	if err != nil || iterationen <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(teile[2])
+	if err != nil {
+		return false
+	}
+	soll, err := base64.RawStdEncoding.DecodeString(teile[3])
+	if err != nil || len(soll) == 0 {
+		return false
+	}
+	ist, err := pbkdf2.Key(sha256.New, passwort, salt, iterationen, len(soll))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(ist, soll) == 1
+}
+
+// Prüft einen konfigurierten Hash beim Start
+func PasswortHashGueltig(hash string) bool {
+	teile := strings.Split(hash, "$")
+	return len(teile) == 4 && teile[0] == passwortHashVerfahren
+}
+
+// Prüft die Superuser-Anmeldung; liefert den Namen des Organisators.
+// gesendet = true, wenn überhaupt Zugangsdaten mitgeschickt wurden.
+func (h *KindHandler) superuser(r *http.Request) (name string, ok bool, gesendet bool) {
+	name, pass, gesendet := r.BasicAuth()
+	if !gesendet {
+		return "", false, false
+	}
+	hash, bekannt := h.Superuser[name]
+	if !bekannt || name == "" {
+		passwortPasst(unbekannterSuperuser(), pass)
+		return "", false, true
+	}
+	if !passwortPasst(hash, pass) {
+		return "", false, true
+	}
+	return name, true, true
+}
+
+// Prüft die Anmeldefrist.
+// Vor der Deadline: ("", true). Nach der Deadline nur für Admins
+// (Superuser oder Rolle admin): (Name, true) – der Aufrufer protokolliert
+// die Änderung anschließend mit logOverride. Sonst wurde 403 geschrieben.
+func (h *KindHandler) checkDeadline(w http.ResponseWriter, id *Identitaet) (override string, ok bool) {
+	if !time.Now().UTC().After(h.Deadline) {
+		return "", true
+	}
+
+	if id != nil && id.Rolle == RolleAdmin {
+		return id.Name, true
+	}
+	http.Error(w, "Anmeldung geschlossen", http.StatusForbidden)
+	return "", false
+}
+
+// Hält eine Änderung nach der Anmeldefrist im Protokoll fest.
+// Ein Fehler beim Protokollieren macht die Änderung nicht rückgängig,
+// wird aber geloggt.
+func (h *KindHandler) logOverride(r *http.Request, akteur, aktion, klasse, objektID string) {
+	eintrag := strukturen.SuperuserProtokoll{
+		Akteur:    akteur,
+		Aktion:    aktion,
+		Klasse:    klasse,
+		ObjektID:  objektID,
+		Zeitpunkt: strukturen.NewParseDate(time.Now()),
+	}
+	if _, err := h.Parse.Create(r.Context(), "superuserProtokoll", eintrag); err != nil {
+		log.Printf("Superuser-Protokoll fehlgeschlagen (%s %s %s/%s): %v",
+			akteur, aktion, klasse, objektID, err)
+	}
+}
+
+// ======  GET /superuser-protokoll – nur für Admins ======
+func (h *KindHandler) SuperuserProtokollRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	query := parse.NewQuery().Order("-createdAt").Limit(1000)
+	out, err := h.Parse.Query(r.Context(), "superuserProtokoll", query)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": out.Results,
+	})
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSuperuserAnmeldung(t *testing.T) {
+	hashAnna, err := PasswortHash("geheim-anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashBen, err := PasswortHash("geheim-ben")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &KindHandler{Superuser: map[string]string{"Anna": hashAnna, "Ben": hashBen}}
+
+	tests := []struct {
+		name         string
+		user, pass   string
+		ohneAuth     bool
+		wantName     string
+		wantOK       bool
+		wantGesendet bool
+	}{
+		{"ohne Zugangsdaten", "", "", true, "", false, false},
+		{"eigenes Passwort", "Anna", "geheim-anna", false, "Anna", true, true},
+		{"anderer Organisator", "Ben", "geheim-ben", false, "Ben", true, true},
+		// der Name kann nicht mit fremdem Passwort vorgetäuscht werden
+		{"fremdes Passwort", "Anna", "geheim-ben", false, "", false, true},
+		{"unbekannter Name", "Cleo", "geheim-anna", false, "", false, true},
+		{"leerer Name", "", "geheim-anna", false, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if !tt.ohneAuth {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			name, ok, gesendet := h.superuser(r)
+			if name != tt.wantName || ok != tt.wantOK || gesendet != tt.wantGesendet {
+				t.Errorf("superuser() = (%q, %v, %v), want (%q, %v, %v)",
+					name, ok, gesendet, tt.wantName, tt.wantOK, tt.wantGesendet)
+			}
+		})
+	}
+}
+
+func TestPasswortHash(t *testing.T) {
+	hash, err := PasswortHash("geheim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !PasswortHashGueltig(hash) {
+		t.Fatalf("Hash ungültig: %s", hash)
+	}
+	// gleicher Text, anderer Salt
+	if zweiter, _ := PasswortHash("geheim"); zweiter == hash {
+		t.Error("Hash ohne Salt")
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		passwort string
+		want     bool
+	}{
+		{"richtig", hash, "geheim", true},
+		{"falsch", hash, "Geheim", false},
+		{"Klartext statt Hash", "geheim", "geheim", false},
+		{"anderes Verfahren", "md5$1$c2FsdA$AAAA", "geheim", false},
+		{"kaputter Salt", "pbkdf2-sha256$1000$!!$AAAA", "geheim", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := passwortPasst(tt.hash, tt.passwort); got != tt.want {
+				t.Errorf("passwortPasst = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sporttag/parse"
+	"sporttag/strukturen"
+	"sporttag/urkunde"
+)
+
+// ===== GET /urkunde?kindObjectId=<id> | ?riegeObjectId=<id> =====
+// Liefert die Urkunde eines Kindes bzw. aller Kinder einer Riege
+// (eine Seite je Kind, in Positionsreihenfolge) als PDF.
+func (h *KindHandler) UrkundeRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleRiegenfuehrer, RolleAdmin)
+	if !ok {
+		return
+	}
+	if h.Urkunden == nil {
+		http.Error(w, "Keine Urkunden-Vorlage konfiguriert", http.StatusNotFound)
+		return
+	}
+
+	kindObjectID := r.URL.Query().Get("kindObjectId")
+	riegeObjectID := r.URL.Query().Get("riegeObjectId")
+	if (kindObjectID == "") == (riegeObjectID == "") {
+		http.Error(w, "genau einer von kindObjectId oder riegeObjectId erforderlich", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		kinder       []parse.Object
+		riegenNummer int
+		dateiname    string
+	)
+
+	if kindObjectID != "" {
+		// ---- einzelnes Kind ----
+		kind, err := h.Parse.Get(r.Context(), "Kind", kindObjectID)
+		if parse.IsNotFound(err) {
+			http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		riege, err := h.riegeVonKind(r.Context(), kindObjectID)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+
+		// Eltern: eigene Kinder, Riegenführer: Kinder der eigenen Riege
+		erlaubt := id.darfKind(kind)
+		if id.Rolle == RolleRiegenfuehrer {
+			erlaubt = riege != nil && id.darfRiege(riege.ObjectID())
+		}
+		if !erlaubt {
+			http.Error(w, "Keine Berechtigung für dieses Kind", http.StatusForbidden)
+			return
+		}
+
+		if riege != nil {
+			riegenNummer = riege.Int("riegenNummer")
+		}
+		kinder = []parse.Object{kind}
+		dateiname = "urkunde_" + kind.String("nachName") + "_" + kind.String("vorName") + ".pdf"
+	} else {
+		// ---- ganze Riege ----
+		if !id.darfRiege(riegeObjectID) {
+			http.Error(w, "Keine Berechtigung für diese Riege", http.StatusForbidden)
+			return
+		}
+		riege, err := h.Parse.Get(r.Context(), "Riege", riegeObjectID)
+		if parse.IsNotFound(err) {
+			http.Error(w, "Riege nicht gefunden", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+
+		query := parse.NewQuery().
+			PointerTo("riegenID", "Riege", riegeObjectID).
+			Order("position").
+			Include("kindID").
+			Limit(1000)
+		out, err := h.Parse.Query(r.Context(), "kinderDerRiege", query)
+		if err != nil {
+			http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+			return
+		}
+		for _, z := range out.Results {
+			var zd strukturen.KinderDerRiege
+			if err := z.Decode(&zd); err != nil || !zd.KindID.Expanded() {
+				continue
+			}
+			var kind parse.Object
+			if err := zd.KindID.Decode(&kind); err == nil {
+				kinder = append(kinder, kind)
+			}
+		}
+		if len(kinder) == 0 {
+			http.Error(w, "Riege hat keine Kinder", http.StatusNotFound)
+			return
+		}
+
+		riegenNummer = riege.Int("riegenNummer")
+		dateiname = "urkunden_riege" + strconv.Itoa(riegenNummer) + ".pdf"
+	}
+
+	// ---- Punkte summieren ----
+	summen, err := h.punkteSummen(r.Context(), kinder)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	jahr := h.Deadline.Year()
+	if h.Deadline.IsZero() {
+		jahr = time.Now().Year()
+	}
+
+	urkunden := make([]urkunde.Daten, 0, len(kinder))
+	for _, k := range kinder {
+		s := summen[k.ObjectID()]
+		urkunden = append(urkunden, urkunde.Daten{
+			VorName:      k.String("vorName"),
+			NachName:     k.String("nachName"),
+			Jahrgang:     k.Int("jahrgang"),
+			Geschlecht:   k.String("geschlecht"),
+			Punkte:       s.punkte,
+			AnzStationen: s.anzahl,
+			RiegenNummer: riegenNummer,
+			Jahr:         jahr,
+		})
+	}
+
+	pdf, err := h.Urkunden.PDF(urkunden)
+	if err != nil {
+		log.Println("Urkunde:", err)
+		http.Error(w, "Urkunde konnte nicht erzeugt werden", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+unerlaubteZeichen.ReplaceAllString(dateiname, "_")+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+type punkteSumme struct {
+	punkte int
+	anzahl int
+}
+
+// Summiert die Resultate je Kind
+func (h *KindHandler) punkteSummen(ctx context.Context, kinder []parse.Object) (map[string]punkteSumme, error) {
+	ids := make([]string, 0, len(kinder))
+	for _, k := range kinder {
+		ids = append(ids, k.ObjectID())
+	}
+
+	query := parse.NewQuery().PointerIn("kindID", "Kind", ids...).Keys("kindID", "punkte")
+	resultate, err := parse.QueryAll(ctx, h.Parse, "resultate", query)
+	if err != nil {
+		return nil, err
+	}
+
+	summen := make(map[string]punkteSumme, len(kinder))
+	for _, res := range resultate {
+		var rd strukturen.Resultate
+		if err := res.Decode(&rd); err != nil || rd.KindID == nil {
+			continue
+		}
+		s := summen[rd.KindID.ObjectID]
+		s.punkte += rd.Punkte
+		s.anzahl++
+		summen[rd.KindID.ObjectID] = s
+	}
+	return summen, nil
+}
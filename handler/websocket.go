@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sporttag/ereignisse"
+)
+
+// ======  Minimaler WebSocket-Server (RFC 6455)  ======
+//
+// Nur was /ereignisse braucht: der Server sendet Textnachrichten, vom
+// Client werden lediglich Ping und Close ausgewertet. Keine Erweiterungen,
+// keine Fragmentierung beim Senden.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// Größte akzeptierte Nachricht vom Client (Ping/Close sind klein)
+const wsMaxNachricht = 64 << 10
+
+func istWebSocket(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+type wsVerbindung struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // schützt das Schreiben (Hauptschleife und Pong)
+}
+
+// Schreibt einen unmaskierten Frame (Server → Client)
+func (c *wsVerbindung) schreibe(opcode byte, daten []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kopf := []byte{0x80 | opcode}
+	switch n := len(daten); {
+	case n < 126:
+		kopf = append(kopf, byte(n))
+	case n <= 0xFFFF:
+		kopf = append(kopf, 126)
+		kopf = binary.BigEndian.AppendUint16(kopf, uint16(n))
+	default:
+		kopf = append(kopf, 127)
+		kopf = binary.BigEndian.AppendUint64(kopf, uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.rw.Write(kopf); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(daten); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+func (c *wsVerbindung) schreibeJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.schreibe(wsText, b)
+}
+
+// Liest Frames des Clients bis Close oder Fehler; beantwortet Pings
+func (c *wsVerbindung) lesen() error {
+	for {
+		var kopf [2]byte
+		if _, err := io.ReadFull(c.rw, kopf[:]); err != nil {
+			return err
+		}
+		opcode := kopf[0] & 0x0F
+		maskiert := kopf[1]&0x80 != 0
+		laenge := uint64(kopf[1] & 0x7F)
+
+		switch laenge {
+		case 126:
+			var b [2]byte
+			if _, err := io.ReadFull(c.rw, b[:]); err != nil {
+				return err
+			}
+			laenge = uint64(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			if _, err := io.ReadFull(c.rw, b[:]); err != nil {
+				return err
+			}
+			laenge = binary.BigEndian.Uint64(b[:])
+		}
+		// Clients müssen maskieren (RFC 6455, 5.1)
+		if !maskiert || laenge > wsMaxNachricht {
+			c.schreibe(wsClose, []byte{0x03, 0xEA}) // 1002 Protokollfehler
+			return errors.New("ungültiger WebSocket-Frame")
+		}
+
+		var maske [4]byte
+		if _, err := io.ReadFull(c.rw, maske[:]); err != nil {
+			return err
+		}
+		daten := make([]byte, laenge)
+		if _, err := io.ReadFull(c.rw, daten); err != nil {
+			return err
+		}
+		for i := range daten {
+			daten[i] ^= maske[i%4]
+		}
+
+		switch opcode {
+		case wsClose:
+			c.schreibe(wsClose, nil)
+			return nil
+		case wsPing:
+			if err := c.schreibe(wsPong, daten); err != nil {
+				return err
+			}
+		}
+		// Text-, Binär- und Pong-Nachrichten werden ignoriert
+	}
+}
+
+// Liefert die Ereignisse als JSON-Textnachrichten über eine WebSocket-Verbindung
+func (h *KindHandler) ereignisseWebSocket(w http.ResponseWriter, r *http.Request, filter ereignisse.Filter, letzteID string) {
+	schluessel := r.Header.Get("Sec-WebSocket-Key")
+	if schluessel == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Ungültiger WebSocket-Handshake", http.StatusBadRequest)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket nicht unterstützt", http.StatusInternalServerError)
+		return
+	}
+
+	abo, verpasst, vollstaendig := h.Ereignisse.Abonnieren(filter, letzteID)
+	defer abo.Abmelden()
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		log.Println("WebSocket:", err)
+		return
+	}
+	defer conn.Close()
+
+	summe := sha1.Sum([]byte(schluessel + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(summe[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	ws := &wsVerbindung{conn: conn, rw: rw}
+	beendet := make(chan struct{})
+	go func() {
+		defer close(beendet)
+		ws.lesen()
+	}()
+
+	// Lücke nicht mehr im Puffer → Client muss den Stand neu laden
+	if !vollstaendig {
+		if ws.schreibeJSON(map[string]any{
+			"typ":   "reset",
+			"daten": map[string]any{"grund": "Ereignisse seit lastEventId nicht mehr verfügbar"},
+		}) != nil {
+			return
+		}
+	}
+	for _, e := range verpasst {
+		if ws.schreibeJSON(e) != nil {
+			return
+		}
+	}
+
+	herzschlag := time.NewTicker(ereignisHerzschlag)
+	defer herzschlag.Stop()
+
+	for {
+		select {
+		case <-beendet:
+			return
+		case e, ok := <-abo.C:
+			if !ok {
+				// zu langsam – der Client verbindet sich mit lastEventId neu
+				ws.schreibe(wsClose, []byte{0x03, 0xF5}) // 1013 später erneut versuchen
+				return
+			}
+			if ws.schreibeJSON(e) != nil {
+				return
+			}
+		case <-herzschlag.C:
+			if ws.schreibe(wsPing, nil) != nil {
+				return
+			}
+		}
+	}
+}
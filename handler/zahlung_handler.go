@@ -0,0 +1,608 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"sporttag/ereignisse"
+	"sporttag/parse"
+	"sporttag/strukturen"
+)
+
+// ======  Kassenbuch  ======
+//
+// Jede Zahlung wird als Eintrag der Klasse "zahlung" gebucht. "bezahlt" am
+// Kind wird daraus abgeleitet und bei jeder Buchung (mit Versionsprüfung)
+// neu gesetzt. Betrag, Art und Methode eines Eintrags werden nicht
+// geändert: Fehlbuchungen werden storniert, zurückgegebenes Geld als
+// Erstattung gebucht. Nur das Zusammenführen von Duplikaten hängt Einträge
+// auf ein anderes Kind um und hält das ursprüngliche in urspruenglichKindID
+// fest (kind_zusammenfuehren_handler.go). Die Klasse ist nur mit dem
+// Master-Key erreichbar (schema.go).
+//
+// Kinder, die vor dem Kassenbuch als bezahlt markiert wurden, erhalten
+// einmalig eine Übernahme-Buchung (go run . kassenbuch-uebernahme),
+// sonst würde die nächste Buchung sie wieder auf unbezahlt setzen.
+
+const (
+	ArtZahlung    = "zahlung"
+	ArtErstattung = "erstattung"
+	ArtStorno     = "storno"
+	// Saldo aus der Zeit vor dem Kassenbuch; nur per Migration, zählt
+	// nicht im Kassenbericht (das Geld ging nicht über die Kasse)
+	ArtUebernahme = "uebernahme"
+
+	MethodeBar          = "bar"
+	MethodeUeberweisung = "ueberweisung"
+)
+
+// ZahlungRequest für POST /zahlung
+type ZahlungRequest struct {
+	KindObjectID    string `json:"kindObjectId"`
+	ExpectedVersion int    `json:"expectedVersion"` // Version des Kindes
+	Art             string `json:"art,omitempty"`   // Standard: zahlung
+	Betrag          int    `json:"betrag,omitempty"`
+	Methode         string `json:"methode,omitempty"`
+	StornoVon       string `json:"stornoVon,omitempty"` // objectId der stornierten Buchung
+	Bemerkung       string `json:"bemerkung,omitempty"`
+}
+
+// Eine zu buchende Zahlung (aus POST /zahlung oder PATCH /kind)
+type buchung struct {
+	art       string
+	betrag    int
+	methode   string
+	stornoVon string
+	bemerkung string
+}
+
+func gueltigeMethode(m string) bool {
+	return m == MethodeBar || m == MethodeUeberweisung
+}
+
+// ===== /zahlung =====
+func (h *KindHandler) ZahlungRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, POST, OPTIONS")
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		h.GetZahlungen(w, r)
+	case http.MethodPost:
+		h.BucheZahlung(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ===== GET /zahlung?kindObjectId=<id> =====
+// Buchungen eines Kindes mit Saldo; Eltern nur für eigene Kinder
+func (h *KindHandler) GetZahlungen(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.requireRolle(w, r, RolleEltern, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	kindObjectID := r.URL.Query().Get("kindObjectId")
+	if kindObjectID == "" {
+		http.Error(w, "kindObjectId erforderlich", http.StatusBadRequest)
+		return
+	}
+	kind, err := h.Parse.Get(r.Context(), "Kind", kindObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	if !id.darfKind(kind) {
+		http.Error(w, "Keine Berechtigung für dieses Kind", http.StatusForbidden)
+		return
+	}
+
+	eintraege, err := h.zahlungenVon(r.Context(), kindObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	saldo, storniert := kassenstand(eintraege)
+	for _, e := range eintraege {
+		e["storniert"] = storniert[e.ObjectID()]
+	}
+	gebuehr, err := h.gebuehrVon(r.Context(), kind)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": eintraege,
+		"saldo":   saldo,
+		"gebuehr": gebuehr,
+		"offen":   max(gebuehr.Betrag-saldo, 0),
+		"bezahlt": h.istBezahlt(saldo, gebuehr),
+	})
+}
+
+// ===== POST /zahlung =====
+// Bucht Zahlung, Erstattung oder Storno (nur admin). Kassenbuchungen
+// sind auch nach der Anmeldefrist möglich (Kasse am Sporttag).
+func (h *KindHandler) BucheZahlung(w http.ResponseWriter, r *http.Request) {
+	// ---- PANIC Abfangen ----
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("PANIC:", r)
+			http.Error(w, "Interner Serverfehler", http.StatusInternalServerError)
+		}
+	}()
+
+	id, ok := h.requireRolle(w, r, RolleAdmin)
+	if !ok {
+		return
+	}
+
+	var req ZahlungRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Ungültiges JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.KindObjectID == "" {
+		http.Error(w, "kindObjectId fehlt", http.StatusBadRequest)
+		return
+	}
+	if req.ExpectedVersion <= 0 {
+		http.Error(w, "expectedVersion fehlt oder ungültig", http.StatusBadRequest)
+		return
+	}
+	if req.Art == "" {
+		req.Art = ArtZahlung
+	}
+
+	kind, err := h.Parse.Get(r.Context(), "Kind", req.KindObjectID)
+	if parse.IsNotFound(err) {
+		http.Error(w, "Kind nicht gefunden", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+
+	// ---- Lock über den Business-Key des Kindes (wie PUT/PATCH /kind) ----
+	var k strukturen.Kind
+	if err := kind.Decode(&k); err != nil {
+		http.Error(w, "Kind ungültig", http.StatusInternalServerError)
+		return
+	}
+	lock := h.lockForKey(kindBusinessKey(k))
+	select {
+	case lock <- struct{}{}:
+		defer func() { <-lock }()
+	default:
+		http.Error(w, "Konflikt: Kind wird bereits bearbeitet", http.StatusConflict)
+		return
+	}
+
+	h.bucheZahlung(w, r, id, kind, req.ExpectedVersion, buchung{
+		art:       req.Art,
+		betrag:    req.Betrag,
+		methode:   req.Methode,
+		stornoVon: req.StornoVon,
+		bemerkung: req.Bemerkung,
+	})
+}
+
+// Prüft und bucht einen Eintrag und leitet bezahlt am Kind neu ab.
+// Der Aufrufer hält den Lock des Kindes. Schreibt die Antwort selbst;
+// liefert true, wenn gebucht wurde.
+func (h *KindHandler) bucheZahlung(
+	w http.ResponseWriter,
+	r *http.Request,
+	id *Identitaet,
+	kind parse.Object,
+	expectedVersion int,
+	b buchung,
+) bool {
+	kindObjectID := kind.ObjectID()
+	eintraege, err := h.zahlungenVon(r.Context(), kindObjectID)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return false
+	}
+	saldo, storniert := kassenstand(eintraege)
+
+	// ---- Validierung je Art ----
+	neuerSaldo := saldo
+	switch b.art {
+	case ArtZahlung, ArtErstattung:
+		if b.betrag <= 0 {
+			http.Error(w, "betrag (Cent) muss größer 0 sein", http.StatusBadRequest)
+			return false
+		}
+		if !gueltigeMethode(b.methode) {
+			http.Error(w, "methode muss bar oder ueberweisung sein", http.StatusBadRequest)
+			return false
+		}
+		if b.stornoVon != "" {
+			http.Error(w, "stornoVon nur bei art=storno", http.StatusBadRequest)
+			return false
+		}
+		if b.art == ArtZahlung {
+			neuerSaldo += b.betrag
+		} else {
+			neuerSaldo -= b.betrag
+		}
+
+	case ArtStorno:
+		if b.stornoVon == "" {
+			http.Error(w, "stornoVon erforderlich", http.StatusBadRequest)
+			return false
+		}
+		var original parse.Object
+		for _, e := range eintraege {
+			if e.ObjectID() == b.stornoVon {
+				original = e
+				break
+			}
+		}
+		if original == nil {
+			http.Error(w, "Buchung für dieses Kind nicht gefunden", http.StatusNotFound)
+			return false
+		}
+		if original.String("art") == ArtStorno {
+			http.Error(w, "Ein Storno kann nicht storniert werden", http.StatusBadRequest)
+			return false
+		}
+		if storniert[b.stornoVon] {
+			http.Error(w, "Buchung ist bereits storniert", http.StatusConflict)
+			return false
+		}
+		// Betrag und Methode kommen aus der stornierten Buchung
+		if (b.betrag != 0 && b.betrag != original.Int("betrag")) || (b.methode != "" && b.methode != original.String("methode")) {
+			http.Error(w, "betrag und methode müssen der stornierten Buchung entsprechen", http.StatusBadRequest)
+			return false
+		}
+		b.betrag = original.Int("betrag")
+		b.methode = original.String("methode")
+		neuerSaldo -= wirkung(original, nil)
+
+	default:
+		http.Error(w, "art muss zahlung, erstattung oder storno sein", http.StatusBadRequest)
+		return false
+	}
+	if neuerSaldo < 0 {
+		http.Error(w, "Buchung übersteigt das Guthaben des Kindes", http.StatusConflict)
+		return false
+	}
+
+	// Gebühr vor dem Speichern bestimmen, damit danach nur noch das Kind scheitern kann
+	gebuehr, err := h.gebuehrVon(r.Context(), kind)
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return false
+	}
+
+	// ---- Eintrag anlegen ----
+	jetzt := strukturen.NewParseDate(time.Now())
+	eintrag := strukturen.Zahlung{
+		KindID:    strukturen.NewParsePointer("Kind", kindObjectID),
+		Art:       b.art,
+		Betrag:    b.betrag,
+		Methode:   b.methode,
+		Kassierer: id.Name,
+		Zeitpunkt: jetzt,
+		Bemerkung: b.bemerkung,
+	}
+	if b.stornoVon != "" {
+		eintrag.StornoVon = strukturen.NewParsePointer("zahlung", b.stornoVon)
+	}
+	out, err := h.Parse.Create(r.Context(), "zahlung", eintrag)
+	if err != nil {
+		http.Error(w, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return false
+	}
+
+	// ---- bezahlt ableiten (Version des Kindes prüfen) ----
+	// Parse kennt keine Transaktionen: bei Fehlschlag wird die Buchung
+	// wieder entfernt, damit Kassenbuch und Kind übereinstimmen.
+	bezahlt := h.istBezahlt(neuerSaldo, gebuehr)
+	kindOut, err := h.updateWithVersion(r.Context(), "Kind", kindObjectID, expectedVersion,
+		map[string]interface{}{"bezahlt": bezahlt})
+	if err != nil {
+		if delErr := h.Parse.Delete(r.Context(), "zahlung", out.ObjectID()); delErr != nil {
+			log.Printf("Zahlung %s konnte nicht zurückgenommen werden: %v", out.ObjectID(), delErr)
+		}
+		if parse.IsNotFound(err) {
+			http.Error(w, "Konflikt: Kind wurde zwischenzeitlich geändert", http.StatusConflict)
+		} else {
+			http.Error(w, "Update fehlgeschlagen", http.StatusBadGateway)
+		}
+		return false
+	}
+
+	// Protokoll am Kind: Buchung und abgeleitetes bezahlt
+	h.audit(r, id, b.art, "Kind", kindObjectID,
+		map[string]any{"bezahlt": kind.Bool("bezahlt"), "saldo": saldo},
+		map[string]any{"buchung": out.ObjectID(), "eintrag": eintrag, "bezahlt": bezahlt, "saldo": neuerSaldo},
+		expectedVersion+1)
+
+	h.Ereignisse.Veroeffentlichen(ereignisse.TypKind, "", "", map[string]any{
+		"aktion":   b.art,
+		"objectId": kindObjectID,
+		"bezahlt":  bezahlt,
+		"saldo":    neuerSaldo,
+		"version":  expectedVersion + 1,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Buchung erfolgreich gespeichert",
+		"objectId":   out.ObjectID(),
+		"art":        b.art,
+		"betrag":     b.betrag,
+		"saldo":      neuerSaldo,
+		"gebuehr":    gebuehr.Betrag,
+		"offen":      max(gebuehr.Betrag-neuerSaldo, 0),
+		"bezahlt":    bezahlt,
+		"newVersion": expectedVersion + 1,
+		"updatedAt":  kindOut["updatedAt"],
+	})
+	return true
+}
+
+// Bucht für jedes als bezahlt markierte Kind ohne Kassenbuch-Einträge
+// eine Übernahme in Höhe seiner Gebühr (ohne konfigurierte Gebühren:
+// betragOhneGebuehr). Idempotent; liefert die Zahl der Buchungen.
+func (h *KindHandler) KassenbuchUebernehmen(ctx context.Context, betragOhneGebuehr int) (int, error) {
+	kinder, err := parse.QueryAll(ctx, h.Parse, "Kind", parse.NewQuery().EqualTo("bezahlt", true))
+	if err != nil {
+		return 0, err
+	}
+	gebucht := 0
+	for _, kind := range kinder {
+		eintraege, err := h.zahlungenVon(ctx, kind.ObjectID())
+		if err != nil {
+			return gebucht, err
+		}
+		if len(eintraege) > 0 {
+			continue
+		}
+		betrag := betragOhneGebuehr
+		if h.Gebuehren.Aktiv() {
+			g, err := h.gebuehrVon(ctx, kind)
+			if err != nil {
+				return gebucht, err
+			}
+			betrag = g.Betrag
+		}
+		if betrag <= 0 {
+			if h.Gebuehren.Aktiv() {
+				continue // keine Gebühr → auch ohne Buchung bezahlt
+			}
+			return gebucht, errors.New("ohne konfigurierte Gebühren ist ein Betrag erforderlich")
+		}
+		_, err = h.Parse.Create(ctx, "zahlung", strukturen.Zahlung{
+			KindID:    strukturen.NewParsePointer("Kind", kind.ObjectID()),
+			Art:       ArtUebernahme,
+			Betrag:    betrag,
+			Kassierer: "Übernahme",
+			Zeitpunkt: strukturen.NewParseDate(time.Now()),
+			Bemerkung: "bezahlt vor Einführung des Kassenbuchs",
+		})
+		if err != nil {
+			return gebucht, err
+		}
+		gebucht++
+	}
+	return gebucht, nil
+}
+
+// Alle Buchungen eines Kindes in zeitlicher Reihenfolge
+func (h *KindHandler) zahlungenVon(ctx context.Context, kindObjectID string) ([]parse.Object, error) {
+	query := parse.NewQuery().
+		PointerTo("kindID", "Kind", kindObjectID).
+		Order("zeitpunkt")
+	return parse.QueryAll(ctx, h.Parse, "zahlung", query)
+}
+
+// Saldo (Cent) und stornierte Buchungen aus dem Kassenbuch eines Kindes
+func kassenstand(eintraege []parse.Object) (saldo int, storniert map[string]bool) {
+	nachID := make(map[string]parse.Object, len(eintraege))
+	for _, e := range eintraege {
+		nachID[e.ObjectID()] = e
+	}
+	storniert = map[string]bool{}
+	for _, e := range eintraege {
+		saldo += wirkung(e, nachID)
+		if e.String("art") == ArtStorno {
+			storniert[stornoVonID(e)] = true
+		}
+	}
+	return saldo, storniert
+}
+
+// Auswirkung einer Buchung auf den Saldo: Zahlung +, Erstattung −,
+// Storno hebt die stornierte Buchung auf (nachID zum Nachschlagen).
+func wirkung(e parse.Object, nachID map[string]parse.Object) int {
+	switch e.String("art") {
+	case ArtZahlung, ArtUebernahme:
+		return e.Int("betrag")
+	case ArtErstattung:
+		return -e.Int("betrag")
+	case ArtStorno:
+		if original, ok := nachID[stornoVonID(e)]; ok {
+			return -wirkung(original, nil)
+		}
+	}
+	return 0
+}
+
+func stornoVonID(e parse.Object) string {
+	var z strukturen.Zahlung
+	if err := e.Decode(&z); err != nil || z.StornoVon == nil {
+		return ""
+	}
+	return z.StornoVon.ObjectID
+}
+
+// ======  Kassenbericht  ======
+
+// Summen einer Gruppe (Tag oder Kassierer), Beträge in Cent
+type kassenSumme struct {
+	Anzahl       int `json:"anzahl"`
+	Summe        int `json:"summe"` // netto nach Erstattungen und Stornos
+	Bar          int `json:"bar"`
+	Ueberweisung int `json:"ueberweisung"`
+}
+
+func (s *kassenSumme) add(betrag int, methode string) {
+	s.Anzahl++
+	s.Summe += betrag
+	if methode == MethodeBar {
+		s.Bar += betrag
+	} else {
+		s.Ueberweisung += betrag
+	}
+}
+
+type tagesBericht struct {
+	Datum string `json:"datum"`
+	kassenSumme
+	Kassierer map[string]*kassenSumme `json:"kassierer"`
+}
+
+type kassiererBericht struct {
+	Kassierer string `json:"kassierer"`
+	kassenSumme
+}
+
+// Tage werden in deutscher Zeit abgegrenzt (Server laufen meist in UTC)
+func kassenZone() *time.Location {
+	if loc, err := time.LoadLocation("Europe/Berlin"); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// ===== GET /zahlung/bericht?von=YYYY-MM-DD&bis=YYYY-MM-DD&kassierer=<name> =====
+// Summen je Tag und je Kassierer für den Kassenabschluss (nur admin).
+// Stornos und Erstattungen zählen an dem Tag und für den Kassierer,
+// an dem bzw. von dem sie gebucht wurden.
+func (h *KindHandler) ZahlungsberichtRouter(w http.ResponseWriter, r *http.Request) {
+	// ---- CORS ----
+	setCORSHeaders(w, "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.requireRolle(w, r, RolleAdmin); !ok {
+		return
+	}
+
+	zone := kassenZone()
+	q := r.URL.Query()
+	var von, bis string
+	for _, p := range []struct {
+		name string
+		ziel *string
+	}{{"von", &von}, {"bis", &bis}} {
+		if v := q.Get(p.name); v != "" {
+			if _, err := time.ParseInLocation(time.DateOnly, v, zone); err != nil {
+				http.Error(w, p.name+" muss im Format YYYY-MM-DD sein", http.StatusBadRequest)
+				return
+			}
+			*p.ziel = v
+		}
+	}
+	nurKassierer := q.Get("kassierer")
+
+	alle, err := parse.QueryAll(r.Context(), h.Parse, "zahlung", parse.NewQuery())
+	if err != nil {
+		http.Error(w, "Parse-Fehler", http.StatusBadGateway)
+		return
+	}
+	nachID := make(map[string]parse.Object, len(alle))
+	for _, e := range alle {
+		nachID[e.ObjectID()] = e
+	}
+
+	tage := map[string]*tagesBericht{}
+	kassierer := map[string]*kassiererBericht{}
+	var gesamt kassenSumme
+
+	for _, e := range alle {
+		var z strukturen.Zahlung
+		if err := e.Decode(&z); err != nil || z.Zeitpunkt == nil {
+			continue
+		}
+		datum := z.Zeitpunkt.Time.In(zone).Format(time.DateOnly)
+		if (von != "" && datum < von) || (bis != "" && datum > bis) {
+			continue
+		}
+		if nurKassierer != "" && z.Kassierer != nurKassierer {
+			continue
+		}
+		if z.Art == ArtUebernahme || nachID[stornoVonID(e)].String("art") == ArtUebernahme {
+			continue
+		}
+
+		betrag := wirkung(e, nachID)
+
+		t, ok := tage[datum]
+		if !ok {
+			t = &tagesBericht{Datum: datum, Kassierer: map[string]*kassenSumme{}}
+			tage[datum] = t
+		}
+		t.add(betrag, z.Methode)
+		if t.Kassierer[z.Kassierer] == nil {
+			t.Kassierer[z.Kassierer] = &kassenSumme{}
+		}
+		t.Kassierer[z.Kassierer].add(betrag, z.Methode)
+
+		k, ok := kassierer[z.Kassierer]
+		if !ok {
+			k = &kassiererBericht{Kassierer: z.Kassierer}
+			kassierer[z.Kassierer] = k
+		}
+		k.add(betrag, z.Methode)
+
+		gesamt.add(betrag, z.Methode)
+	}
+
+	tagListe := make([]*tagesBericht, 0, len(tage))
+	for _, t := range tage {
+		tagListe = append(tagListe, t)
+	}
+	sort.Slice(tagListe, func(i, j int) bool { return tagListe[i].Datum < tagListe[j].Datum })
+
+	kassiererListe := make([]*kassiererBericht, 0, len(kassierer))
+	for _, k := range kassierer {
+		kassiererListe = append(kassiererListe, k)
+	}
+	sort.Slice(kassiererListe, func(i, j int) bool { return kassiererListe[i].Kassierer < kassiererListe[j].Kassierer })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tage":      tagListe,
+		"kassierer": kassiererListe,
+		"gesamt":    gesamt,
+	})
+}
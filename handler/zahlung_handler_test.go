@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"sporttag/strukturen"
+)
+
+// Vor dem Kassenbuch bezahlte Kinder bleiben nach der Übernahme bezahlt
+func TestKassenbuchUebernehmen(t *testing.T) {
+	h, mem := testHandler(t)
+	ctx := context.Background()
+	alt := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Anna", NachName: "Muster", Jahrgang: 2014, Geschlecht: "w"})
+	offen := kindRegistrieren(t, h, &testAdmin, strukturen.Kind{VorName: "Ben", NachName: "Muster", Jahrgang: 2015, Geschlecht: "m"})
+	if _, err := mem.Update(ctx, "Kind", alt, map[string]any{"bezahlt": true}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.KassenbuchUebernehmen(ctx, 0); err == nil {
+		t.Error("ohne Gebühren und Betrag muss ein Fehler kommen")
+	}
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"erster Lauf", 1},
+		{"zweiter Lauf ändert nichts", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := h.KassenbuchUebernehmen(ctx, 500)
+			if err != nil || n != tt.want {
+				t.Fatalf("%d Buchungen (%v), want %d", n, err, tt.want)
+			}
+		})
+	}
+	for kindID, want := range map[string]int{alt: 1, offen: 0} {
+		eintraege, err := h.zahlungenVon(ctx, kindID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(eintraege) != want {
+			t.Errorf("Kind %s: %d Buchungen, want %d", kindID, len(eintraege), want)
+		}
+	}
+
+	// Erstattung eines Teils: Saldo bleibt positiv, also weiter bezahlt
+	kind, err := mem.Get(ctx, "Kind", alt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := anfrage(t, h, h.BucheZahlung, http.MethodPost, &testAdmin, ZahlungRequest{
+		KindObjectID:    alt,
+		ExpectedVersion: kind.Int("version"),
+		Art:             ArtErstattung,
+		Betrag:          200,
+		Methode:         MethodeBar,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status %d: %s", w.Code, w.Body.String())
+	}
+	if out := antwort(t, w); out["bezahlt"] != true || out["saldo"] != float64(300) {
+		t.Errorf("bezahlt %v, saldo %v; want true, 300", out["bezahlt"], out["saldo"])
+	}
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Datei legt jede Nachricht als .eml-Datei in einem Verzeichnis ab
+// (lokale Entwicklung: mit jedem Mailprogramm zu öffnen).
+type Datei struct {
+	Verzeichnis string
+	Absender    string
+}
+
+// NewDatei legt das Verzeichnis bei Bedarf an
+func NewDatei(verzeichnis, absender string) (*Datei, error) {
+	if err := os.MkdirAll(verzeichnis, 0o755); err != nil {
+		return nil, err
+	}
+	return &Datei{Verzeichnis: verzeichnis, Absender: absender}, nil
+}
+
+func (d *Datei) Senden(ctx context.Context, n Nachricht) error {
+	jetzt := time.Now()
+	nachricht, err := formatieren(d.Absender, n, jetzt)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", jetzt.UTC().Format("20060102T150405.000000"), zufall()[:8])
+	return os.WriteFile(filepath.Join(d.Verzeichnis, name), nachricht, 0o644)
+}
+
+// Speicher behält alle Nachrichten im Speicher (Tests, Entwicklung ohne
+// Dateisystem). FehlerSetzen simuliert einen ausgefallenen Mailserver.
+type Speicher struct {
+	mu       sync.Mutex
+	gesendet []Nachricht
+	fehler   error
+}
+
+func (s *Speicher) Senden(ctx context.Context, n Nachricht) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fehler != nil {
+		return s.fehler
+	}
+	s.gesendet = append(s.gesendet, n)
+	return nil
+}
+
+// Gesendet liefert eine Kopie aller bisher verschickten Nachrichten
+func (s *Speicher) Gesendet() []Nachricht {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Nachricht(nil), s.gesendet...)
+}
+
+// FehlerSetzen: alle folgenden Sendungen schlagen mit err fehl (nil = wieder senden)
+func (s *Speicher) FehlerSetzen(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fehler = err
+}
@@ -0,0 +1,159 @@
+// Package mailer verschickt die E-Mails des Sporttags (Anmeldebestätigung,
+// Änderungsmitteilung, Zahlungserinnerung).
+//
+// Texte kommen aus Vorlagen (text/template), der Versand über ein
+// austauschbares Backend: SMTP für den Betrieb, Dateien oder Speicher für
+// lokale Entwicklung und Tests. Die Warteschlange (Ausgang) liegt nicht
+// hier, sondern im Handler, der sie in Parse speichert.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Backends
+const (
+	BackendSMTP     = "smtp"
+	BackendDatei    = "datei"
+	BackendSpeicher = "speicher"
+)
+
+// Nachricht – eine fertige E-Mail (nur Text)
+type Nachricht struct {
+	An      string `json:"an"`
+	Betreff string `json:"betreff"`
+	Text    string `json:"text"`
+}
+
+// Mailer verschickt eine Nachricht. Implementierungen müssen
+// nebenläufig aufrufbar sein.
+type Mailer interface {
+	Senden(ctx context.Context, n Nachricht) error
+}
+
+// SMTPKonfiguration – Zugang zum Mailserver
+type SMTPKonfiguration struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"` // 465 → implizites TLS, sonst STARTTLS, falls angeboten
+	Benutzer string `json:"benutzer,omitempty"`
+	Passwort string `json:"passwort,omitempty"` // besser: SPORTTAG_SMTP_PASSWORT
+}
+
+// Konfiguration aus config.json ("mail")
+type Konfiguration struct {
+	Backend     string            `json:"backend"` // smtp | datei | speicher, "" → keine E-Mails
+	Absender    string            `json:"absender"`
+	SMTP        SMTPKonfiguration `json:"smtp"`
+	Verzeichnis string            `json:"verzeichnis,omitempty"` // Backend datei
+	Vorlagen    string            `json:"vorlagen,omitempty"`    // eigene Vorlagen (JSON), "" → eingebaute
+	// Wartezeit des Ausgangs zwischen zwei Durchläufen in Sekunden (Standard 30)
+	Intervall int `json:"intervall,omitempty"`
+}
+
+// Aktiv: werden überhaupt E-Mails verschickt?
+func (k Konfiguration) Aktiv() bool {
+	return k.Backend != ""
+}
+
+// Prüft Backend und Absender
+func (k Konfiguration) Validate() error {
+	if !k.Aktiv() {
+		return nil
+	}
+	if _, err := mail.ParseAddress(k.Absender); err != nil {
+		return fmt.Errorf("absender ungültig: %v", err)
+	}
+	if k.Intervall < 0 {
+		return errors.New("intervall darf nicht negativ sein")
+	}
+	switch k.Backend {
+	case BackendSMTP:
+		if k.SMTP.Host == "" || k.SMTP.Port <= 0 {
+			return errors.New("smtp.host und smtp.port erforderlich")
+		}
+	case BackendDatei:
+		if k.Verzeichnis == "" {
+			return errors.New("verzeichnis erforderlich")
+		}
+	case BackendSpeicher:
+	default:
+		return fmt.Errorf("unbekanntes Backend %q", k.Backend)
+	}
+	return nil
+}
+
+// New erzeugt das konfigurierte Backend
+func New(k Konfiguration) (Mailer, error) {
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	switch k.Backend {
+	case BackendSMTP:
+		return &SMTP{Konfiguration: k.SMTP, Absender: k.Absender}, nil
+	case BackendDatei:
+		return NewDatei(k.Verzeichnis, k.Absender)
+	case BackendSpeicher:
+		return &Speicher{}, nil
+	}
+	return nil, errors.New("keine E-Mails konfiguriert")
+}
+
+// Erzeugt die vollständige Nachricht im RFC-5322-Format (UTF-8,
+// quoted-printable), wie sie an den Server geht bzw. in der Datei steht.
+func formatieren(absender string, n Nachricht, jetzt time.Time) ([]byte, error) {
+	von, err := mail.ParseAddress(absender)
+	if err != nil {
+		return nil, fmt.Errorf("absender ungültig: %v", err)
+	}
+	an, err := mail.ParseAddress(n.An)
+	if err != nil {
+		return nil, fmt.Errorf("empfänger ungültig: %v", err)
+	}
+	// keine Header-Injection über den Betreff
+	if strings.ContainsAny(n.Betreff, "\r\n") {
+		return nil, errors.New("betreff enthält Zeilenumbrüche")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", von.String())
+	fmt.Fprintf(&b, "To: %s\r\n", an.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Betreff))
+	fmt.Fprintf(&b, "Date: %s\r\n", jetzt.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", zufall(), domain(von.Address))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	text := strings.ReplaceAll(strings.ReplaceAll(n.Text, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func zufall() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domain(adresse string) string {
+	if i := strings.LastIndex(adresse, "@"); i >= 0 {
+		return adresse[i+1:]
+	}
+	return "localhost"
+}
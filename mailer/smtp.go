@@ -0,0 +1,87 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP verschickt über einen Mailserver. Jede Nachricht öffnet eine
+// eigene Verbindung; der Ausgang schickt ohnehin nacheinander.
+type SMTP struct {
+	Konfiguration SMTPKonfiguration
+	Absender      string
+}
+
+func (s *SMTP) Senden(ctx context.Context, n Nachricht) error {
+	nachricht, err := formatieren(s.Absender, n, time.Now())
+	if err != nil {
+		return err
+	}
+	von, _ := mail.ParseAddress(s.Absender)
+	an, _ := mail.ParseAddress(n.An)
+
+	// ---- Verbindung (mit Kontext-Timeout) ----
+	adresse := net.JoinHostPort(s.Konfiguration.Host, strconv.Itoa(s.Konfiguration.Port))
+	tlsConfig := &tls.Config{ServerName: s.Konfiguration.Host}
+	var conn net.Conn
+	if s.Konfiguration.Port == 465 {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", adresse)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", adresse)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.Konfiguration.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && s.Konfiguration.Port != 465 {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.Konfiguration.Benutzer != "" {
+		// PlainAuth verweigert ohne TLS (außer localhost) selbst
+		auth := smtp.PlainAuth("", s.Konfiguration.Benutzer, s.Konfiguration.Passwort, s.Konfiguration.Host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	// ---- Nachricht übertragen ----
+	if err := c.Mail(von.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(an.Address); err != nil {
+		return err
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(nachricht); err != nil {
+		wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	if err := c.Quit(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
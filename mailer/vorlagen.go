@@ -0,0 +1,181 @@
+package mailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Namen der Vorlagen
+const (
+	VorlageBestaetigung       = "bestaetigung"
+	VorlageAenderung          = "aenderung"
+	VorlageZahlungserinnerung = "zahlungserinnerung"
+)
+
+// Vorlage – Betreff und Text als text/template
+type Vorlage struct {
+	Betreff string `json:"betreff"`
+	Text    string `json:"text"`
+}
+
+// Kind in den Vorlagendaten
+type Kind struct {
+	VorName  string
+	NachName string
+	Jahrgang int
+	Gebuehr  int // Cent
+	Offen    int // Cent
+}
+
+// Aenderung eines Feldes (Vorlage aenderung)
+type Aenderung struct {
+	Feld string
+	Alt  string
+	Neu  string
+}
+
+// Daten für alle Vorlagen; in den Texten als {{.Name}} usw. verfügbar,
+// Beträge mit {{betrag .Gebuehr}}
+type Daten struct {
+	Name        string // Anrede, z. B. Name des Erziehungsberechtigten
+	Kinder      []Kind
+	Gebuehr     int // Summe in Cent
+	Offen       int // Summe in Cent
+	Aenderungen []Aenderung
+}
+
+// Eingebaute Vorlagen; eine Datei (Konfiguration.Vorlagen) kann
+// einzelne davon ersetzen.
+var standardVorlagen = map[string]Vorlage{
+	VorlageBestaetigung: {
+		Betreff: "Anmeldung zum Sporttag bestätigt",
+		Text: `Hallo{{with .Name}} {{.}}{{end}},
+
+vielen Dank für die Anmeldung zum Sporttag. Angemeldet sind:
+{{range .Kinder}}
+  - {{.VorName}} {{.NachName}} (Jahrgang {{.Jahrgang}}){{if .Gebuehr}}: {{betrag .Gebuehr}}{{end}}
+{{- end}}
+{{if .Gebuehr}}
+Startgebühr gesamt: {{betrag .Gebuehr}}
+{{end}}
+Sportliche Grüße
+Das Sporttag-Team
+`,
+	},
+	VorlageAenderung: {
+		Betreff: "Anmeldung zum Sporttag geändert",
+		Text: `Hallo{{with .Name}} {{.}}{{end}},
+
+die Anmeldung von {{range $i, $k := .Kinder}}{{if $i}}, {{end}}{{$k.VorName}} {{$k.NachName}}{{end}} wurde geändert:
+{{range .Aenderungen}}
+  - {{.Feld}}: {{.Alt}} → {{.Neu}}
+{{- end}}
+
+Falls die Änderung nicht von Ihnen stammt, melden Sie sich bitte beim Sporttag-Team.
+
+Sportliche Grüße
+Das Sporttag-Team
+`,
+	},
+	VorlageZahlungserinnerung: {
+		Betreff: "Erinnerung: Startgebühr Sporttag",
+		Text: `Hallo{{with .Name}} {{.}}{{end}},
+
+für die Anmeldung zum Sporttag ist noch ein Betrag offen:
+{{range .Kinder}}
+  - {{.VorName}} {{.NachName}}: {{betrag .Offen}} von {{betrag .Gebuehr}}
+{{- end}}
+
+Offen gesamt: {{betrag .Offen}}
+
+Falls Sie bereits bezahlt haben, betrachten Sie diese E-Mail bitte als gegenstandslos.
+
+Sportliche Grüße
+Das Sporttag-Team
+`,
+	},
+}
+
+var funktionen = template.FuncMap{
+	// Cent → "8,00 €"
+	"betrag": func(cent int) string {
+		vorzeichen := ""
+		if cent < 0 {
+			vorzeichen, cent = "-", -cent
+		}
+		return fmt.Sprintf("%s%d,%02d €", vorzeichen, cent/100, cent%100)
+	},
+}
+
+type vorlage struct {
+	betreff *template.Template
+	text    *template.Template
+}
+
+// Vorlagen – geprüfte, einsatzbereite Vorlagen
+type Vorlagen struct {
+	vorlagen map[string]vorlage
+}
+
+// LadeVorlagen übernimmt die eingebauten Vorlagen und ersetzt sie durch
+// die aus datei ("" → nur eingebaute). Fehlerhafte Vorlagen sind ein
+// Startfehler, damit nie halbe E-Mails verschickt werden.
+func LadeVorlagen(datei string) (*Vorlagen, error) {
+	quellen := map[string]Vorlage{}
+	for name, v := range standardVorlagen {
+		quellen[name] = v
+	}
+	if datei != "" {
+		b, err := os.ReadFile(datei)
+		if err != nil {
+			return nil, err
+		}
+		var eigene map[string]Vorlage
+		if err := json.Unmarshal(b, &eigene); err != nil {
+			return nil, fmt.Errorf("%s: %v", datei, err)
+		}
+		for name, v := range eigene {
+			if _, ok := standardVorlagen[name]; !ok {
+				return nil, fmt.Errorf("%s: unbekannte Vorlage %q", datei, name)
+			}
+			quellen[name] = v
+		}
+	}
+
+	v := &Vorlagen{vorlagen: map[string]vorlage{}}
+	for name, q := range quellen {
+		betreff, err := template.New(name + ".betreff").Funcs(funktionen).Option("missingkey=error").Parse(q.Betreff)
+		if err != nil {
+			return nil, fmt.Errorf("Vorlage %s (betreff): %v", name, err)
+		}
+		text, err := template.New(name).Funcs(funktionen).Option("missingkey=error").Parse(q.Text)
+		if err != nil {
+			return nil, fmt.Errorf("Vorlage %s (text): %v", name, err)
+		}
+		v.vorlagen[name] = vorlage{betreff: betreff, text: text}
+	}
+	return v, nil
+}
+
+// Erzeugen füllt die Vorlage name für den Empfänger an
+func (v *Vorlagen) Erzeugen(name, an string, daten Daten) (Nachricht, error) {
+	t, ok := v.vorlagen[name]
+	if !ok {
+		return Nachricht{}, fmt.Errorf("unbekannte Vorlage %q", name)
+	}
+	var betreff, text strings.Builder
+	if err := t.betreff.Execute(&betreff, daten); err != nil {
+		return Nachricht{}, err
+	}
+	if err := t.text.Execute(&text, daten); err != nil {
+		return Nachricht{}, err
+	}
+	return Nachricht{
+		An:      an,
+		Betreff: strings.TrimSpace(betreff.String()),
+		Text:    text.String(),
+	}, nil
+}
@@ -1,28 +1,60 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"sporttag/auswertung"
+	"sporttag/ereignisse"
+	"sporttag/gebuehren"
 	"sporttag/handler"
+	"sporttag/mailer"
+	"sporttag/parse"
+	"sporttag/planung"
+	"sporttag/urkunde"
+	"sporttag/wertung"
 )
 
 type Config struct {
 	Deadline       time.Time `json:"deadline"`
-	SuperUserPass  string    `json:"superuser_password"`
+	SuperUserPass  string    `json:"superuser_password"` // veraltet, wird abgelehnt
 	ParseAppID     string    `json:"parse_app_id"`
 	ParseJSKey     string    `json:"parse_js_key"`
 	ParseServerURL string    `json:"parse_server_url"`
+	ParseMasterKey string    `json:"parse_master_key"`
+	TokenSecret    string    `json:"token_secret"`
+	// Superuser für Änderungen nach der Deadline: Name → Passwort-Hash
+	Superuser map[string]string `json:"superuser"`
+	// Standardregeln der automatischen Riegenbildung
+	RiegenBildung planung.Regeln `json:"riegen_bildung"`
+	// Pfad der versionierten Punktetabellen (leer → keine Punkteberechnung)
+	Punktetabellen string `json:"punktetabellen"`
+	// Altersklassen und Tie-Break-Regeln der Rangliste
+	Rangliste auswertung.Regeln `json:"rangliste"`
+	// Urkunden-Vorlage und Punkteschwellen (leere vorlage → keine Urkunden)
+	Urkunden urkunde.Konfiguration `json:"urkunden"`
+	// Startgebühren, Geschwisterrabatt, Nachmeldezuschlag, Befreiungen
+	Gebuehren gebuehren.Konfiguration `json:"gebuehren"`
+	// Anzahl Live-Ereignisse, die für Last-Event-ID vorgehalten werden
+	EreignisPuffer int `json:"ereignis_puffer"`
+	// E-Mail-Versand (leeres backend → keine E-Mails)
+	Mail mailer.Konfiguration `json:"mail"`
 }
 
 // Lädt Konfigurationsdaten insbesondere das Ende-Datum der Registrierung
 // ab dem Ende-Datum haben beliebige Anwender keinen Zugriff mehr.
 //
-// lediglich der Superuser (Passwort in Umgebungsvariable SPORTTAG_SUPERUSER_PASS)
-// kann weiterhin Änderungen vornehmen.
+// lediglich die Superuser (je Organisator ein eigenes Passwort, in
+// config.json nur als Hash) können weiterhin Änderungen vornehmen.
 func loadConfig() (Config, error) {
 	var config Config
 	b, err := os.ReadFile("config.json")
@@ -30,26 +62,203 @@ func loadConfig() (Config, error) {
 		return config, err
 	}
 	err = json.Unmarshal(b, &config)
+	// Umgebungsvariable hat Vorrang vor config.json
+	if key := os.Getenv("SPORTTAG_PARSE_MASTER_KEY"); key != "" {
+		config.ParseMasterKey = key
+	}
+	if secret := os.Getenv("SPORTTAG_TOKEN_SECRET"); secret != "" {
+		config.TokenSecret = secret
+	}
+	if pass := os.Getenv("SPORTTAG_SMTP_PASSWORT"); pass != "" {
+		config.Mail.SMTP.Passwort = pass
+	}
 	return config, err
 }
 
+// Erzeugt den Hash eines Superuser-Passworts für config.json:
+//
+//	go run . passwort-hash < passwort.txt
+func passwortHashAusgeben() {
+	passwort, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatalf("Passwort lesen: %v", err)
+	}
+	passwort = strings.TrimRight(passwort, "\r\n")
+	if passwort == "" {
+		log.Fatal("leeres Passwort")
+	}
+	hash, err := handler.PasswortHash(passwort)
+	if err != nil {
+		log.Fatalf("Passwort-Hash: %v", err)
+	}
+	fmt.Println(hash)
+}
+
+// Übernimmt "bezahlt" aus der Zeit vor dem Kassenbuch als Buchung:
+//
+//	go run . kassenbuch-uebernahme [betragCent]
+//
+// Der Betrag ist nur ohne konfigurierte Gebühren nötig.
+func kassenbuchUebernehmen(h *handler.KindHandler) {
+	betrag := 0
+	if len(os.Args) > 2 {
+		var err error
+		if betrag, err = strconv.Atoi(os.Args[2]); err != nil || betrag <= 0 {
+			log.Fatalf("kassenbuch-uebernahme: ungültiger Betrag %q", os.Args[2])
+		}
+	}
+	n, err := h.KassenbuchUebernehmen(context.Background(), betrag)
+	if err != nil {
+		log.Fatalf("kassenbuch-uebernahme nach %d Buchungen: %v", n, err)
+	}
+	log.Printf("kassenbuch-uebernahme: %d Buchungen angelegt", n)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "passwort-hash" {
+		passwortHashAusgeben()
+		return
+	}
+
 	// ---- Konfiguration laden ----
 	config, err := loadConfig()
 	if err != nil {
 		log.Fatalf("Config-Fehler: %v", err)
 	}
+	if config.SuperUserPass != "" || os.Getenv("SPORTTAG_SUPERUSER_PASS") != "" {
+		log.Fatal("Config-Fehler: superuser_password wird nicht mehr unterstützt – " +
+			`je Organisator einen Eintrag in "superuser" anlegen (go run . passwort-hash)`)
+	}
+	for name, hash := range config.Superuser {
+		if name == "" || !handler.PasswortHashGueltig(hash) {
+			log.Fatalf("Config-Fehler (superuser): ungültiger Eintrag %q", name)
+		}
+	}
+
+	// ---- Klassenberechtigungen in Parse setzen (go run . schema) ----
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		schemaEinrichten(config)
+		return
+	}
 
 	// ---- Handler initialisieren ----
+	// Ohne parse_server_url läuft das Backend gegen einen In-Memory-Server
+	// (lokale Entwicklung ohne Back4App)
+	var parseClient parse.Client
+	if config.ParseServerURL != "" {
+		// geschützte Klassen sind nur mit dem Master-Key erreichbar (schema.go)
+		if config.ParseMasterKey == "" {
+			log.Fatal("Config-Fehler: parse_master_key (bzw. SPORTTAG_PARSE_MASTER_KEY) fehlt")
+		}
+		parseClient = parse.NewRESTClient(config.ParseServerURL, config.ParseAppID, config.ParseJSKey, config.ParseMasterKey)
+	} else {
+		log.Println("Keine parse_server_url konfiguriert – verwende In-Memory-Parse")
+		parseClient = parse.NewMemoryClient()
+	}
+
+	// ---- Punktetabellen laden ----
+	// Eine konfigurierte, aber fehlerhafte Datei ist ein Startfehler,
+	// damit nie nach einer falschen Tabelle gewertet wird.
+	var tabellen *wertung.Tabellen
+	if config.Punktetabellen != "" {
+		tabellen, err = wertung.Laden(config.Punktetabellen)
+		if err != nil {
+			log.Fatalf("Punktetabellen: %v", err)
+		}
+		log.Printf("Punktetabellen Version %s geladen", tabellen.Version)
+	}
+
+	if err := config.Rangliste.Validate(); err != nil {
+		log.Fatalf("Config-Fehler (rangliste): %v", err)
+	}
+	if err := config.Gebuehren.Validate(); err != nil {
+		log.Fatalf("Config-Fehler (gebuehren): %v", err)
+	}
+
+	// ---- Urkunden-Vorlage laden ----
+	var urkunden *urkunde.Generator
+	if config.Urkunden.Vorlage != "" {
+		urkunden, err = urkunde.NewGenerator(config.Urkunden)
+		if err != nil {
+			log.Fatalf("Urkunden: %v", err)
+		}
+	}
+
+	// ---- E-Mail-Versand ----
+	var mails mailer.Mailer
+	var mailVorlagen *mailer.Vorlagen
+	if config.Mail.Aktiv() {
+		mails, err = mailer.New(config.Mail)
+		if err != nil {
+			log.Fatalf("Config-Fehler (mail): %v", err)
+		}
+		mailVorlagen, err = mailer.LadeVorlagen(config.Mail.Vorlagen)
+		if err != nil {
+			log.Fatalf("Mail-Vorlagen: %v", err)
+		}
+	}
+
 	kindHandler := &handler.KindHandler{
-		Deadline:       config.Deadline,
-		ParseAppID:     config.ParseAppID,
-		ParseJSKey:     config.ParseJSKey,
-		ParseServerURL: config.ParseServerURL,
+		Deadline:      config.Deadline,
+		Superuser:     config.Superuser,
+		TokenSecret:   config.TokenSecret,
+		Parse:         parseClient,
+		RiegenBildung: config.RiegenBildung,
+		Wertung:       tabellen,
+		Rangliste:     config.Rangliste,
+		Urkunden:      urkunden,
+		Gebuehren:     config.Gebuehren,
+		Ereignisse:    ereignisse.NewBus(config.EreignisPuffer),
+		Mailer:        mails,
+		MailVorlagen:  mailVorlagen,
+	}
+
+	// ---- Altbestand ins Kassenbuch übernehmen (go run . kassenbuch-uebernahme) ----
+	if len(os.Args) > 1 && os.Args[1] == "kassenbuch-uebernahme" {
+		kassenbuchUebernehmen(kindHandler)
+		return
+	}
+
+	// ---- E-Mail-Ausgang im Hintergrund ----
+	if mails != nil {
+		intervall := time.Duration(config.Mail.Intervall) * time.Second
+		if intervall == 0 {
+			intervall = 30 * time.Second
+		}
+		kindHandler.MailAusgangStarten(context.Background(), intervall)
+		log.Printf("E-Mail-Versand über %s aktiv", config.Mail.Backend)
 	}
 
 	// 🔁 EINHEITLICHE RESSOURCE
 	http.HandleFunc("/kind", kindHandler.KindRouter)
+	http.HandleFunc("/kind/zusammenfuehren", kindHandler.KindZusammenfuehrenRouter)
+	http.HandleFunc("/anmeldung", kindHandler.AnmeldungRouter)
+	http.HandleFunc("/erziehungsberechtigter", kindHandler.ErziehungsberechtigterRouter)
+	http.HandleFunc("/zahlung", kindHandler.ZahlungRouter)
+	http.HandleFunc("/zahlung/bericht", kindHandler.ZahlungsberichtRouter)
+	http.HandleFunc("/gebuehren", kindHandler.GebuehrenRouter)
+	http.HandleFunc("/gebuehren/offen", kindHandler.OffeneGebuehrenRouter)
+	http.HandleFunc("/gebuehren/befreiung", kindHandler.BefreiungRouter)
+	http.HandleFunc("/gebuehren/erinnerung", kindHandler.ZahlungserinnerungRouter)
+	http.HandleFunc("/mail-ausgang", kindHandler.MailAusgangRouter)
+	http.HandleFunc("/riege", kindHandler.RiegeRouter)
+	http.HandleFunc("/riege-zuordnung", kindHandler.KinderDerRiegeRouter)
+	http.HandleFunc("/riege-zuordnung/verschieben", kindHandler.MoveKindToRiege)
+	http.HandleFunc("/riege-zuordnung/reihenfolge", kindHandler.ReorderRiege)
+	http.HandleFunc("/station", kindHandler.StationRouter)
+	http.HandleFunc("/station/beschreibung", kindHandler.UploadStationBeschreibung)
+	http.HandleFunc("/resultat", kindHandler.ResultatRouter)
+	http.HandleFunc("/punktetabellen", kindHandler.PunktetabellenRouter)
+	http.HandleFunc("/rangliste", kindHandler.RanglisteRouter)
+	http.HandleFunc("/urkunde", kindHandler.UrkundeRouter)
+	http.HandleFunc("/riegen-logging", kindHandler.RiegenLoggingRouter)
+	http.HandleFunc("/rotationsplan", kindHandler.RotationsplanRouter)
+	http.HandleFunc("/riegen-bildung", kindHandler.RiegenBildungRouter)
+	http.HandleFunc("/superuser-protokoll", kindHandler.SuperuserProtokollRouter)
+	http.HandleFunc("/audit", kindHandler.AuditRouter)
+	http.HandleFunc("/token", kindHandler.TokenRouter)
+	http.HandleFunc("/benutzerrolle", kindHandler.BenutzerrolleRouter)
+	http.HandleFunc("/ereignisse", kindHandler.EreignisseRouter)
 
 	// ---- Server starten ----
 	port := os.Getenv("PORT")
@@ -59,5 +268,6 @@ func main() {
 
 	// ---- Server starten ----
 	log.Println("Server läuft auf :" + port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	// jede Anfrage bekommt eine Request-ID (Audit-Log)
+	log.Fatal(http.ListenAndServe(":"+port, handler.RequestID(http.DefaultServeMux)))
 }
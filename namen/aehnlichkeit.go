@@ -0,0 +1,133 @@
+package namen
+
+import "strings"
+
+// KoelnerPhonetik liefert den Code nach der Kölner Phonetik
+// (Postel 1969), z. B. "Müller" → "657", "Hoeferlin" → "03756".
+// Der Name wird vorher normalisiert; Leerzeichen trennen nicht.
+func KoelnerPhonetik(name string) string {
+	var buchstaben []rune
+	for _, r := range strings.ToUpper(Normalisieren(name)) {
+		if r >= 'A' && r <= 'Z' {
+			buchstaben = append(buchstaben, r)
+		}
+	}
+
+	var codes []byte
+	for i, r := range buchstaben {
+		var vor, nach rune
+		if i > 0 {
+			vor = buchstaben[i-1]
+		}
+		if i+1 < len(buchstaben) {
+			nach = buchstaben[i+1]
+		}
+		switch r {
+		case 'A', 'E', 'I', 'J', 'O', 'U', 'Y':
+			codes = append(codes, '0')
+		case 'H':
+			// wird nicht codiert
+		case 'B':
+			codes = append(codes, '1')
+		case 'P':
+			if nach == 'H' {
+				codes = append(codes, '3')
+			} else {
+				codes = append(codes, '1')
+			}
+		case 'D', 'T':
+			if strings.ContainsRune("CSZ", nach) {
+				codes = append(codes, '8')
+			} else {
+				codes = append(codes, '2')
+			}
+		case 'F', 'V', 'W':
+			codes = append(codes, '3')
+		case 'G', 'K', 'Q':
+			codes = append(codes, '4')
+		case 'C':
+			switch {
+			case i == 0 && strings.ContainsRune("AHKLOQRUX", nach):
+				codes = append(codes, '4')
+			case i > 0 && !strings.ContainsRune("SZ", vor) && strings.ContainsRune("AHKOQUX", nach):
+				codes = append(codes, '4')
+			default:
+				codes = append(codes, '8')
+			}
+		case 'X':
+			if i > 0 && strings.ContainsRune("CKQ", vor) {
+				codes = append(codes, '8')
+			} else {
+				codes = append(codes, '4', '8')
+			}
+		case 'L':
+			codes = append(codes, '5')
+		case 'M', 'N':
+			codes = append(codes, '6')
+		case 'R':
+			codes = append(codes, '7')
+		case 'S', 'Z':
+			codes = append(codes, '8')
+		}
+	}
+
+	// gleiche Nachbarn zusammenfassen, dann "0" außer am Anfang entfernen
+	var out []byte
+	for i, c := range codes {
+		if i > 0 && codes[i-1] == c {
+			continue
+		}
+		if c == '0' && i > 0 {
+			continue
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// Levenshtein: Anzahl Einfügungen, Löschungen und Ersetzungen (je Zeichen)
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	vorher := make([]int, len(rb)+1)
+	aktuell := make([]int, len(rb)+1)
+	for j := range vorher {
+		vorher[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		aktuell[0] = i
+		for j := 1; j <= len(rb); j++ {
+			kosten := 1
+			if ra[i-1] == rb[j-1] {
+				kosten = 0
+			}
+			aktuell[j] = min(vorher[j]+1, aktuell[j-1]+1, vorher[j-1]+kosten)
+		}
+		vorher, aktuell = aktuell, vorher
+	}
+	return vorher[len(rb)]
+}
+
+// Gleich: nach Normalisierung identisch ("max " = "Max", "Hoeferlin" = "Höferlin")
+func Gleich(a, b string) bool {
+	return Normalisieren(a) == Normalisieren(b)
+}
+
+// Aehnlich: gleich, gleich klingend (Kölner Phonetik) oder mit wenigen
+// Tippfehlern (bis 3 Zeichen keiner, bis 7 Zeichen einer, sonst zwei).
+func Aehnlich(a, b string) bool {
+	na, nb := Normalisieren(a), Normalisieren(b)
+	if na == nb {
+		return true
+	}
+	if pa := KoelnerPhonetik(na); pa != "" && pa == KoelnerPhonetik(nb) {
+		return true
+	}
+	erlaubt := 0
+	switch kuerzer := min(len([]rune(na)), len([]rune(nb))); {
+	case kuerzer >= 8:
+		erlaubt = 2
+	case kuerzer >= 4:
+		erlaubt = 1
+	}
+	return Levenshtein(na, nb) <= erlaubt
+}
@@ -0,0 +1,98 @@
+// Package namen vergleicht Personennamen für die Duplikaterkennung:
+// Normalisierung (Leerraum, Groß-/Kleinschreibung, Umlaute, ß, Unicode
+// NFC), Kölner Phonetik und Levenshtein-Distanz.
+//
+// Wie planung und auswertung arbeitet es nur auf Zeichenketten und
+// kennt weder Parse noch HTTP.
+package namen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kombinierendes Zeichen → Paare aus Grundbuchstabe und zusammengesetztem
+// Buchstaben. Deckt die in Namen üblichen lateinischen Buchstaben ab
+// (ohne golang.org/x/text); daraus entstehen NFC und das Entfernen
+// diakritischer Zeichen.
+var kombinationen = map[rune]string{
+	'\u0300': "AÀEÈIÌOÒUÙaàeèiìoòuù",                     // Gravis
+	'\u0301': "AÁEÉIÍOÓUÚYÝaáeéiíoóuúyýCĆcćNŃnńSŚsśZŹzź", // Akut
+	'\u0302': "AÂEÊIÎOÔUÛaâeêiîoôuû",                     // Zirkumflex
+	'\u0303': "AÃNÑOÕaãnñoõ",                             // Tilde
+	'\u0308': "AÄEËIÏOÖUÜaäeëiïoöuüyÿ",                   // Trema/Umlaut
+	'\u030A': "AÅaåUŮuů",                                 // Ring
+	'\u0327': "CÇcçSŞsş",                                 // Cedille
+	'\u030C': "CČcčSŠsšZŽzžRŘrřEĚeěNŇnň",                 // Hatschek
+}
+
+var (
+	zusammen  = map[[2]rune]rune{} // Grundbuchstabe + kombinierendes Zeichen → NFC
+	grundform = map[rune]rune{}    // zusammengesetzt → Grundbuchstabe
+)
+
+func init() {
+	for kombinierend, paare := range kombinationen {
+		r := []rune(paare)
+		for i := 0; i+1 < len(r); i += 2 {
+			zusammen[[2]rune{r[i], kombinierend}] = r[i+1]
+			grundform[r[i+1]] = r[i]
+		}
+	}
+}
+
+// Faltungen vor dem Vergleich: Umlaute wie in "Hoeferlin" geschrieben
+var faltung = map[rune]string{
+	'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss",
+	'æ': "ae", 'ø': "oe", 'œ': "oe",
+}
+
+// NFC setzt Grundbuchstabe + kombinierendes Zeichen zu einem Zeichen
+// zusammen ("ü" → "ü"), wie es macOS-Tastaturen und manche
+// Formulare liefern.
+func NFC(s string) string {
+	var out []rune
+	for _, r := range s {
+		if n := len(out); n > 0 && unicode.Is(unicode.Mn, r) {
+			if z, ok := zusammen[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = z
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// Bereinigen bereitet einen Namen zum Speichern auf: NFC, Leerraum am
+// Rand entfernt und innen auf ein Leerzeichen reduziert.
+// Groß-/Kleinschreibung bleibt erhalten.
+func Bereinigen(s string) string {
+	return strings.Join(strings.Fields(NFC(s)), " ")
+}
+
+// Normalisieren liefert die Vergleichsform eines Namens: bereinigt,
+// klein geschrieben, ä/ö/ü/ß gefaltet ("Höferlin" = "hoeferlin"),
+// übrige diakritische Zeichen entfernt ("René" = "rene"),
+// Bindestriche und Apostrophe wie Leerzeichen.
+func Normalisieren(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(Bereinigen(s)) {
+		if f, ok := faltung[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+		if g, ok := grundform[r]; ok {
+			r = g
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// verbliebene kombinierende Zeichen ohne Grundbuchstaben
+		case r == '-' || r == '\'' || r == '’' || unicode.IsSpace(r):
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,116 @@
+package namen
+
+import "testing"
+
+func TestNormalisieren(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"Umlaut", "Höferlin", "hoeferlin"},
+		{"Umlaut ausgeschrieben", "Hoeferlin", "hoeferlin"},
+		{"Leerraum und Großschreibung", "  max ", "max"},
+		{"zerlegtes ü", "Mu\u0308ller", "mueller"},
+		{"ß", "Straße", "strasse"},
+		{"Akzent", "René", "rene"},
+		{"Bindestrich und Apostroph", "  Jean-Luc  O’Brien ", "jean luc o brien"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalisieren(tt.in); got != tt.want {
+				t.Errorf("Normalisieren(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBereinigen(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"max ", "max"},
+		{"  Anna   Lena ", "Anna Lena"},
+		{"Mu\u0308ller", "Müller"}, // NFC
+	}
+	for _, tt := range tests {
+		if got := Bereinigen(tt.in); got != tt.want {
+			t.Errorf("Bereinigen(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKoelnerPhonetik(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Müller", "657"},
+		{"Müller-Lüdenscheidt", "65752682"},
+		{"Höferlin", "03756"},
+		{"Hoeferlin", "03756"},
+		{"Meyer", "67"},
+		{"Maier", "67"},
+		{"Schmidt", "862"},
+		{"Schmitt", "862"},
+		{"Christoph", "47823"},
+		{"Xaver", "4837"},
+		{"Lukas", "548"},
+		{"Lucas", "548"},
+		{"Wolfgang", "353464"},
+	}
+	for _, tt := range tests {
+		if got := KoelnerPhonetik(tt.in); got != tt.want {
+			t.Errorf("KoelnerPhonetik(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"kitten", "sitting", 3},
+		{"müller", "muller", 1}, // je Zeichen, nicht je Byte
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGleichUndAehnlich(t *testing.T) {
+	tests := []struct {
+		a, b         string
+		wantGleich   bool
+		wantAehnlich bool
+	}{
+		{"Höferlin", "Hoeferlin", true, true},
+		{"max ", "Max", true, true},
+		{"Mu\u0308ller", "Müller", true, true},
+		{"Meyer", "Maier", false, true}, // gleich klingend
+		// Tippfehler: bis 3 Zeichen keiner …
+		{"Ida", "Ina", false, false},
+		// … bis 7 Zeichen einer …
+		{"Nina", "Nika", false, true},
+		{"Nina", "Niko", false, false},
+		// … sonst zwei
+		{"Bernhardt", "Berkhardz", false, true},
+		{"Bernhardt", "Berkhartz", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := Gleich(tt.a, tt.b); got != tt.wantGleich {
+				t.Errorf("Gleich = %v, want %v", got, tt.wantGleich)
+			}
+			if got := Aehnlich(tt.a, tt.b); got != tt.wantAehnlich {
+				t.Errorf("Aehnlich = %v, want %v", got, tt.wantAehnlich)
+			}
+		})
+	}
+}
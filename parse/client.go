@@ -0,0 +1,173 @@
+// Package parse kapselt den Zugriff auf den Parse-Server (Back4App).
+//
+// Die Handler arbeiten ausschließlich gegen das Interface Client
+// (ParseClient). Für den Betrieb gibt es die REST-Implementierung,
+// für Tests und lokale Entwicklung eine In-Memory-Implementierung,
+// die sich wie Parse verhält (where-Queries, Increment, bedingte Updates).
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"sporttag/strukturen"
+)
+
+// Client ist die ParseClient-Abstraktion für alle Handler
+type Client interface {
+	// Query liefert die Objekte einer Klasse zur Abfrage q (nil = alle)
+	Query(ctx context.Context, className string, q *Query) (*QueryResult, error)
+	// Get liefert ein einzelnes Objekt, include optional
+	Get(ctx context.Context, className, objectID string, include ...string) (Object, error)
+	// Create legt ein Objekt an und liefert objectId und createdAt
+	Create(ctx context.Context, className string, data any) (Object, error)
+	// Update ändert ein Objekt; ist where gesetzt, wird nur geändert,
+	// wenn das Objekt die Bedingung erfüllt (sonst ErrNotFound)
+	Update(ctx context.Context, className, objectID string, data any, where map[string]any) (Object, error)
+	// Delete löscht ein Objekt
+	Delete(ctx context.Context, className, objectID string) error
+	// Batch führt mehrere Operationen in einem Request aus
+	Batch(ctx context.Context, ops []BatchOp) ([]BatchResult, error)
+	// UploadFile speichert eine Datei über die Parse Files API;
+	// das Ergebnis wird anschließend einem File-Feld zugewiesen
+	UploadFile(ctx context.Context, name, contentType string, data io.Reader) (*strukturen.ParseFile, error)
+	// DeleteFile löscht eine hochgeladene Datei (name wie von UploadFile
+	// geliefert); erfordert bei Parse den Master-Key
+	DeleteFile(ctx context.Context, name string) error
+	// CurrentUser liefert den _User zu einem Session-Token (GET /users/me),
+	// ErrInvalidSession wenn die Session ungültig ist
+	CurrentUser(ctx context.Context, sessionToken string) (Object, error)
+}
+
+// Kompilierzeit-Prüfung: beide Implementierungen erfüllen Client
+var (
+	_ Client = (*RESTClient)(nil)
+	_ Client = (*MemoryClient)(nil)
+)
+
+// ===== Objekte =====
+
+// Object ist ein Parse-Objekt, wie es der Server als JSON liefert
+type Object map[string]any
+
+// ObjectID liefert die objectId oder ""
+func (o Object) ObjectID() string {
+	s, _ := o["objectId"].(string)
+	return s
+}
+
+// String liefert ein String-Feld oder ""
+func (o Object) String(key string) string {
+	s, _ := o[key].(string)
+	return s
+}
+
+// Int liefert ein Zahlenfeld als int (JSON-Zahlen sind float64)
+func (o Object) Int(key string) int {
+	switch v := o[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// Bool liefert ein bool-Feld oder false
+func (o Object) Bool(key string) bool {
+	b, _ := o[key].(bool)
+	return b
+}
+
+// Decode überträgt das Objekt in eine Struktur (z. B. strukturen.Kind)
+func (o Object) Decode(v any) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// QueryResult entspricht der Antwort von GET /classes/<Klasse>
+type QueryResult struct {
+	Results []Object `json:"results"`
+	Count   int      `json:"count"`
+}
+
+// ===== Batch =====
+
+// BatchOp ist eine einzelne Operation in POST /batch
+type BatchOp struct {
+	Method    string // POST, PUT oder DELETE
+	ClassName string
+	ObjectID  string // leer bei POST
+	Body      any
+}
+
+// BatchResult ist das Ergebnis einer BatchOp, genau eines der Felder ist gesetzt
+type BatchResult struct {
+	Success Object `json:"success,omitempty"`
+	Error   *Error `json:"error,omitempty"`
+}
+
+// ===== Fehler =====
+
+// Error ist ein Fehler, den der Parse-Server gemeldet hat
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("parse: %d %s", e.Code, e.Message)
+}
+
+// Is erlaubt errors.Is(err, parse.ErrNotFound) über den Parse-Fehlercode
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrNotFound: Objekt existiert nicht oder where-Bedingung nicht erfüllt
+var ErrNotFound = &Error{Code: 101, Message: "Object not found."}
+
+// ErrInvalidQuery: where-Ausdruck konnte nicht ausgewertet werden
+var ErrInvalidQuery = &Error{Code: 102, Message: "Invalid query."}
+
+// ErrInvalidSession: Session-Token unbekannt oder abgelaufen
+var ErrInvalidSession = &Error{Code: 209, Message: "Invalid session token"}
+
+// IsNotFound ist eine Abkürzung für errors.Is(err, ErrNotFound)
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Maximale Seitengröße einer Parse-Abfrage
+const maxLimit = 1000
+
+// QueryAll lädt alle Treffer seitenweise (Parse liefert höchstens 1000
+// Objekte je Abfrage). Ohne Sortierung wird nach objectId sortiert, damit
+// die Seiten stabil sind. Limit und Skip der Abfrage werden überschrieben.
+func QueryAll(ctx context.Context, c Client, className string, q *Query) ([]Object, error) {
+	if q == nil {
+		q = NewQuery()
+	}
+	if len(q.order) == 0 {
+		q.Order("objectId")
+	}
+
+	var alle []Object
+	for skip := 0; ; skip += maxLimit {
+		out, err := c.Query(ctx, className, q.Limit(maxLimit).Skip(skip))
+		if err != nil {
+			return nil, err
+		}
+		alle = append(alle, out.Results...)
+		if len(out.Results) < maxLimit {
+			return alle, nil
+		}
+	}
+}
@@ -0,0 +1,738 @@
+package parse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sporttag/strukturen"
+)
+
+// Parse liefert ohne limit höchstens 100 Objekte
+const defaultLimit = 100
+
+// MemoryClient hält alle Klassen im Speicher und bildet die
+// Semantik des Parse-Servers nach. Gedacht für Tests und lokale
+// Entwicklung ohne Back4App.
+type MemoryClient struct {
+	mu      sync.Mutex
+	classes map[string]map[string]Object
+	files   map[string]memoryFile
+	// Now liefert die Serverzeit, in Tests austauschbar
+	Now func() time.Time
+}
+
+// NewMemoryClient erzeugt einen leeren In-Memory-Parse-Server
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		classes: map[string]map[string]Object{},
+		files:   map[string]memoryFile{},
+		Now:     time.Now,
+	}
+}
+
+// ======  Hilfsfunktionen  ======
+
+// Zeitformat der Parse-Felder createdAt/updatedAt
+const parseTimeFormat = "2006-01-02T15:04:05.000Z"
+
+func (m *MemoryClient) now() string {
+	return m.Now().UTC().Format(parseTimeFormat)
+}
+
+// objectId im Stil von Parse: 10 alphanumerische Zeichen
+func newObjectID() string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, 10)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// Überführt beliebige Daten in generisches JSON (map, float64, ...),
+// damit gespeicherte Objekte unabhängig vom Aufrufer sind
+func toGeneric(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = map[string]any{}
+	}
+	return out, nil
+}
+
+func copyObject(o Object) Object {
+	c, _ := toGeneric(o)
+	return c
+}
+
+func (m *MemoryClient) class(className string) map[string]Object {
+	c, ok := m.classes[className]
+	if !ok {
+		c = map[string]Object{}
+		m.classes[className] = c
+	}
+	return c
+}
+
+// ======  Client-Implementierung  ======
+
+func (m *MemoryClient) Query(ctx context.Context, className string, q *Query) (*QueryResult, error) {
+	// gleiche Parameter wie beim REST-Aufruf auswerten
+	params, err := q.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	var where map[string]any
+	if w := params.Get("where"); w != "" {
+		if err := json.Unmarshal([]byte(w), &where); err != nil {
+			return nil, ErrInvalidQuery
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// ---- Filtern ----
+	var treffer []Object
+	for _, o := range m.class(className) {
+		ok, err := matches(o, where)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			treffer = append(treffer, o)
+		}
+	}
+
+	// ---- Sortieren (stabil nach objectId, damit Ergebnisse reproduzierbar sind) ----
+	sort.Slice(treffer, func(i, j int) bool {
+		return treffer[i].ObjectID() < treffer[j].ObjectID()
+	})
+	if order := params.Get("order"); order != "" {
+		sortObjects(treffer, strings.Split(order, ","))
+	}
+
+	out := &QueryResult{}
+	if params.Get("count") == "1" {
+		out.Count = len(treffer)
+	}
+
+	// ---- skip / limit ----
+	skip, _ := strconv.Atoi(params.Get("skip"))
+	limit := defaultLimit
+	if l := params.Get("limit"); l != "" {
+		limit, _ = strconv.Atoi(l)
+	}
+	if skip > len(treffer) {
+		skip = len(treffer)
+	}
+	treffer = treffer[skip:]
+	if limit >= 0 && limit < len(treffer) {
+		treffer = treffer[:limit]
+	}
+
+	// ---- keys / include ----
+	var keys []string
+	if k := params.Get("keys"); k != "" {
+		keys = strings.Split(k, ",")
+	}
+	var include []string
+	if i := params.Get("include"); i != "" {
+		include = strings.Split(i, ",")
+	}
+
+	out.Results = make([]Object, 0, len(treffer))
+	for _, o := range treffer {
+		c := copyObject(o)
+		if keys != nil {
+			c = selectKeys(c, keys)
+		}
+		m.expand(c, include)
+		out.Results = append(out.Results, c)
+	}
+	return out, nil
+}
+
+func (m *MemoryClient) Get(ctx context.Context, className, objectID string, include ...string) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.class(className)[objectID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := copyObject(o)
+	m.expand(c, include)
+	return c, nil
+}
+
+func (m *MemoryClient) Create(ctx context.Context, className string, data any) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(className, data)
+}
+
+func (m *MemoryClient) create(className string, data any) (Object, error) {
+	felder, err := toGeneric(data)
+	if err != nil {
+		return nil, err
+	}
+
+	o := Object{}
+	if err := applyUpdate(o, felder); err != nil {
+		return nil, err
+	}
+	id := newObjectID()
+	ts := m.now()
+	o["objectId"] = id
+	o["createdAt"] = ts
+	o["updatedAt"] = ts
+	m.class(className)[id] = o
+
+	return Object{"objectId": id, "createdAt": ts}, nil
+}
+
+func (m *MemoryClient) Update(ctx context.Context, className, objectID string, data any, where map[string]any) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.update(className, objectID, data, where)
+}
+
+func (m *MemoryClient) update(className, objectID string, data any, where map[string]any) (Object, error) {
+	o, ok := m.class(className)[objectID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// ---- bedingtes Update: where muss auf das Objekt passen ----
+	if len(where) > 0 {
+		w, err := toGeneric(where)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := matches(o, w)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotFound
+		}
+	}
+
+	felder, err := toGeneric(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// auf Kopie arbeiten, damit ein fehlerhaftes Update nichts verändert
+	c := copyObject(o)
+	if err := applyUpdate(c, felder); err != nil {
+		return nil, err
+	}
+	ts := m.now()
+	c["updatedAt"] = ts
+	m.class(className)[objectID] = c
+
+	// Parse liefert bei Increment den neuen Wert mit zurück
+	out := Object{"updatedAt": ts}
+	for k, v := range felder {
+		if op, ok := v.(map[string]any); ok && op["__op"] == "Increment" {
+			out[k] = c[k]
+		}
+	}
+	return out, nil
+}
+
+func (m *MemoryClient) Delete(ctx context.Context, className, objectID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.delete(className, objectID)
+}
+
+func (m *MemoryClient) delete(className, objectID string) error {
+	c := m.class(className)
+	if _, ok := c[objectID]; !ok {
+		return ErrNotFound
+	}
+	delete(c, objectID)
+	return nil
+}
+
+func (m *MemoryClient) Batch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// wie bei Parse: jede Operation für sich, keine Transaktion
+	results := make([]BatchResult, 0, len(ops))
+	for _, op := range ops {
+		var (
+			out Object
+			err error
+		)
+		switch op.Method {
+		case http.MethodPost:
+			out, err = m.create(op.ClassName, op.Body)
+		case http.MethodPut:
+			out, err = m.update(op.ClassName, op.ObjectID, op.Body, nil)
+		case http.MethodDelete:
+			err = m.delete(op.ClassName, op.ObjectID)
+			out = Object{}
+		default:
+			err = &Error{Code: 107, Message: "unsupported batch method " + op.Method}
+		}
+
+		if err != nil {
+			perr, ok := err.(*Error)
+			if !ok {
+				perr = &Error{Code: 1, Message: err.Error()}
+			}
+			results = append(results, BatchResult{Error: perr})
+			continue
+		}
+		results = append(results, BatchResult{Success: out})
+	}
+	return results, nil
+}
+
+// Hochgeladene Datei im Speicher
+type memoryFile struct {
+	ContentType string
+	Data        []byte
+}
+
+// Dateien erhalten wie bei Parse einen eindeutigen Präfix
+func (m *MemoryClient) UploadFile(ctx context.Context, name, contentType string, data io.Reader) (*strukturen.ParseFile, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gespeichert := newObjectID() + "_" + name
+	m.files[gespeichert] = memoryFile{ContentType: contentType, Data: b}
+	return &strukturen.ParseFile{Name: gespeichert, URL: "memory://files/" + gespeichert}, nil
+}
+
+func (m *MemoryClient) DeleteFile(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; !ok {
+		return ErrNotFound
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// AnzahlDateien liefert die Zahl der gespeicherten Dateien (für Tests)
+func (m *MemoryClient) AnzahlDateien() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.files)
+}
+
+// File liefert eine hochgeladene Datei (für Tests)
+func (m *MemoryClient) File(name string) (contentType string, data []byte, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.files[name]
+	return f.ContentType, f.Data, ok
+}
+
+// Benutzer werden in der Klasse "_User" mit Feld "sessionToken" abgelegt
+func (m *MemoryClient) CurrentUser(ctx context.Context, sessionToken string) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sessionToken == "" {
+		return nil, ErrInvalidSession
+	}
+	for _, u := range m.class("_User") {
+		if u.String("sessionToken") == sessionToken {
+			return copyObject(u), nil
+		}
+	}
+	return nil, ErrInvalidSession
+}
+
+// ======  include / keys  ======
+
+// Ersetzt Pointer-Felder durch das vollständige Objekt ("__type": "Object").
+// Pfade mit Punkt (z. B. "kindID.erziehungsberechtigterID") werden rekursiv aufgelöst.
+func (m *MemoryClient) expand(o Object, include []string) {
+	for _, path := range include {
+		teile := strings.SplitN(path, ".", 2)
+		ptr, ok := o[teile[0]].(map[string]any)
+		if !ok || ptr["__type"] != "Pointer" {
+			if ok && ptr["__type"] == "Object" && len(teile) == 2 {
+				m.expand(ptr, teile[1:])
+			}
+			continue
+		}
+		className, _ := ptr["className"].(string)
+		objectID, _ := ptr["objectId"].(string)
+		ziel, ok := m.class(className)[objectID]
+		if !ok {
+			// wie Parse: fehlendes Ziel bleibt ein Pointer
+			continue
+		}
+		c := copyObject(ziel)
+		c["__type"] = "Object"
+		c["className"] = className
+		if len(teile) == 2 {
+			m.expand(c, teile[1:])
+		}
+		o[teile[0]] = map[string]any(c)
+	}
+}
+
+func selectKeys(o Object, keys []string) Object {
+	out := Object{
+		"objectId":  o["objectId"],
+		"createdAt": o["createdAt"],
+		"updatedAt": o["updatedAt"],
+	}
+	for _, k := range keys {
+		k = strings.SplitN(k, ".", 2)[0]
+		if v, ok := o[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// ======  Sortierung  ======
+
+func sortObjects(objs []Object, order []string) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		for _, feld := range order {
+			desc := strings.HasPrefix(feld, "-")
+			feld = strings.TrimPrefix(feld, "-")
+			c, ok := compare(objs[i][feld], objs[j][feld])
+			if !ok || c == 0 {
+				continue
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// ======  where-Auswertung  ======
+
+func matches(o Object, where map[string]any) (bool, error) {
+	for key, cond := range where {
+		switch key {
+		case "$or":
+			teile, ok := cond.([]any)
+			if !ok {
+				return false, ErrInvalidQuery
+			}
+			eines := false
+			for _, t := range teile {
+				tw, ok := t.(map[string]any)
+				if !ok {
+					return false, ErrInvalidQuery
+				}
+				ok, err := matches(o, tw)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					eines = true
+					break
+				}
+			}
+			if !eines {
+				return false, nil
+			}
+			continue
+		case "$and":
+			teile, ok := cond.([]any)
+			if !ok {
+				return false, ErrInvalidQuery
+			}
+			for _, t := range teile {
+				tw, ok := t.(map[string]any)
+				if !ok {
+					return false, ErrInvalidQuery
+				}
+				ok, err := matches(o, tw)
+				if err != nil || !ok {
+					return false, err
+				}
+			}
+			continue
+		}
+
+		wert, vorhanden := o[key]
+		ok, err := matchField(wert, vorhanden, cond)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Prüft einen einzelnen Feldwert gegen eine Bedingung
+func matchField(wert any, vorhanden bool, cond any) (bool, error) {
+	ops, isMap := cond.(map[string]any)
+	if !isMap || !hasOperators(ops) {
+		// einfache Gleichheit (auch Pointer/Date), bei Arrays: enthält
+		return equalsOrContains(wert, cond), nil
+	}
+
+	for op, arg := range ops {
+		switch op {
+		case "$eq":
+			if !equalsOrContains(wert, arg) {
+				return false, nil
+			}
+		case "$ne":
+			if equalsOrContains(wert, arg) {
+				return false, nil
+			}
+		case "$in", "$nin":
+			liste, ok := arg.([]any)
+			if !ok {
+				return false, ErrInvalidQuery
+			}
+			drin := false
+			for _, l := range liste {
+				if equalsOrContains(wert, l) {
+					drin = true
+					break
+				}
+			}
+			if drin != (op == "$in") {
+				return false, nil
+			}
+		case "$all":
+			liste, ok := arg.([]any)
+			if !ok {
+				return false, ErrInvalidQuery
+			}
+			for _, l := range liste {
+				if !equalsOrContains(wert, l) {
+					return false, nil
+				}
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			c, ok := compare(wert, arg)
+			if !ok {
+				return false, nil
+			}
+			if (op == "$gt" && c <= 0) || (op == "$gte" && c < 0) ||
+				(op == "$lt" && c >= 0) || (op == "$lte" && c > 0) {
+				return false, nil
+			}
+		case "$exists":
+			soll, _ := arg.(bool)
+			if (vorhanden && wert != nil) != soll {
+				return false, nil
+			}
+		case "$regex":
+			muster, ok := arg.(string)
+			if !ok {
+				return false, ErrInvalidQuery
+			}
+			if opt, _ := ops["$options"].(string); strings.Contains(opt, "i") {
+				muster = "(?i)" + muster
+			}
+			re, err := regexp.Compile(muster)
+			if err != nil {
+				return false, ErrInvalidQuery
+			}
+			s, ok := wert.(string)
+			if !ok || !re.MatchString(s) {
+				return false, nil
+			}
+		case "$options":
+			// wird bei $regex ausgewertet
+		default:
+			return false, &Error{Code: 102, Message: "Invalid query operator " + op}
+		}
+	}
+	return true, nil
+}
+
+func hasOperators(m map[string]any) bool {
+	for k := range m {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// Gleichheit nach Parse-Regeln; Arrays matchen, wenn sie den Wert enthalten
+func equalsOrContains(wert, soll any) bool {
+	if arr, ok := wert.([]any); ok {
+		if _, sollArr := soll.([]any); !sollArr {
+			for _, e := range arr {
+				if equal(e, soll) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return equal(wert, soll)
+}
+
+func equal(a, b any) bool {
+	// Pointer: nur className und objectId vergleichen
+	if pa, ok := pointerKey(a); ok {
+		pb, ok := pointerKey(b)
+		return ok && pa == pb
+	}
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// Pointer und expandierte Objekte werden über className/objectId verglichen
+func pointerKey(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	if m["__type"] != "Pointer" && m["__type"] != "Object" {
+		return "", false
+	}
+	c, _ := m["className"].(string)
+	id, _ := m["objectId"].(string)
+	return c + "$" + id, true
+}
+
+// Vergleicht Zahlen, Strings, bools und Parse-Dates; ok=false bei unvergleichbaren Typen
+func compare(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			// false < true
+			return -1, true
+		}
+		return 1, true
+	case map[string]any:
+		// {"__type": "Date", "iso": "..."} – ISO-Strings sind lexikographisch sortierbar
+		xa, xok := dateISO(x)
+		ym, _ := b.(map[string]any)
+		ya, yok := dateISO(ym)
+		if xok && yok {
+			return strings.Compare(xa, ya), true
+		}
+	}
+	return 0, false
+}
+
+func dateISO(m map[string]any) (string, bool) {
+	if m == nil || m["__type"] != "Date" {
+		return "", false
+	}
+	iso, ok := m["iso"].(string)
+	return iso, ok
+}
+
+// ======  Update-Operationen  ======
+
+// Wendet Felder und __op-Operationen (Increment, Delete, Add, AddUnique, Remove) an
+func applyUpdate(o Object, felder map[string]any) error {
+	for k, v := range felder {
+		op, ok := v.(map[string]any)
+		if !ok || op["__op"] == nil {
+			o[k] = v
+			continue
+		}
+
+		switch op["__op"] {
+		case "Increment":
+			amount, _ := op["amount"].(float64)
+			alt, _ := o[k].(float64)
+			o[k] = alt + amount
+		case "Delete":
+			delete(o, k)
+		case "Add", "AddUnique", "Remove":
+			objs, _ := op["objects"].([]any)
+			arr, _ := o[k].([]any)
+			for _, neu := range objs {
+				switch op["__op"] {
+				case "Add":
+					arr = append(arr, neu)
+				case "AddUnique":
+					if !equalsOrContains(arr, neu) {
+						arr = append(arr, neu)
+					}
+				case "Remove":
+					rest := arr[:0]
+					for _, e := range arr {
+						if !equal(e, neu) {
+							rest = append(rest, e)
+						}
+					}
+					arr = rest
+				}
+			}
+			if arr == nil {
+				arr = []any{}
+			}
+			o[k] = arr
+		default:
+			return &Error{Code: 111, Message: fmt.Sprint("unsupported __op ", op["__op"])}
+		}
+	}
+	return nil
+}
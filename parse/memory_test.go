@@ -0,0 +1,365 @@
+package parse
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"sporttag/strukturen"
+)
+
+// Legt Testkinder an und liefert objectId je Vorname
+func kinderAnlegen(t *testing.T, m *MemoryClient) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+
+	riege, err := m.Create(ctx, "Riege", map[string]any{"riegenNummer": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	kinder := []map[string]any{
+		{"vorName": "Anna", "nachName": "Muster", "jahrgang": 2012, "bezahlt": true, "tags": []string{"helfer"},
+			"riegeID": strukturen.NewParsePointer("Riege", riege.ObjectID()), "angemeldetAm": strukturen.NewParseDate(datum)},
+		{"vorName": "Ben", "nachName": "Muster", "jahrgang": 2014, "bezahlt": false,
+			"angemeldetAm": strukturen.NewParseDate(datum.AddDate(0, 0, 1))},
+		{"vorName": "Cleo", "nachName": `O"Brien`, "jahrgang": 2016, "bezahlt": true},
+	}
+	ids := map[string]string{}
+	for _, k := range kinder {
+		out, err := m.Create(ctx, "Kind", k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[k["vorName"].(string)] = out.ObjectID()
+	}
+	ids["riege"] = riege.ObjectID()
+	return ids
+}
+
+func vornamen(objs []Object) []string {
+	var namen []string
+	for _, o := range objs {
+		namen = append(namen, o.String("vorName"))
+	}
+	return namen
+}
+
+func TestMemoryQueryWhere(t *testing.T) {
+	m := NewMemoryClient()
+	ids := kinderAnlegen(t, m)
+	stichtag := strukturen.NewParseDate(time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  []string
+	}{
+		{"alle", nil, []string{"Anna", "Ben", "Cleo"}},
+		{"gleich", NewQuery().EqualTo("nachName", "Muster"), []string{"Anna", "Ben"}},
+		{"gleich mit Sonderzeichen", NewQuery().EqualTo("nachName", `O"Brien`), []string{"Cleo"}},
+		{"ungleich", NewQuery().NotEqualTo("nachName", "Muster"), []string{"Cleo"}},
+		{"ungleich fehlendes Feld", NewQuery().NotEqualTo("riegeID", strukturen.NewParsePointer("Riege", ids["riege"])), []string{"Ben", "Cleo"}},
+		{"bool", NewQuery().EqualTo("bezahlt", false), []string{"Ben"}},
+		{"in", NewQuery().In("jahrgang", 2012, 2016), []string{"Anna", "Cleo"}},
+		{"nicht in", NewQuery().NotIn("jahrgang", 2012, 2016), []string{"Ben"}},
+		{"größer", NewQuery().GreaterThan("jahrgang", 2012), []string{"Ben", "Cleo"}},
+		{"größer gleich", NewQuery().GreaterThanOrEqualTo("jahrgang", 2014), []string{"Ben", "Cleo"}},
+		{"kleiner", NewQuery().LessThan("jahrgang", 2014), []string{"Anna"}},
+		{"kleiner gleich", NewQuery().LessThanOrEqualTo("jahrgang", 2014), []string{"Anna", "Ben"}},
+		{"Bereich", NewQuery().GreaterThan("jahrgang", 2012).LessThan("jahrgang", 2016), []string{"Ben"}},
+		{"Datum", NewQuery().LessThan("angemeldetAm", stichtag), []string{"Anna"}},
+		{"existiert", NewQuery().Exists("angemeldetAm", true), []string{"Anna", "Ben"}},
+		{"existiert nicht", NewQuery().Exists("angemeldetAm", false), []string{"Cleo"}},
+		{"Pointer", NewQuery().PointerTo("riegeID", "Riege", ids["riege"]), []string{"Anna"}},
+		{"Pointer in", NewQuery().PointerIn("riegeID", "Riege", ids["riege"], "gibtsnicht"), []string{"Anna"}},
+		{"Array enthält", NewQuery().EqualTo("tags", "helfer"), []string{"Anna"}},
+		{"beginnt mit", NewQuery().StartsWith("vorName", "B"), []string{"Ben"}},
+		{"beginnt mit Sonderzeichen", NewQuery().StartsWith("nachName", `O"`), []string{"Cleo"}},
+		{"Regex als Wert", NewQuery().EqualTo("nachName", `{"$regex":".*"}`), nil},
+		{"Regex ohne Treffer bei Metazeichen", NewQuery().Contains("vorName", ".*"), nil},
+		{"oder", NewQuery().Or(
+			NewQuery().EqualTo("vorName", "Anna"),
+			NewQuery().EqualTo("jahrgang", 2016),
+		), []string{"Anna", "Cleo"}},
+		{"oder und Bedingung", NewQuery().EqualTo("bezahlt", true).Or(
+			NewQuery().EqualTo("vorName", "Ben"),
+			NewQuery().EqualTo("vorName", "Cleo"),
+		), []string{"Cleo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			if q != nil {
+				q.Order("vorName")
+			} else {
+				q = NewQuery().Order("vorName")
+			}
+			out, err := m.Query(context.Background(), "Kind", q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := vornamen(out.Results); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryQueryUnbekannterOperator(t *testing.T) {
+	m := NewMemoryClient()
+	kinderAnlegen(t, m)
+
+	q := NewQuery()
+	q.Where()["jahrgang"] = map[string]any{"$near": 1}
+	if _, err := m.Query(context.Background(), "Kind", q); err == nil {
+		t.Fatal("unbekannter Operator muss ein Fehler sein")
+	}
+}
+
+func TestMemoryQueryOptionen(t *testing.T) {
+	m := NewMemoryClient()
+	kinderAnlegen(t, m)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		query     *Query
+		want      []string
+		wantCount int
+	}{
+		{"absteigend", NewQuery().Order("-jahrgang"), []string{"Cleo", "Ben", "Anna"}, 0},
+		{"zwei Felder", NewQuery().Order("nachName", "-vorName"), []string{"Ben", "Anna", "Cleo"}, 0},
+		{"bool aufsteigend", NewQuery().Order("bezahlt", "vorName"), []string{"Ben", "Anna", "Cleo"}, 0},
+		{"bool absteigend", NewQuery().Order("-bezahlt", "vorName"), []string{"Anna", "Cleo", "Ben"}, 0},
+		{"limit und skip", NewQuery().Order("vorName").Skip(1).Limit(1), []string{"Ben"}, 0},
+		{"skip hinter dem Ende", NewQuery().Order("vorName").Skip(5), nil, 0},
+		{"count", NewQuery().Order("vorName").EqualTo("nachName", "Muster").Limit(0).Count(), nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := m.Query(ctx, "Kind", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := vornamen(out.Results); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if out.Count != tt.wantCount {
+				t.Errorf("count = %d, want %d", out.Count, tt.wantCount)
+			}
+		})
+	}
+
+	// keys beschränkt die Felder, objectId bleibt
+	out, err := m.Query(ctx, "Kind", NewQuery().Keys("vorName").Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := out.Results[0]; o.ObjectID() == "" || o.String("vorName") == "" || o["jahrgang"] != nil {
+		t.Errorf("keys: %v", o)
+	}
+
+	// include löst den Pointer auf
+	out, err = m.Query(ctx, "Kind", NewQuery().EqualTo("vorName", "Anna").Include("riegeID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	riege, _ := out.Results[0]["riegeID"].(map[string]any)
+	if riege["__type"] != "Object" || riege["riegenNummer"] != float64(1) {
+		t.Errorf("include: %v", riege)
+	}
+}
+
+func TestCompareBool(t *testing.T) {
+	tests := []struct {
+		a, b bool
+		want int
+	}{
+		{false, false, 0},
+		{true, true, 0},
+		{false, true, -1},
+		{true, false, 1},
+	}
+	for _, tt := range tests {
+		got, ok := compare(tt.a, tt.b)
+		if !ok || got != tt.want {
+			t.Errorf("compare(%v, %v) = %d, %v; want %d", tt.a, tt.b, got, ok, tt.want)
+		}
+	}
+}
+
+func TestMemoryIncrement(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+
+	obj, err := m.Create(ctx, "Kind", map[string]any{"version": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := obj.ObjectID()
+
+	tests := []struct {
+		name   string
+		amount int
+		want   float64
+	}{
+		{"plus eins", 1, 2},
+		{"plus drei", 3, 5},
+		{"minus zwei", -2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := m.Update(ctx, "Kind", id, map[string]any{
+				"version": map[string]any{"__op": "Increment", "amount": tt.amount},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Parse liefert den neuen Wert in der Antwort
+			if out["version"] != tt.want {
+				t.Errorf("Antwort version = %v, want %v", out["version"], tt.want)
+			}
+			gespeichert, _ := m.Get(ctx, "Kind", id)
+			if gespeichert.Int("version") != int(tt.want) {
+				t.Errorf("gespeichert version = %d, want %v", gespeichert.Int("version"), tt.want)
+			}
+		})
+	}
+
+	// Increment auf ein fehlendes Feld beginnt bei 0
+	if _, err := m.Update(ctx, "Kind", id, map[string]any{
+		"zaehler": map[string]any{"__op": "Increment", "amount": 2},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := m.Get(ctx, "Kind", id); o.Int("zaehler") != 2 {
+		t.Errorf("zaehler = %d, want 2", o.Int("zaehler"))
+	}
+}
+
+func TestMemoryBedingtesUpdate(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+
+	obj, err := m.Create(ctx, "Kind", map[string]any{"vorName": "Anna", "version": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := obj.ObjectID()
+
+	tests := []struct {
+		name     string
+		objectID string
+		where    map[string]any
+		wantErr  bool
+	}{
+		{"Version passt", id, map[string]any{"version": 3}, false},
+		{"Version veraltet", id, map[string]any{"version": 3}, true},
+		{"Version aktuell", id, map[string]any{"version": 4}, false},
+		{"Operator passt nicht", id, map[string]any{"version": map[string]any{"$lt": 2}}, true},
+		{"Objekt fehlt", "gibtsnicht", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vorher, _ := m.Get(ctx, "Kind", id)
+			out, err := m.Update(ctx, "Kind", tt.objectID, map[string]any{
+				"vorName": "Berta",
+				"version": map[string]any{"__op": "Increment", "amount": 1},
+			}, tt.where)
+
+			if tt.wantErr {
+				if !IsNotFound(err) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+				// fehlgeschlagene Bedingung ändert nichts
+				nachher, _ := m.Get(ctx, "Kind", id)
+				if nachher.Int("version") != vorher.Int("version") || nachher.String("vorName") != vorher.String("vorName") {
+					t.Errorf("Objekt verändert: %v → %v", vorher, nachher)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out["updatedAt"] == nil {
+				t.Error("updatedAt fehlt")
+			}
+			if out["version"] != float64(vorher.Int("version")+1) {
+				t.Errorf("version = %v", out["version"])
+			}
+		})
+	}
+}
+
+func TestMemoryBatch(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+
+	vorhanden, err := m.Create(ctx, "Kind", map[string]any{"vorName": "Anna", "version": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	geloescht, err := m.Create(ctx, "Kind", map[string]any{"vorName": "Ben"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []BatchOp{
+		{Method: http.MethodPost, ClassName: "Kind", Body: map[string]any{"vorName": "Cleo"}},
+		{Method: http.MethodPut, ClassName: "Kind", ObjectID: vorhanden.ObjectID(),
+			Body: map[string]any{"version": map[string]any{"__op": "Increment", "amount": 1}}},
+		{Method: http.MethodDelete, ClassName: "Kind", ObjectID: geloescht.ObjectID()},
+		{Method: http.MethodDelete, ClassName: "Kind", ObjectID: "gibtsnicht"},
+		{Method: http.MethodPut, ClassName: "Kind", ObjectID: "gibtsnicht", Body: map[string]any{}},
+		{Method: http.MethodGet, ClassName: "Kind"},
+	}
+	results, err := m.Batch(ctx, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(ops) {
+		t.Fatalf("%d Ergebnisse für %d Operationen", len(results), len(ops))
+	}
+
+	tests := []struct {
+		name     string
+		wantCode int // 0 = Erfolg
+	}{
+		{"anlegen", 0},
+		{"ändern", 0},
+		{"löschen", 0},
+		{"löschen fehlt", ErrNotFound.Code},
+		{"ändern fehlt", ErrNotFound.Code},
+		{"unbekannte Methode", 107},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := results[i]
+			if tt.wantCode == 0 {
+				if r.Error != nil || r.Success == nil {
+					t.Fatalf("Ergebnis %d: %+v", i, r)
+				}
+				return
+			}
+			if r.Error == nil || r.Error.Code != tt.wantCode {
+				t.Fatalf("Ergebnis %d: %+v, want Code %d", i, r, tt.wantCode)
+			}
+		})
+	}
+
+	// ---- Wirkung: keine Transaktion, erfolgreiche Operationen bleiben ----
+	neu := results[0].Success.ObjectID()
+	if o, err := m.Get(ctx, "Kind", neu); err != nil || o.String("vorName") != "Cleo" {
+		t.Errorf("angelegtes Objekt: %v, %v", o, err)
+	}
+	if o, _ := m.Get(ctx, "Kind", vorhanden.ObjectID()); o.Int("version") != 2 {
+		t.Errorf("version = %d, want 2", o.Int("version"))
+	}
+	if _, err := m.Get(ctx, "Kind", geloescht.ObjectID()); !IsNotFound(err) {
+		t.Errorf("gelöschtes Objekt noch vorhanden: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package parse
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sporttag/strukturen"
+)
+
+// Query baut typisierte Parse-Abfragen.
+//
+// Werte werden ausschließlich über encoding/json serialisiert, dadurch
+// entsteht immer gültiges, korrekt escaptes where-JSON. Ein Name wie
+// `O"Brien` oder `{"$regex":".*"}` bleibt ein gewöhnlicher String und
+// kann keine Parse-Operatoren einschleusen.
+//
+//	q := parse.NewQuery().
+//		EqualTo("nachName", k.NachName).
+//		GreaterThan("jahrgang", 2012).
+//		Order("nachName", "-jahrgang").
+//		Limit(50)
+type Query struct {
+	where   map[string]any
+	order   []string
+	limit   int
+	skip    int
+	keys    []string
+	include []string
+	count   bool
+}
+
+// NewQuery erzeugt eine leere Abfrage (alle Objekte, Parse-Standardlimit)
+func NewQuery() *Query {
+	return &Query{where: map[string]any{}, limit: -1}
+}
+
+// ======  where-Bedingungen  ======
+
+// Operator an ein Feld anhängen, bestehende Operatoren bleiben erhalten
+func (q *Query) addOp(key, op string, value any) *Query {
+	cond, ok := q.where[key].(map[string]any)
+	if !ok {
+		cond = map[string]any{}
+		q.where[key] = cond
+	}
+	cond[op] = value
+	return q
+}
+
+// EqualTo: Feld ist gleich value (bei Array-Feldern: enthält value).
+// value muss ein einfacher Wert sein (string, Zahl, bool, Pointer, Datum).
+func (q *Query) EqualTo(key string, value any) *Query {
+	q.where[key] = value
+	return q
+}
+
+// NotEqualTo: Feld ist ungleich value
+func (q *Query) NotEqualTo(key string, value any) *Query {
+	return q.addOp(key, "$ne", value)
+}
+
+// PointerTo: Feld zeigt auf className/objectID
+func (q *Query) PointerTo(key, className, objectID string) *Query {
+	return q.EqualTo(key, strukturen.NewParsePointer(className, objectID))
+}
+
+// In: Feld ist einer der Werte
+func (q *Query) In(key string, values ...any) *Query {
+	return q.addOp(key, "$in", nonNil(values))
+}
+
+// NotIn: Feld ist keiner der Werte
+func (q *Query) NotIn(key string, values ...any) *Query {
+	return q.addOp(key, "$nin", nonNil(values))
+}
+
+// PointerIn: Feld zeigt auf eines der Objekte von className
+func (q *Query) PointerIn(key, className string, objectIDs ...string) *Query {
+	values := make([]any, 0, len(objectIDs))
+	for _, id := range objectIDs {
+		values = append(values, strukturen.NewParsePointer(className, id))
+	}
+	return q.addOp(key, "$in", values)
+}
+
+// GreaterThan: Feld > value
+func (q *Query) GreaterThan(key string, value any) *Query {
+	return q.addOp(key, "$gt", value)
+}
+
+// GreaterThanOrEqualTo: Feld >= value
+func (q *Query) GreaterThanOrEqualTo(key string, value any) *Query {
+	return q.addOp(key, "$gte", value)
+}
+
+// LessThan: Feld < value
+func (q *Query) LessThan(key string, value any) *Query {
+	return q.addOp(key, "$lt", value)
+}
+
+// LessThanOrEqualTo: Feld <= value
+func (q *Query) LessThanOrEqualTo(key string, value any) *Query {
+	return q.addOp(key, "$lte", value)
+}
+
+// Exists: Feld ist gesetzt (true) bzw. nicht gesetzt (false)
+func (q *Query) Exists(key string, exists bool) *Query {
+	return q.addOp(key, "$exists", exists)
+}
+
+// Matches: Feld passt auf den regulären Ausdruck pattern.
+// options wie bei Parse, z. B. "i" für Groß-/Kleinschreibung ignorieren.
+// pattern wird unverändert übernommen – für Benutzereingaben StartsWith
+// oder Contains verwenden.
+func (q *Query) Matches(key, pattern, options string) *Query {
+	q.addOp(key, "$regex", pattern)
+	if options != "" {
+		q.addOp(key, "$options", options)
+	}
+	return q
+}
+
+// StartsWith: Feld beginnt mit prefix (Sonderzeichen werden maskiert)
+func (q *Query) StartsWith(key, prefix string) *Query {
+	return q.Matches(key, "^"+regexp.QuoteMeta(prefix), "")
+}
+
+// Contains: Feld enthält substr (Sonderzeichen werden maskiert)
+func (q *Query) Contains(key, substr string) *Query {
+	return q.Matches(key, regexp.QuoteMeta(substr), "")
+}
+
+// Or verknüpft die where-Bedingungen mehrerer Abfragen mit $or
+func (q *Query) Or(queries ...*Query) *Query {
+	teile := make([]any, 0, len(queries))
+	for _, sub := range queries {
+		teile = append(teile, sub.where)
+	}
+	q.where["$or"] = teile
+	return q
+}
+
+// ======  Optionen  ======
+
+// Order sortiert nach den Feldern, "-feld" für absteigend
+func (q *Query) Order(fields ...string) *Query {
+	q.order = append(q.order, fields...)
+	return q
+}
+
+// Limit begrenzt die Anzahl der Ergebnisse (Parse-Maximum 1000, 0 bei reinem Count)
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Skip überspringt die ersten n Ergebnisse
+func (q *Query) Skip(n int) *Query {
+	q.skip = n
+	return q
+}
+
+// Keys beschränkt die zurückgelieferten Felder
+func (q *Query) Keys(keys ...string) *Query {
+	q.keys = append(q.keys, keys...)
+	return q
+}
+
+// Include löst Pointer-Felder zu vollständigen Objekten auf
+func (q *Query) Include(keys ...string) *Query {
+	q.include = append(q.include, keys...)
+	return q
+}
+
+// Count fordert zusätzlich die Gesamtzahl der Treffer an
+func (q *Query) Count() *Query {
+	q.count = true
+	return q
+}
+
+// ======  Ausgabe  ======
+
+// Where liefert die where-Bedingung (z. B. für bedingte Updates)
+func (q *Query) Where() map[string]any {
+	return q.where
+}
+
+// WhereJSON liefert die where-Bedingung als JSON
+func (q *Query) WhereJSON() (string, error) {
+	b, err := json.Marshal(q.where)
+	return string(b), err
+}
+
+// Values liefert die URL-Parameter für GET /classes/<Klasse>
+func (q *Query) Values() (url.Values, error) {
+	v := url.Values{}
+	if q == nil {
+		return v, nil
+	}
+	if len(q.where) > 0 {
+		w, err := q.WhereJSON()
+		if err != nil {
+			return nil, err
+		}
+		v.Set("where", w)
+	}
+	if len(q.order) > 0 {
+		v.Set("order", strings.Join(q.order, ","))
+	}
+	if q.limit >= 0 {
+		v.Set("limit", strconv.Itoa(q.limit))
+	}
+	if q.skip > 0 {
+		v.Set("skip", strconv.Itoa(q.skip))
+	}
+	if len(q.keys) > 0 {
+		v.Set("keys", strings.Join(q.keys, ","))
+	}
+	if len(q.include) > 0 {
+		v.Set("include", strings.Join(q.include, ","))
+	}
+	if q.count {
+		v.Set("count", "1")
+	}
+	return v, nil
+}
+
+// nil-Slices würden als null serialisiert, Parse erwartet ein Array
+func nonNil(values []any) []any {
+	if values == nil {
+		return []any{}
+	}
+	return values
+}
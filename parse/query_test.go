@@ -0,0 +1,186 @@
+package parse
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"sporttag/strukturen"
+)
+
+// Namen aus Benutzereingaben müssen als gewöhnliche Strings im where landen
+func TestQueryEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"Anführungszeichen", `O"Brien`, `{"nachName":"O\"Brien"}`},
+		{"Backslash", `Back\slash`, `{"nachName":"Back\\slash"}`},
+		{"Anführungszeichen und Backslash", `\"`, `{"nachName":"\\\""}`},
+		{"Operator als Text", `{"$regex":".*"}`, `{"nachName":"{\"$regex\":\".*\"}"}`},
+		{"ne als Text", `{"$ne":null}`, `{"nachName":"{\"$ne\":null}"}`},
+		{"Steuerzeichen", "a\nb", `{"nachName":"a\nb"}`},
+		{"HTML wird maskiert", `<script>`, `{"nachName":"\u003cscript\u003e"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery().EqualTo("nachName", tt.value)
+			got, err := q.WhereJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("where = %s, want %s", got, tt.want)
+			}
+
+			// Rückweg: Parse sieht genau einen String, keinen Operator
+			var where map[string]any
+			if err := json.Unmarshal([]byte(got), &where); err != nil {
+				t.Fatal(err)
+			}
+			if s, ok := where["nachName"].(string); !ok || s != tt.value {
+				t.Errorf("nachName = %#v, want String %q", where["nachName"], tt.value)
+			}
+
+			// auch nach URL-Kodierung unverändert
+			params, err := q.Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := url.ParseQuery(params.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Get("where") != tt.want {
+				t.Errorf("URL-where = %s, want %s", decoded.Get("where"), tt.want)
+			}
+		})
+	}
+}
+
+// Sonderzeichen in StartsWith/Contains werden im Regex maskiert
+func TestQueryRegexMaskierung(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"StartsWith", NewQuery().StartsWith("vorName", `A.*`), `{"vorName":{"$regex":"^A\\.\\*"}}`},
+		{"Contains", NewQuery().Contains("vorName", `(x)`), `{"vorName":{"$regex":"\\(x\\)"}}`},
+		{"Matches mit Optionen", NewQuery().Matches("vorName", "^an", "i"), `{"vorName":{"$options":"i","$regex":"^an"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.WhereJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("where = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Das where-JSON der Operatoren entspricht der Parse-REST-API
+func TestQueryWhereJSON(t *testing.T) {
+	datum := strukturen.NewParseDate(time.Date(2026, 9, 26, 16, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"leer", NewQuery(), `{}`},
+		{"gleich Zahl", NewQuery().EqualTo("jahrgang", 2014), `{"jahrgang":2014}`},
+		{"gleich bool", NewQuery().EqualTo("bezahlt", true), `{"bezahlt":true}`},
+		{"ungleich", NewQuery().NotEqualTo("abgemeldet", true), `{"abgemeldet":{"$ne":true}}`},
+		{"in", NewQuery().In("jahrgang", 2013, 2014), `{"jahrgang":{"$in":[2013,2014]}}`},
+		{"in leer", NewQuery().In("jahrgang"), `{"jahrgang":{"$in":[]}}`},
+		{"nicht in", NewQuery().NotIn("geschlecht", "m"), `{"geschlecht":{"$nin":["m"]}}`},
+		{"größer und kleiner", NewQuery().GreaterThan("jahrgang", 2012).LessThan("jahrgang", 2016),
+			`{"jahrgang":{"$gt":2012,"$lt":2016}}`},
+		{"größer gleich und kleiner gleich", NewQuery().GreaterThanOrEqualTo("punkte", 10).LessThanOrEqualTo("punkte", 20),
+			`{"punkte":{"$gte":10,"$lte":20}}`},
+		{"Datum", NewQuery().LessThan("zeitpunkt", datum),
+			`{"zeitpunkt":{"$lt":{"__type":"Date","iso":"2026-09-26T16:00:00.000Z"}}}`},
+		{"existiert", NewQuery().Exists("riegeID", false), `{"riegeID":{"$exists":false}}`},
+		{"Pointer", NewQuery().PointerTo("kindID", "Kind", "abc123"),
+			`{"kindID":{"__type":"Pointer","className":"Kind","objectId":"abc123"}}`},
+		{"Pointer in", NewQuery().PointerIn("riegenID", "Riege", "r1", "r2"),
+			`{"riegenID":{"$in":[{"__type":"Pointer","className":"Riege","objectId":"r1"},{"__type":"Pointer","className":"Riege","objectId":"r2"}]}}`},
+		{"oder", NewQuery().Or(
+			NewQuery().EqualTo("akteur", "Admin"),
+			NewQuery().EqualTo("akteurId", "Admin"),
+		), `{"$or":[{"akteur":"Admin"},{"akteurId":"Admin"}]}`},
+		{"mehrere Felder", NewQuery().EqualTo("vorName", "Anna").EqualTo("jahrgang", 2014),
+			`{"jahrgang":2014,"vorName":"Anna"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.WhereJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("where = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Die übrigen URL-Parameter für GET /classes/<Klasse>
+func TestQueryValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  url.Values
+	}{
+		{"nil", nil, url.Values{}},
+		{"leer", NewQuery(), url.Values{}},
+		{"order", NewQuery().Order("nachName", "-jahrgang"), url.Values{"order": {"nachName,-jahrgang"}}},
+		{"order angehängt", NewQuery().Order("position").Order("-createdAt"), url.Values{"order": {"position,-createdAt"}}},
+		{"limit und skip", NewQuery().Limit(50).Skip(100), url.Values{"limit": {"50"}, "skip": {"100"}}},
+		{"limit 0", NewQuery().Limit(0), url.Values{"limit": {"0"}}},
+		{"skip 0 entfällt", NewQuery().Skip(0), url.Values{}},
+		{"keys", NewQuery().Keys("vorName", "nachName"), url.Values{"keys": {"vorName,nachName"}}},
+		{"include", NewQuery().Include("kindID", "kindID.erziehungsberechtigterID"),
+			url.Values{"include": {"kindID,kindID.erziehungsberechtigterID"}}},
+		{"count", NewQuery().Limit(0).Count(), url.Values{"limit": {"0"}, "count": {"1"}}},
+		{"alles", NewQuery().EqualTo("jahrgang", 2014).Order("-punkte").Limit(10).Skip(20).Keys("punkte").Include("kindID").Count(),
+			url.Values{
+				"where":   {`{"jahrgang":2014}`},
+				"order":   {"-punkte"},
+				"limit":   {"10"},
+				"skip":    {"20"},
+				"keys":    {"punkte"},
+				"include": {"kindID"},
+				"count":   {"1"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Encode() != tt.want.Encode() {
+				t.Errorf("Values = %s\nwant      %s", got.Encode(), tt.want.Encode())
+			}
+		})
+	}
+}
+
+// Where liefert die Bedingung für bedingte Updates (updateWithVersion)
+func TestQueryWhere(t *testing.T) {
+	where := NewQuery().EqualTo("version", 3).Where()
+	b, err := json.Marshal(where)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"version":3}` {
+		t.Errorf("where = %s", b)
+	}
+}
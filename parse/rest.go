@@ -0,0 +1,263 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"sporttag/strukturen"
+)
+
+// Parse erlaubt höchstens 50 Operationen pro Batch-Request
+const maxBatchSize = 50
+
+// RESTClient spricht die REST-API des Parse-Servers an.
+//
+// Mit MasterKey laufen alle Zugriffe als Master-Key-Anfragen: der Server
+// ist dann der einzige, der die per CLP gesperrten Klassen (Kontaktdaten,
+// Kassenbuch, Protokolle, siehe schema.go) lesen und schreiben kann. Der
+// JavaScript-Key des Frontends reicht dafür nicht mehr aus.
+type RESTClient struct {
+	ServerURL string
+	AppID     string
+	JSKey     string
+	MasterKey string
+	HTTP      *http.Client
+}
+
+// NewRESTClient erzeugt einen Client für serverURL (z. B. https://parseapi.back4app.com)
+func NewRESTClient(serverURL, appID, jsKey, masterKey string) *RESTClient {
+	return &RESTClient{
+		ServerURL: strings.TrimRight(serverURL, "/"),
+		AppID:     appID,
+		JSKey:     jsKey,
+		MasterKey: masterKey,
+		HTTP:      http.DefaultClient,
+	}
+}
+
+// ======  Hilfsfunktionen  ======
+
+// Führt einen Request aus und dekodiert die Antwort nach out
+func (c *RESTClient) do(ctx context.Context, method, path string, body any, out any) error {
+	return c.doSession(ctx, method, path, body, out, "")
+}
+
+// Setzt die Schlüssel-Header; mit Session-Token ohne Master-Key, damit
+// Parse die Anfrage wirklich als dieser Benutzer ausführt
+func (c *RESTClient) setKeys(req *http.Request, sessionToken string) {
+	req.Header.Set("X-Parse-Application-Id", c.AppID)
+	req.Header.Set("X-Parse-Javascript-Key", c.JSKey)
+	if sessionToken != "" {
+		req.Header.Set("X-Parse-Session-Token", sessionToken)
+		return
+	}
+	if c.MasterKey != "" {
+		req.Header.Set("X-Parse-Master-Key", c.MasterKey)
+	}
+}
+
+// wie do, zusätzlich im Kontext einer Benutzer-Session
+func (c *RESTClient) doSession(ctx context.Context, method, path string, body any, out any, sessionToken string) error {
+	var rdr io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.ServerURL+path, rdr)
+	if err != nil {
+		return err
+	}
+	c.setKeys(req, sessionToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// ---- Parse-Fehler ----
+	if resp.StatusCode >= 400 {
+		var perr Error
+		if err := json.NewDecoder(resp.Body).Decode(&perr); err != nil || perr.Code == 0 {
+			return &Error{Code: resp.StatusCode, Message: resp.Status}
+		}
+		return &perr
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Pfad, unter dem der Parse-Server gemountet ist (für /batch nötig)
+func (c *RESTClient) mountPath() string {
+	u, err := url.Parse(c.ServerURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+func classPath(className, objectID string) string {
+	p := "/classes/" + url.PathEscape(className)
+	if objectID != "" {
+		p += "/" + url.PathEscape(objectID)
+	}
+	return p
+}
+
+// ======  Client-Implementierung  ======
+
+func (c *RESTClient) Query(ctx context.Context, className string, q *Query) (*QueryResult, error) {
+	params, err := q.Values()
+	if err != nil {
+		return nil, err
+	}
+	path := classPath(className, "")
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var out QueryResult
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *RESTClient) Get(ctx context.Context, className, objectID string, include ...string) (Object, error) {
+	path := classPath(className, objectID)
+	if len(include) > 0 {
+		path += "?include=" + url.QueryEscape(strings.Join(include, ","))
+	}
+	var out Object
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RESTClient) Create(ctx context.Context, className string, data any) (Object, error) {
+	var out Object
+	if err := c.do(ctx, http.MethodPost, classPath(className, ""), data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RESTClient) Update(ctx context.Context, className, objectID string, data any, where map[string]any) (Object, error) {
+	// ⚠️ objectId IM PFAD + where NUR für die Bedingung
+	path := classPath(className, objectID)
+	if len(where) > 0 {
+		whereJSON, err := json.Marshal(where)
+		if err != nil {
+			return nil, err
+		}
+		path += "?where=" + url.QueryEscape(string(whereJSON))
+	}
+
+	var out Object
+	if err := c.do(ctx, http.MethodPut, path, data, &out); err != nil {
+		return nil, err
+	}
+
+	// ✅ KEIN updatedAt = KEIN Update
+	if _, ok := out["updatedAt"]; !ok {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+func (c *RESTClient) Delete(ctx context.Context, className, objectID string) error {
+	return c.do(ctx, http.MethodDelete, classPath(className, objectID), nil, nil)
+}
+
+func (c *RESTClient) Batch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	type request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Body   any    `json:"body,omitempty"`
+	}
+
+	results := make([]BatchResult, 0, len(ops))
+	for start := 0; start < len(ops); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(ops))
+
+		requests := make([]request, 0, end-start)
+		for _, op := range ops[start:end] {
+			requests = append(requests, request{
+				Method: op.Method,
+				Path:   c.mountPath() + classPath(op.ClassName, op.ObjectID),
+				Body:   op.Body,
+			})
+		}
+
+		var out []BatchResult
+		err := c.do(ctx, http.MethodPost, "/batch", map[string]any{"requests": requests}, &out)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, out...)
+	}
+	return results, nil
+}
+
+func (c *RESTClient) CurrentUser(ctx context.Context, sessionToken string) (Object, error) {
+	if sessionToken == "" {
+		return nil, ErrInvalidSession
+	}
+	var out Object
+	if err := c.doSession(ctx, http.MethodGet, "/users/me", nil, &out, sessionToken); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RESTClient) UploadFile(ctx context.Context, name, contentType string, data io.Reader) (*strukturen.ParseFile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ServerURL+"/files/"+url.PathEscape(name), data)
+	if err != nil {
+		return nil, err
+	}
+	c.setKeys(req, "")
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var perr Error
+		if err := json.NewDecoder(resp.Body).Decode(&perr); err != nil || perr.Code == 0 {
+			return nil, &Error{Code: resp.StatusCode, Message: resp.Status}
+		}
+		return nil, &perr
+	}
+
+	// Antwort: {"name": "<prefix>_<name>", "url": "https://..."}
+	var out struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &strukturen.ParseFile{Name: out.Name, URL: out.URL}, nil
+}
+
+func (c *RESTClient) DeleteFile(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/files/"+url.PathEscape(name), nil, nil)
+}
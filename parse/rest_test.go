@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Nimmt die Anfragen auf und antwortet mit antwort(r)
+type aufzeichnung struct {
+	methode, pfad    string
+	masterKey, jsKey string
+	sessionToken     string
+	body             map[string]any
+}
+
+func testServer(t *testing.T, antwort func(w http.ResponseWriter, r *http.Request)) (*RESTClient, *[]aufzeichnung) {
+	t.Helper()
+	var anfragen []aufzeichnung
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := aufzeichnung{
+			methode:      r.Method,
+			pfad:         r.URL.Path,
+			masterKey:    r.Header.Get("X-Parse-Master-Key"),
+			jsKey:        r.Header.Get("X-Parse-Javascript-Key"),
+			sessionToken: r.Header.Get("X-Parse-Session-Token"),
+		}
+		json.NewDecoder(r.Body).Decode(&a.body)
+		anfragen = append(anfragen, a)
+		antwort(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewRESTClient(srv.URL, "app", "js", "master"), &anfragen
+}
+
+func TestRESTClientSchluessel(t *testing.T) {
+	c, anfragen := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/files/") {
+			json.NewEncoder(w).Encode(map[string]string{"name": "x_a.pdf", "url": "https://files/x_a.pdf"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"objectId": "u1", "results": []any{}})
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		aufruf      func() error
+		wantMaster  string
+		wantSession string
+	}{
+		{"Query", func() error { _, err := c.Query(ctx, "Erziehungsberechtigter", nil); return err }, "master", ""},
+		{"Create", func() error { _, err := c.Create(ctx, "auditLog", map[string]any{}); return err }, "master", ""},
+		// email fremder _User liefert Parse nur mit Master-Key (Mail-Empfänger)
+		{"Get _User", func() error { _, err := c.Get(ctx, "_User", "u1"); return err }, "master", ""},
+		{"Datei", func() error {
+			_, err := c.UploadFile(ctx, "a.pdf", "application/pdf", strings.NewReader("%PDF"))
+			return err
+		}, "master", ""},
+		{"Datei löschen", func() error { return c.DeleteFile(ctx, "x_a.pdf") }, "master", ""},
+		// /users/me muss als der Benutzer laufen, nicht als Master
+		{"CurrentUser", func() error { _, err := c.CurrentUser(ctx, "r:abc"); return err }, "", "r:abc"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.aufruf(); err != nil {
+				t.Fatal(err)
+			}
+			a := (*anfragen)[i]
+			if a.masterKey != tt.wantMaster || a.sessionToken != tt.wantSession || a.jsKey != "js" {
+				t.Errorf("Header: master=%q session=%q js=%q", a.masterKey, a.sessionToken, a.jsKey)
+			}
+		})
+	}
+}
+
+func TestSetzeBerechtigungen(t *testing.T) {
+	tests := []struct {
+		name        string
+		klasseFehlt bool
+		want        []string
+	}{
+		{"Klasse vorhanden", false, []string{"PUT /schemas/auditLog"}},
+		{"Klasse fehlt", true, []string{"PUT /schemas/auditLog", "POST /schemas/auditLog"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, anfragen := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.klasseFehlt && r.Method == http.MethodPut {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(Error{Code: codeKlasseFehlt, Message: "Class auditLog does not exist."})
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{})
+			})
+			if err := c.SetzeBerechtigungen(context.Background(), "auditLog", NurMasterKey()); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, a := range *anfragen {
+				got = append(got, a.methode+" "+a.pfad)
+				if a.masterKey != "master" {
+					t.Errorf("%s ohne Master-Key", a.pfad)
+				}
+				clp, _ := a.body["classLevelPermissions"].(map[string]any)
+				for _, op := range clpOperationen {
+					if erlaubt, ok := clp[op].(map[string]any); !ok || len(erlaubt) != 0 {
+						t.Errorf("%s: %s = %v, want {}", a.pfad, op, clp[op])
+					}
+				}
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Anfragen %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// ohne Master-Key gar nicht erst versuchen
+	c := NewRESTClient("http://127.0.0.1:0", "app", "js", "")
+	if err := c.SetzeBerechtigungen(context.Background(), "auditLog", NurMasterKey()); err == nil {
+		t.Error("ohne Master-Key muss ein Fehler kommen")
+	}
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// ======  Klassenberechtigungen (Class-Level Permissions)  ======
+//
+// Parse prüft CLPs für alle Anfragen mit Application-/JavaScript-Key.
+// Anfragen mit Master-Key sind davon ausgenommen. Eine Klasse, deren
+// Operationen alle leer sind, ist damit nur noch für den Server (Master-
+// Key) zugänglich; das Frontend kann sie weder lesen noch schreiben.
+
+// Berechtigungen entspricht "classLevelPermissions" der Schema-API.
+// Schlüssel sind die Operationen (find, get, create, ...), Werte die
+// erlaubten Rollen/Benutzer, z. B. {"*": true} für alle.
+type Berechtigungen map[string]any
+
+// Operationen, die Parse über CLPs regelt
+var clpOperationen = []string{"find", "count", "get", "create", "update", "delete", "addField"}
+
+// NurMasterKey sperrt alle Operationen für Clients ohne Master-Key
+func NurMasterKey() Berechtigungen {
+	b := Berechtigungen{"protectedFields": map[string]any{}}
+	for _, op := range clpOperationen {
+		b[op] = map[string]any{}
+	}
+	return b
+}
+
+// Parse-Fehlercode für eine unbekannte Klasse
+const codeKlasseFehlt = 103
+
+// SetzeBerechtigungen schreibt die CLPs einer Klasse über die Schema-API
+// (benötigt den Master-Key). Fehlt die Klasse, wird sie angelegt.
+func (c *RESTClient) SetzeBerechtigungen(ctx context.Context, className string, b Berechtigungen) error {
+	if c.MasterKey == "" {
+		return errors.New("parse: Schema-API benötigt den Master-Key")
+	}
+	path := "/schemas/" + url.PathEscape(className)
+	body := map[string]any{"className": className, "classLevelPermissions": b}
+
+	err := c.do(ctx, http.MethodPut, path, body, nil)
+	var perr *Error
+	if errors.As(err, &perr) && perr.Code == codeKlasseFehlt {
+		return c.do(ctx, http.MethodPost, path, body, nil)
+	}
+	return err
+}
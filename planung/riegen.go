@@ -0,0 +1,297 @@
+package planung
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Geschlechter in einer Riege
+const (
+	GeschlechtGemischt = "gemischt"
+	GeschlechtGetrennt = "getrennt"
+)
+
+// JahrgangBand fasst Jahrgänge zu einer Altersgruppe zusammen
+type JahrgangBand struct {
+	Von        int  `json:"von"`
+	Bis        int  `json:"bis"`
+	FuenfKampf bool `json:"fuenfKampf"` // Riegen dieses Bandes turnen Fünfkampf
+}
+
+func (b JahrgangBand) enthaelt(jahrgang int) bool {
+	return jahrgang >= b.Von && jahrgang <= b.Bis
+}
+
+// Regeln für die Riegenbildung (config.json "riegen_bildung" bzw. Request)
+type Regeln struct {
+	JahrgangBaender     []JahrgangBand `json:"jahrgangBaender,omitempty"` // leer → ein Band für alle
+	Geschlecht          string         `json:"geschlecht,omitempty"`      // gemischt (Standard) | getrennt
+	MinGroesse          int            `json:"minGroesse,omitempty"`
+	MaxGroesse          int            `json:"maxGroesse"`
+	GeschwisterZusammen bool           `json:"geschwisterZusammen,omitempty"`
+}
+
+// Prüft die Regeln auf Plausibilität
+func (r Regeln) Validate() error {
+	if r.MaxGroesse <= 0 {
+		return fmt.Errorf("maxGroesse muss größer als 0 sein")
+	}
+	if r.MinGroesse < 0 || r.MinGroesse > r.MaxGroesse {
+		return fmt.Errorf("minGroesse muss zwischen 0 und maxGroesse liegen")
+	}
+	switch r.Geschlecht {
+	case "", GeschlechtGemischt, GeschlechtGetrennt:
+	default:
+		return fmt.Errorf("geschlecht muss %q oder %q sein", GeschlechtGemischt, GeschlechtGetrennt)
+	}
+	for i, b := range r.JahrgangBaender {
+		if b.Von > b.Bis {
+			return fmt.Errorf("Jahrgangband %d: von > bis", i+1)
+		}
+		for _, a := range r.JahrgangBaender[:i] {
+			if b.Von <= a.Bis && a.Von <= b.Bis {
+				return fmt.Errorf("Jahrgangbänder %d-%d und %d-%d überschneiden sich", a.Von, a.Bis, b.Von, b.Bis)
+			}
+		}
+	}
+	return nil
+}
+
+// KindInfo – was die Riegenbildung von einem Kind wissen muss
+type KindInfo struct {
+	ObjectID                 string `json:"objectId"`
+	VorName                  string `json:"vorName"`
+	NachName                 string `json:"nachName"`
+	Jahrgang                 int    `json:"jahrgang"`
+	Geschlecht               string `json:"geschlecht"`
+	ElternID                 string `json:"elternID,omitempty"`
+	ErziehungsberechtigterID string `json:"erziehungsberechtigterID,omitempty"`
+}
+
+// Geschwister: gleicher Erziehungsberechtigter, sonst gleiches Elternkonto
+// (derselbe Schlüssel wie familieVon im handler). Ohne beides ist das Kind
+// eine eigene Einheit – ein gleicher Nachname macht noch keine Geschwister.
+func (k KindInfo) familie() string {
+	switch {
+	case k.ErziehungsberechtigterID != "":
+		return "eb:" + k.ErziehungsberechtigterID
+	case k.ElternID != "":
+		return "eltern:" + k.ElternID
+	}
+	return "kind:" + k.ObjectID
+}
+
+// RiegenVorschlag – eine zu bildende Riege; Kinder in Positionsreihenfolge
+type RiegenVorschlag struct {
+	Bezeichnung string     `json:"bezeichnung"`
+	FuenfKampf  bool       `json:"fuenfKampf"`
+	Kinder      []KindInfo `json:"kinder"`
+}
+
+// Einteilung – Ergebnis von BildeRiegen
+type Einteilung struct {
+	Riegen          []RiegenVorschlag `json:"riegen"`
+	NichtZugeordnet []KindInfo        `json:"nichtZugeordnet,omitempty"` // kein passendes Jahrgangband
+	Warnungen       []string          `json:"warnungen,omitempty"`
+}
+
+// Eine Gruppe = Jahrgangband (und ggf. Geschlecht), wird in Riegen aufgeteilt
+type gruppe struct {
+	band       JahrgangBand
+	geschlecht string
+	einheiten  map[string][]KindInfo // Familie (oder Einzelkind) → Kinder
+}
+
+// BildeRiegen teilt die Kinder nach den Regeln in Riegen ein.
+//
+// Ablauf: Kinder werden nach Jahrgangband (und bei "getrennt" nach
+// Geschlecht) gruppiert. Geschwister bilden eine Einheit, die im Band des
+// ältesten Geschwisters landet. Jede Gruppe wird auf so wenige Riegen wie
+// möglich verteilt (größte Einheiten zuerst in die kleinste Riege), ohne
+// maxGroesse zu überschreiten. Verstöße gegen minGroesse oder zu große
+// Geschwistergruppen werden als Warnung gemeldet.
+func BildeRiegen(kinder []KindInfo, regeln Regeln) (*Einteilung, error) {
+	if err := regeln.Validate(); err != nil {
+		return nil, err
+	}
+
+	baender := regeln.JahrgangBaender
+	if len(baender) == 0 {
+		baender = []JahrgangBand{{Von: 0, Bis: 9999}}
+	}
+	getrennt := regeln.Geschlecht == GeschlechtGetrennt
+
+	ergebnis := &Einteilung{}
+
+	// ---- Einheiten bilden (Geschwister bzw. Einzelkinder) ----
+	einheiten := map[string][]KindInfo{}
+	var schluessel []string
+	for _, k := range kinder {
+		key := "kind:" + k.ObjectID
+		if regeln.GeschwisterZusammen {
+			key = k.familie()
+		}
+		if getrennt {
+			key += "|" + k.Geschlecht
+		}
+		if _, ok := einheiten[key]; !ok {
+			schluessel = append(schluessel, key)
+		}
+		einheiten[key] = append(einheiten[key], k)
+	}
+	sort.Strings(schluessel)
+
+	// ---- Einheiten den Gruppen zuordnen ----
+	gruppen := map[string]*gruppe{}
+	var gruppenKeys []string
+	for _, key := range schluessel {
+		einheit := einheiten[key]
+		// ältestes Geschwister zuerst
+		sort.SliceStable(einheit, func(i, j int) bool { return einheit[i].Jahrgang < einheit[j].Jahrgang })
+
+		bandIdx := -1
+		for _, k := range einheit {
+			if bandIdx = bandVon(baender, k.Jahrgang); bandIdx >= 0 {
+				break
+			}
+		}
+		if bandIdx < 0 {
+			ergebnis.NichtZugeordnet = append(ergebnis.NichtZugeordnet, einheit...)
+			continue
+		}
+
+		geschlecht := ""
+		if getrennt {
+			geschlecht = einheit[0].Geschlecht
+		}
+		gKey := fmt.Sprintf("%04d|%s", bandIdx, geschlecht)
+		g, ok := gruppen[gKey]
+		if !ok {
+			g = &gruppe{band: baender[bandIdx], geschlecht: geschlecht, einheiten: map[string][]KindInfo{}}
+			gruppen[gKey] = g
+			gruppenKeys = append(gruppenKeys, gKey)
+		}
+		g.einheiten[key] = einheit
+	}
+
+	// Reihenfolge: Bänder wie konfiguriert, dann Geschlecht
+	sort.Strings(gruppenKeys)
+
+	// ---- Gruppen auf Riegen verteilen ----
+	for _, gKey := range gruppenKeys {
+		g := gruppen[gKey]
+		riegen, warnungen := verteile(g, regeln)
+		ergebnis.Riegen = append(ergebnis.Riegen, riegen...)
+		ergebnis.Warnungen = append(ergebnis.Warnungen, warnungen...)
+	}
+
+	return ergebnis, nil
+}
+
+func bandVon(baender []JahrgangBand, jahrgang int) int {
+	for i, b := range baender {
+		if b.enthaelt(jahrgang) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Verteilt die Einheiten einer Gruppe auf möglichst wenige Riegen
+func verteile(g *gruppe, regeln Regeln) ([]RiegenVorschlag, []string) {
+	var warnungen []string
+	bezeichnung := fmt.Sprintf("Jahrgang %d-%d", g.band.Von, g.band.Bis)
+	if g.band.Von == 0 && g.band.Bis == 9999 {
+		bezeichnung = "Alle Jahrgänge"
+	}
+	if g.geschlecht != "" {
+		bezeichnung += " " + g.geschlecht
+	}
+
+	// größte Einheiten zuerst, bei Gleichstand nach Name (stabil)
+	keys := make([]string, 0, len(g.einheiten))
+	anzahl := 0
+	for key, einheit := range g.einheiten {
+		keys = append(keys, key)
+		anzahl += len(einheit)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := g.einheiten[keys[i]], g.einheiten[keys[j]]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		if einheit := g.einheiten[key]; len(einheit) > regeln.MaxGroesse {
+			warnungen = append(warnungen, fmt.Sprintf(
+				"%s: Geschwistergruppe %s (%d Kinder) ist größer als maxGroesse",
+				bezeichnung, einheit[0].NachName, len(einheit)))
+		}
+	}
+
+	// so wenige Riegen wie möglich; passt es nicht, eine Riege mehr
+	var riegen [][]KindInfo
+	for n := (anzahl + regeln.MaxGroesse - 1) / regeln.MaxGroesse; ; n++ {
+		var ok bool
+		riegen, ok = packe(g.einheiten, keys, n, regeln.MaxGroesse)
+		if ok || n >= len(keys) {
+			break
+		}
+	}
+
+	vorschlaege := make([]RiegenVorschlag, 0, len(riegen))
+	for i, kinder := range riegen {
+		sort.SliceStable(kinder, func(a, b int) bool {
+			if kinder[a].NachName != kinder[b].NachName {
+				return kinder[a].NachName < kinder[b].NachName
+			}
+			return kinder[a].VorName < kinder[b].VorName
+		})
+
+		name := bezeichnung
+		if len(riegen) > 1 {
+			name += " (" + strconv.Itoa(i+1) + ")"
+		}
+		if len(kinder) < regeln.MinGroesse {
+			warnungen = append(warnungen, fmt.Sprintf(
+				"%s: nur %d Kinder (minGroesse %d)", name, len(kinder), regeln.MinGroesse))
+		}
+		vorschlaege = append(vorschlaege, RiegenVorschlag{
+			Bezeichnung: name,
+			FuenfKampf:  g.band.FuenfKampf,
+			Kinder:      kinder,
+		})
+	}
+	return vorschlaege, warnungen
+}
+
+// Legt jede Einheit in die aktuell kleinste Riege, in die sie noch passt.
+// ok = false, wenn eine Einheit nirgends Platz fand (sie liegt dann in der
+// kleinsten Riege).
+func packe(einheiten map[string][]KindInfo, keys []string, n, max int) ([][]KindInfo, bool) {
+	riegen := make([][]KindInfo, n)
+	ok := true
+	for _, key := range keys {
+		einheit := einheiten[key]
+		ziel, kleinste := -1, 0
+		for i := range riegen {
+			if len(riegen[i]) < len(riegen[kleinste]) {
+				kleinste = i
+			}
+			if len(riegen[i])+len(einheit) <= max && (ziel < 0 || len(riegen[i]) < len(riegen[ziel])) {
+				ziel = i
+			}
+		}
+		if ziel < 0 {
+			// nur ein Fehlschlag, wenn die Einheit grundsätzlich passen würde
+			if len(einheit) <= max {
+				ok = false
+			}
+			ziel = kleinste
+		}
+		riegen[ziel] = append(riegen[ziel], einheit...)
+	}
+	return riegen, ok
+}
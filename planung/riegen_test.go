@@ -0,0 +1,46 @@
+package planung
+
+import "testing"
+
+// Geschwister nach demselben Schlüssel wie die Gebühren, nie nach Nachname
+func TestFamilie(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b KindInfo
+		want bool
+	}{
+		{"gleicher Erziehungsberechtigter",
+			KindInfo{ObjectID: "a", NachName: "Muster", ErziehungsberechtigterID: "eb1", ElternID: "u1"},
+			KindInfo{ObjectID: "b", NachName: "Beispiel", ErziehungsberechtigterID: "eb1", ElternID: "u2"}, true},
+		{"Erziehungsberechtigter vor Elternkonto",
+			KindInfo{ObjectID: "a", ErziehungsberechtigterID: "eb1", ElternID: "u1"},
+			KindInfo{ObjectID: "b", ErziehungsberechtigterID: "eb2", ElternID: "u1"}, false},
+		{"gleiches Elternkonto",
+			KindInfo{ObjectID: "a", ElternID: "u1"},
+			KindInfo{ObjectID: "b", ElternID: "u1"}, true},
+		{"nur gleicher Nachname",
+			KindInfo{ObjectID: "a", NachName: "Muster"},
+			KindInfo{ObjectID: "b", NachName: "muster "}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.familie() == tt.b.familie(); got != tt.want {
+				t.Errorf("%q == %q: %v, want %v", tt.a.familie(), tt.b.familie(), got, tt.want)
+			}
+		})
+	}
+
+	// Kinder mit gleichem Nachnamen landen nicht als Einheit in einer Riege
+	kinder := []KindInfo{
+		{ObjectID: "a", NachName: "Muster", Jahrgang: 2014},
+		{ObjectID: "b", NachName: "Muster", Jahrgang: 2014},
+		{ObjectID: "c", NachName: "Muster", Jahrgang: 2014},
+	}
+	e, err := BildeRiegen(kinder, Regeln{MaxGroesse: 2, GeschwisterZusammen: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Warnungen) != 0 {
+		t.Errorf("Warnungen %v, want keine", e.Warnungen)
+	}
+}
@@ -0,0 +1,146 @@
+// Package planung enthält die Planungsalgorithmen für den Sporttag
+// (Stationsrotation, Riegenbildung). Die Funktionen arbeiten nur auf
+// einfachen Eingabedaten und kennen weder HTTP noch Parse.
+package planung
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// RiegeInfo – was der Planer von einer Riege wissen muss
+type RiegeInfo struct {
+	ObjectID     string
+	RiegenNummer int
+	FuenfKampf   bool
+}
+
+// StationInfo – was der Planer von einer Station wissen muss
+type StationInfo struct {
+	ObjectID       string
+	StationsNummer int
+	NurZehnKampf   bool
+}
+
+// Einsatz: Riege ist im Slot an der Station
+type Einsatz struct {
+	Slot            int       `json:"slot"`
+	Beginn          time.Time `json:"beginn"`
+	Ende            time.Time `json:"ende"`
+	RiegeObjectID   string    `json:"riegeObjectId"`
+	RiegenNummer    int       `json:"riegenNummer"`
+	StationObjectID string    `json:"stationObjectId"`
+	StationsNummer  int       `json:"stationsNummer"`
+}
+
+// Rotationsplan – Ergebnis von Rotation
+type Rotationsplan struct {
+	Start             time.Time `json:"start"`
+	MinutenProStation int       `json:"minutenProStation"`
+	AnzSlots          int       `json:"anzSlots"`
+	Ende              time.Time `json:"ende"`
+	Einsaetze         []Einsatz `json:"einsaetze"`
+}
+
+// Rotation erstellt einen kollisionsfreien Plan: in jedem Slot ist an jeder
+// Station höchstens eine Riege, und jede Riege besucht jede ihrer
+// Pflichtstationen genau einmal (Fünfkampf-Riegen ohne Zehnkampf-Stationen).
+//
+// Pro Slot wird eine maximale Zuordnung Riege → Station gesucht
+// (augmentierende Pfade). Riegen mit den meisten offenen Stationen kommen
+// zuerst zum Zug; jede Riege bevorzugt die Station (Index + Slot) mod n,
+// sodass sich ein gleichmäßiger Umlauf ergibt.
+func Rotation(riegen []RiegeInfo, stationen []StationInfo, start time.Time, minutenProStation int) (*Rotationsplan, error) {
+	if minutenProStation <= 0 {
+		return nil, errors.New("minutenProStation muss größer als 0 sein")
+	}
+	if len(stationen) == 0 {
+		return nil, errors.New("keine Stationen vorhanden")
+	}
+
+	riegen = append([]RiegeInfo(nil), riegen...)
+	sort.Slice(riegen, func(i, j int) bool { return riegen[i].RiegenNummer < riegen[j].RiegenNummer })
+	stationen = append([]StationInfo(nil), stationen...)
+	sort.Slice(stationen, func(i, j int) bool { return stationen[i].StationsNummer < stationen[j].StationsNummer })
+
+	// ---- offene Stationen je Riege ----
+	offen := make([]map[int]bool, len(riegen))
+	for i, rg := range riegen {
+		offen[i] = map[int]bool{}
+		for s, st := range stationen {
+			if rg.FuenfKampf && st.NurZehnKampf {
+				continue
+			}
+			offen[i][s] = true
+		}
+	}
+
+	dauer := time.Duration(minutenProStation) * time.Minute
+	plan := &Rotationsplan{Start: start, MinutenProStation: minutenProStation, Ende: start}
+
+	for slot := 0; ; slot++ {
+		// Reihenfolge: meiste offene Stationen zuerst, dann Riegennummer
+		reihenfolge := make([]int, 0, len(riegen))
+		for i := range riegen {
+			if len(offen[i]) > 0 {
+				reihenfolge = append(reihenfolge, i)
+			}
+		}
+		if len(reihenfolge) == 0 {
+			break
+		}
+		sort.SliceStable(reihenfolge, func(a, b int) bool {
+			return len(offen[reihenfolge[a]]) > len(offen[reihenfolge[b]])
+		})
+
+		// ---- maximale Zuordnung für diesen Slot ----
+		belegt := map[int]int{} // Station → Riege
+		for _, i := range reihenfolge {
+			besucht := map[int]bool{}
+			zuordnen(i, slot, len(stationen), offen, belegt, besucht)
+		}
+
+		beginn := start.Add(time.Duration(slot) * dauer)
+		stationsIndizes := make([]int, 0, len(belegt))
+		for s := range belegt {
+			stationsIndizes = append(stationsIndizes, s)
+		}
+		sort.Ints(stationsIndizes)
+		for _, s := range stationsIndizes {
+			i := belegt[s]
+			delete(offen[i], s)
+			plan.Einsaetze = append(plan.Einsaetze, Einsatz{
+				Slot:            slot + 1,
+				Beginn:          beginn,
+				Ende:            beginn.Add(dauer),
+				RiegeObjectID:   riegen[i].ObjectID,
+				RiegenNummer:    riegen[i].RiegenNummer,
+				StationObjectID: stationen[s].ObjectID,
+				StationsNummer:  stationen[s].StationsNummer,
+			})
+		}
+		plan.AnzSlots = slot + 1
+		plan.Ende = beginn.Add(dauer)
+	}
+
+	return plan, nil
+}
+
+// Sucht für Riege i eine freie Station oder verdrängt eine andere Riege,
+// die auf eine andere Station ausweichen kann (Kuhn-Algorithmus).
+func zuordnen(i, slot, n int, offen []map[int]bool, belegt map[int]int, besucht map[int]bool) bool {
+	for k := 0; k < n; k++ {
+		s := (i + slot + k) % n
+		if !offen[i][s] || besucht[s] {
+			continue
+		}
+		besucht[s] = true
+		andere, vergeben := belegt[s]
+		if !vergeben || zuordnen(andere, slot, n, offen, belegt, besucht) {
+			belegt[s] = i
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package planung
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRotation(t *testing.T) {
+	start := time.Date(2026, 9, 26, 9, 0, 0, 0, time.UTC)
+	riegen := func(anzahl int, fuenfKampf ...int) []RiegeInfo {
+		fk := map[int]bool{}
+		for _, n := range fuenfKampf {
+			fk[n] = true
+		}
+		out := make([]RiegeInfo, anzahl)
+		for i := range out {
+			out[i] = RiegeInfo{ObjectID: fmt.Sprintf("r%d", i+1), RiegenNummer: i + 1, FuenfKampf: fk[i+1]}
+		}
+		return out
+	}
+	stationen := func(anzahl int, nurZehnKampf ...int) []StationInfo {
+		zk := map[int]bool{}
+		for _, n := range nurZehnKampf {
+			zk[n] = true
+		}
+		out := make([]StationInfo, anzahl)
+		for i := range out {
+			out[i] = StationInfo{ObjectID: fmt.Sprintf("s%d", i+1), StationsNummer: i + 1, NurZehnKampf: zk[i+1]}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		riegen    []RiegeInfo
+		stationen []StationInfo
+		wantSlots int
+	}{
+		{"so viele Riegen wie Stationen", riegen(4), stationen(4), 4},
+		{"weniger Riegen als Stationen", riegen(2), stationen(5), 5},
+		{"mehr Riegen als Stationen", riegen(5), stationen(3), 5},
+		{"Fünfkampf ohne Zehnkampf-Stationen", riegen(4, 1, 3), stationen(6, 5, 6), 6},
+		{"nur Fünfkampf", riegen(3, 1, 2, 3), stationen(5, 4, 5), 3},
+		{"keine Riegen", nil, stationen(3), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Rotation(tt.riegen, tt.stationen, start, 15)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.AnzSlots != tt.wantSlots {
+				t.Errorf("%d Slots, want %d", plan.AnzSlots, tt.wantSlots)
+			}
+			if want := start.Add(time.Duration(tt.wantSlots) * 15 * time.Minute); !plan.Ende.Equal(want) {
+				t.Errorf("Ende %v, want %v", plan.Ende, want)
+			}
+
+			besuche := map[string]map[string]int{} // Riege → Station → Anzahl
+			type imSlot struct {
+				slot int
+				id   string
+			}
+			stationImSlot := map[imSlot]bool{}
+			riegeImSlot := map[imSlot]bool{}
+			for _, e := range plan.Einsaetze {
+				if stationImSlot[imSlot{e.Slot, e.StationObjectID}] {
+					t.Errorf("Slot %d: Station %s doppelt belegt", e.Slot, e.StationObjectID)
+				}
+				stationImSlot[imSlot{e.Slot, e.StationObjectID}] = true
+				if riegeImSlot[imSlot{e.Slot, e.RiegeObjectID}] {
+					t.Errorf("Slot %d: Riege %s an zwei Stationen", e.Slot, e.RiegeObjectID)
+				}
+				riegeImSlot[imSlot{e.Slot, e.RiegeObjectID}] = true
+				if want := start.Add(time.Duration(e.Slot-1) * 15 * time.Minute); !e.Beginn.Equal(want) || e.Ende.Sub(e.Beginn) != 15*time.Minute {
+					t.Errorf("Slot %d: %v–%v", e.Slot, e.Beginn, e.Ende)
+				}
+				if besuche[e.RiegeObjectID] == nil {
+					besuche[e.RiegeObjectID] = map[string]int{}
+				}
+				besuche[e.RiegeObjectID][e.StationObjectID]++
+			}
+
+			// jede Riege genau einmal an jeder Pflichtstation, nie an einer anderen
+			for _, rg := range tt.riegen {
+				for _, st := range tt.stationen {
+					want := 1
+					if rg.FuenfKampf && st.NurZehnKampf {
+						want = 0
+					}
+					if got := besuche[rg.ObjectID][st.ObjectID]; got != want {
+						t.Errorf("Riege %d an Station %d: %d-mal, want %d", rg.RiegenNummer, st.StationsNummer, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRotationUngueltig(t *testing.T) {
+	start := time.Date(2026, 9, 26, 9, 0, 0, 0, time.UTC)
+	if _, err := Rotation([]RiegeInfo{{ObjectID: "r1", RiegenNummer: 1}}, nil, start, 15); err == nil {
+		t.Error("ohne Stationen: kein Fehler")
+	}
+	if _, err := Rotation(nil, []StationInfo{{ObjectID: "s1", StationsNummer: 1}}, start, 0); err == nil {
+		t.Error("minutenProStation 0: kein Fehler")
+	}
+}
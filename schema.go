@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"sporttag/parse"
+)
+
+// ======  Klassenberechtigungen  ======
+//
+// Das Backend greift mit dem Master-Key auf Parse zu (parse_master_key).
+// Klassen mit Kontaktdaten, Geld oder Protokollen werden per CLP für
+// alle anderen Clients gesperrt – der JavaScript-Key des Frontends reicht
+// dann weder zum Lesen noch zum Schreiben. Die Rechteprüfung der Handler
+// (z. B. GET /erziehungsberechtigter nur für Admins und Riegenführer) ist
+// damit der einzige Weg an diese Daten.
+//
+// Für die Protokolle (auditLog, zahlung, superuserProtokoll) heißt das:
+// ohne Master-Key weder lesen noch ändern oder löschen. Der Master-Key
+// selbst umgeht die Klassenberechtigungen; dass Einträge nur angelegt
+// werden, sichert allein der Server-Code, nicht Parse.
+//
+// Einrichten bzw. nach Änderungen erneut ausführen:
+//
+//	go run . schema
+//
+// Der Aufruf ist idempotent; fehlende Klassen werden angelegt.
+
+// Klassen, die nur der Server (Master-Key) lesen und schreiben darf
+var geschuetzteKlassen = []string{
+	"Erziehungsberechtigter", // Telefon, E-Mail, Notfallkontakte
+	"mailAusgang",            // Empfängeradressen der Familien
+	"Benutzerrolle",          // Rollen der Parse-Benutzer (auth.go)
+	"auditLog",               // Änderungsprotokoll (handler/audit.go)
+	"zahlung",                // Kassenbuch
+	"superuserProtokoll",     // Änderungen nach der Anmeldefrist
+}
+
+func schemaEinrichten(config Config) {
+	if config.ParseServerURL == "" || config.ParseMasterKey == "" {
+		log.Fatal("schema: parse_server_url und parse_master_key erforderlich")
+	}
+	client := parse.NewRESTClient(config.ParseServerURL, config.ParseAppID, config.ParseJSKey, config.ParseMasterKey)
+
+	for _, klasse := range geschuetzteKlassen {
+		if err := client.SetzeBerechtigungen(context.Background(), klasse, parse.NurMasterKey()); err != nil {
+			log.Fatalf("schema: %s: %v", klasse, err)
+		}
+		log.Printf("schema: %s nur mit Master-Key", klasse)
+	}
+}
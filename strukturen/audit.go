@@ -0,0 +1,17 @@
+package strukturen
+
+// AuditEintrag entspricht der Klasse "auditLog": fortlaufendes Protokoll
+// von Änderungen mit altem und neuem Stand (nur der Server schreibt)
+type AuditEintrag struct {
+	Akteur    string     `json:"akteur"`        // Name bzw. Benutzer-ID
+	AkteurID  string     `json:"akteurId"`      // Benutzer-ID ("" beim Superuser)
+	Rolle     string     `json:"rolle"`         // Rolle des Akteurs
+	Aktion    string     `json:"aktion"`        // z. B. "zusammenfuehren"
+	Klasse    string     `json:"klasse"`        // betroffene Parse-Klasse
+	ObjektID  string     `json:"objektId"`      // objectId des geänderten Objekts
+	Alt       any        `json:"alt,omitempty"` // Stand vorher
+	Neu       any        `json:"neu,omitempty"` // Stand nachher
+	Version   int        `json:"version"`       // Version nach der Änderung, 0 = unversioniert
+	Zeitpunkt *ParseDate `json:"zeitpunkt"`     // Serverzeit
+	RequestID string     `json:"requestId"`     // X-Request-ID der Anfrage
+}
@@ -0,0 +1,11 @@
+package strukturen
+
+// Benutzerrolle entspricht der Klasse "Benutzerrolle": Rolle und
+// Zuständigkeit eines Parse-_User. Die Klasse ist nur mit dem Master-Key
+// erreichbar, Benutzer können ihre Rolle also nicht selbst ändern.
+type Benutzerrolle struct {
+	UserID     *ParsePointer `json:"userID"`               // → _User
+	Rolle      string        `json:"rolle"`                // eltern, riegenfuehrer, stationshelfer, admin
+	RiegeID    *ParsePointer `json:"riegeID,omitempty"`    // nur Riegenführer
+	StationsID *ParsePointer `json:"stationsID,omitempty"` // nur Stationshelfer
+}
@@ -0,0 +1,14 @@
+package strukturen
+
+// Erziehungsberechtigter entspricht der Klasse "Erziehungsberechtigter".
+// Kinder verweisen per Pointer "erziehungsberechtigterID" darauf.
+// Nur für admin und Riegenführer lesbar (Kontaktdaten).
+type Erziehungsberechtigter struct {
+	VorName        string `json:"vorName"`
+	NachName       string `json:"nachName"`
+	Telefon        string `json:"telefon"`
+	Email          string `json:"email,omitempty"`
+	NotfallName    string `json:"notfallName,omitempty"` // Notfallkontakt, falls nicht erreichbar
+	NotfallTelefon string `json:"notfallTelefon,omitempty"`
+	ElternID       string `json:"elternID,omitempty"` // _User, der angemeldet hat
+}
@@ -6,3 +6,12 @@ type Kind struct {
 	Jahrgang   int    `json:"jahrgang"`
 	Geschlecht string `json:"geschlecht"`
 }
+
+// Abmeldung – Felder eines abgemeldeten Kindes (DELETE /kind). Das Kind
+// bleibt gespeichert, steht aber in keiner Riege und keiner Rangliste.
+type Abmeldung struct {
+	Abgemeldet    bool       `json:"abgemeldet,omitempty"`
+	AbmeldeGrund  string     `json:"abmeldeGrund,omitempty"`
+	AbgemeldetAm  *ParseDate `json:"abgemeldetAm,omitempty"`
+	AbgemeldetVon string     `json:"abgemeldetVon,omitempty"`
+}
@@ -0,0 +1,17 @@
+package strukturen
+
+// MailAusgang entspricht der Klasse "mailAusgang": jede E-Mail wird
+// zuerst hier abgelegt und dann im Hintergrund verschickt, so dass
+// ein langsamer Mailserver keine Anfrage aufhält und nach einem
+// Neustart nichts verloren geht.
+type MailAusgang struct {
+	An               string     `json:"an"`
+	Betreff          string     `json:"betreff"`
+	Text             string     `json:"text"`
+	Vorlage          string     `json:"vorlage"`                    // bestaetigung | aenderung | zahlungserinnerung
+	Status           string     `json:"status"`                     // offen | gesendet | fehlgeschlagen
+	Versuche         int        `json:"versuche"`                   // bisherige Zustellversuche
+	NaechsterVersuch *ParseDate `json:"naechsterVersuch,omitempty"` // frühestens dann (nur offen)
+	GesendetAm       *ParseDate `json:"gesendetAm,omitempty"`
+	Fehler           string     `json:"fehler,omitempty"` // letzter Fehler des Mailservers
+}
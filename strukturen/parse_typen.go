@@ -0,0 +1,157 @@
+package strukturen
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ParsePointer bildet einen Parse-Pointer ab:
+//
+//	{"__type": "Pointer", "className": "Kind", "objectId": "abc123"}
+//
+// Liefert Parse die Relation per include aufgelöst zurück
+// ({"__type": "Object", ...}), wird das vollständige Objekt
+// zusätzlich in Object abgelegt und kann mit Decode gelesen werden.
+type ParsePointer struct {
+	ClassName string
+	ObjectID  string
+	// Object enthält das eingebettete Objekt, falls per include expandiert
+	Object json.RawMessage
+}
+
+// NewParsePointer erzeugt einen Pointer auf className/objectID
+func NewParsePointer(className, objectID string) *ParsePointer {
+	return &ParsePointer{ClassName: className, ObjectID: objectID}
+}
+
+// Expanded meldet, ob Parse das vollständige Objekt mitgeliefert hat
+func (p *ParsePointer) Expanded() bool {
+	return p != nil && len(p.Object) > 0
+}
+
+// Decode liest das eingebettete Objekt in v ein (z. B. *Kind)
+func (p *ParsePointer) Decode(v any) error {
+	if !p.Expanded() {
+		return errors.New("pointer nicht expandiert: " + p.ClassName)
+	}
+	return json.Unmarshal(p.Object, v)
+}
+
+// Beim Schreiben wird immer die reine Pointer-Form erzeugt,
+// Parse akzeptiert beim Speichern kein eingebettetes Objekt.
+func (p ParsePointer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string `json:"__type"`
+		ClassName string `json:"className"`
+		ObjectID  string `json:"objectId"`
+	}{"Pointer", p.ClassName, p.ObjectID})
+}
+
+func (p *ParsePointer) UnmarshalJSON(b []byte) error {
+	var kopf struct {
+		Type      string `json:"__type"`
+		ClassName string `json:"className"`
+		ObjectID  string `json:"objectId"`
+	}
+	if err := json.Unmarshal(b, &kopf); err != nil {
+		return err
+	}
+
+	switch kopf.Type {
+	case "Pointer":
+		*p = ParsePointer{ClassName: kopf.ClassName, ObjectID: kopf.ObjectID}
+	case "Object":
+		*p = ParsePointer{
+			ClassName: kopf.ClassName,
+			ObjectID:  kopf.ObjectID,
+			Object:    append(json.RawMessage(nil), b...),
+		}
+	default:
+		return errors.New("kein Parse-Pointer: __type=" + kopf.Type)
+	}
+	return nil
+}
+
+// ParseFile bildet eine Parse-Datei ab:
+//
+//	{"__type": "File", "name": "...", "url": "..."}
+type ParseFile struct {
+	Name string
+	URL  string
+}
+
+func (f ParseFile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"__type"`
+		Name string `json:"name"`
+		URL  string `json:"url,omitempty"`
+	}{"File", f.Name, f.URL})
+}
+
+func (f *ParseFile) UnmarshalJSON(b []byte) error {
+	var roh struct {
+		Type string `json:"__type"`
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := json.Unmarshal(b, &roh); err != nil {
+		return err
+	}
+	if roh.Type != "File" {
+		return errors.New("keine Parse-Datei: __type=" + roh.Type)
+	}
+	*f = ParseFile{Name: roh.Name, URL: roh.URL}
+	return nil
+}
+
+// ParseDate bildet ein Parse-Datum ab:
+//
+//	{"__type": "Date", "iso": "2026-09-26T16:00:00.000Z"}
+//
+// Beim Lesen wird auch ein einfacher RFC3339-String akzeptiert
+// (createdAt/updatedAt liefert Parse in dieser Form).
+type ParseDate struct {
+	time.Time
+}
+
+// NewParseDate erzeugt ein Parse-Datum (immer UTC)
+func NewParseDate(t time.Time) *ParseDate {
+	return &ParseDate{Time: t.UTC()}
+}
+
+func (d ParseDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"__type"`
+		ISO  string `json:"iso"`
+	}{"Date", d.UTC().Format("2006-01-02T15:04:05.000Z")})
+}
+
+func (d *ParseDate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		d.Time = t
+		return nil
+	}
+
+	var roh struct {
+		Type string `json:"__type"`
+		ISO  string `json:"iso"`
+	}
+	if err := json.Unmarshal(b, &roh); err != nil {
+		return err
+	}
+	if roh.Type != "Date" {
+		return errors.New("kein Parse-Datum: __type=" + roh.Type)
+	}
+	t, err := time.Parse(time.RFC3339Nano, roh.ISO)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
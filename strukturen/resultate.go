@@ -1,11 +1,14 @@
 package strukturen
 
-import "time"
-
 // Resultate entspricht der Klasse "resultate"
 type Resultate struct {
-	KindID     any       `json:"kindID,omitempty"`     // Pointer → Kind
-	StationsID any       `json:"stationsID,omitempty"` // Pointer → Station
-	Punkte     int       `json:"punkte,omitempty"`
-	ErreichtUm time.Time `json:"erreichtUm"`
+	KindID          *ParsePointer `json:"kindID,omitempty"`          // Pointer → Kind
+	StationsID      *ParsePointer `json:"stationsID,omitempty"`      // Pointer → Station
+	Punkte          int           `json:"punkte"`                    // 0 Punkte sind ein gültiges Resultat
+	Messwert        *float64      `json:"messwert,omitempty"`        // Rohwert (Sekunden, Meter, Anzahl)
+	TabellenVersion string        `json:"tabellenVersion,omitempty"` // Version der Punktetabellen
+	ErreichtUm      *ParseDate    `json:"erreichtUm,omitempty"`      // Serverzeit der Erfassung
+	ErfasstVon      string        `json:"erfasstVon,omitempty"`      // Name des Stationshelfers
+
+	UrspruenglichKindID *ParsePointer `json:"urspruenglichKindID,omitempty"` // Pointer → Kind (nach Zusammenführung)
 }
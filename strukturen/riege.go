@@ -4,5 +4,10 @@ package strukturen
 type Riege struct {
 	RiegenNummer     int  `json:"riegenNummer,omitempty"`
 	FuenfKampf       bool `json:"fuenfKampf"`
-	WettkampfBeendet bool `json:"wetttkampfBeendet"`
+	WettkampfBeendet bool `json:"wetttkampfBeendet"` // Spalte in Parse heißt so (drei t), siehe unten
 }
+
+// Die Parse-Spalte "wetttkampfBeendet" ist ein alter Tippfehler. Umbenennen
+// hieße alle Riegen migrieren und das Frontend gleichzeitig umstellen; sie
+// bleibt daher bestehen. Eigene Antworten des Backends (z. B. POST
+// /riegen-logging) schreiben das Feld richtig als "wettkampfBeendet".
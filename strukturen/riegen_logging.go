@@ -1,11 +1,10 @@
 package strukturen
 
-import "time"
-
-// RiegenLogging entspricht der Klasse "riegenLogging"
+// RiegenLogging entspricht der Klasse "riegenLogging" (ein Eintrag je Riege)
 type RiegenLogging struct {
-	RiegenID                 any       `json:"riegenID"`   // Pointer → Riege
-	StationsID               any       `json:"stationsID"` // Pointer → Station
-	AnzAbsolvierterStationen int       `json:"anzAbsolvierterStationen,omitempty"`
-	LetzteStationUm          time.Time `json:"letzteStationUm,omitempty"`
+	RiegenID                 *ParsePointer `json:"riegenID,omitempty"`   // Pointer → Riege
+	StationsID               *ParsePointer `json:"stationsID,omitempty"` // Pointer → zuletzt absolvierte Station
+	AnzAbsolvierterStationen int           `json:"anzAbsolvierterStationen,omitempty"`
+	LetzteStationUm          *ParseDate    `json:"letzteStationUm,omitempty"`
+	ErledigteStationen       []string      `json:"erledigteStationen,omitempty"` // objectIds der Stationen
 }
@@ -0,0 +1,11 @@
+package strukturen
+
+// Rotationsplan entspricht der Klasse "rotationsplan" (ein Eintrag je Einsatz)
+type Rotationsplan struct {
+	RiegenID   *ParsePointer `json:"riegenID,omitempty"`   // Pointer → Riege
+	StationsID *ParsePointer `json:"stationsID,omitempty"` // Pointer → Station
+	Slot       int           `json:"slot"`
+	Beginn     *ParseDate    `json:"beginn,omitempty"`
+	Ende       *ParseDate    `json:"ende,omitempty"`
+	PlanID     string        `json:"planID,omitempty"` // Kennung des Speichervorgangs
+}
@@ -2,8 +2,11 @@ package strukturen
 
 // Station entspricht der Klasse "Station" in Back4App
 type Station struct {
-	StationsName   string `json:"stationsName,omitempty"`
-	StationsNummer int    `json:"stationsNummer,omitempty"`
-	NurZehnKampf   bool   `json:"nurZehnKampf"`
-	Beschreibung   any    `json:"beschreibung"` // Parse File → generisch
+	StationsName   string     `json:"stationsName,omitempty"`
+	StationsNummer int        `json:"stationsNummer,omitempty"`
+	NurZehnKampf   bool       `json:"nurZehnKampf"`
+	Beschreibung   *ParseFile `json:"beschreibung,omitempty"` // Parse File
+	Disziplin      string     `json:"disziplin,omitempty"`    // zeit | weite | anzahl
+	Richtung       string     `json:"richtung,omitempty"`     // kleinerBesser | groesserBesser
+	Tabelle        string     `json:"tabelle,omitempty"`      // Name in punktetabellen.json
 }
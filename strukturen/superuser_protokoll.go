@@ -0,0 +1,11 @@
+package strukturen
+
+// SuperuserProtokoll entspricht der Klasse "superuserProtokoll":
+// jede Änderung nach der Anmeldefrist wird hier festgehalten
+type SuperuserProtokoll struct {
+	Akteur    string     `json:"akteur"`    // Name des Superusers
+	Aktion    string     `json:"aktion"`    // z. B. "registrieren", "aktualisieren"
+	Klasse    string     `json:"klasse"`    // betroffene Parse-Klasse
+	ObjektID  string     `json:"objektId"`  // objectId des geänderten Objekts
+	Zeitpunkt *ParseDate `json:"zeitpunkt"` // Serverzeit der Änderung
+}
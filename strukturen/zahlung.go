@@ -0,0 +1,20 @@
+package strukturen
+
+// Zahlung entspricht der Klasse "zahlung" (Kassenbuch je Kind).
+// Buchungen werden nicht geändert; Fehlbuchungen werden storniert,
+// zurückgezahltes Geld als Erstattung gebucht. Gelöscht wird nur eine
+// Buchung, deren Kind-Update scheitert (handler/zahlung_handler.go).
+// Beim Zusammenführen von Duplikaten wechselt kindID; das ursprüngliche
+// Kind bleibt in urspruenglichKindID erhalten.
+type Zahlung struct {
+	KindID    *ParsePointer `json:"kindID,omitempty"`    // Pointer → Kind
+	Art       string        `json:"art"`                 // zahlung | erstattung | storno | uebernahme
+	Betrag    int           `json:"betrag"`              // Cent, immer positiv
+	Methode   string        `json:"methode"`             // bar | ueberweisung (leer bei uebernahme)
+	Kassierer string        `json:"kassierer"`           // Name des Buchenden
+	Zeitpunkt *ParseDate    `json:"zeitpunkt,omitempty"` // Serverzeit der Buchung
+	StornoVon *ParsePointer `json:"stornoVon,omitempty"` // Pointer → zahlung (nur bei storno)
+	Bemerkung string        `json:"bemerkung,omitempty"`
+
+	UrspruenglichKindID *ParsePointer `json:"urspruenglichKindID,omitempty"` // Pointer → Kind (nach Zusammenführung)
+}
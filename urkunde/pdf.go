@@ -0,0 +1,208 @@
+package urkunde
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ======  Minimaler PDF-Writer  ======
+//
+// Erzeugt PDF 1.4 mit den Standardschriften Helvetica und Helvetica-Bold
+// (nicht eingebettet, WinAnsiEncoding). Das genügt für Urkunden mit
+// deutschem Text und kommt ohne externe Bibliotheken oder Dienste aus.
+
+type schrift string
+
+const (
+	schriftNormal schrift = "F1" // Helvetica
+	schriftFett   schrift = "F2" // Helvetica-Bold
+)
+
+// pdfDokument sammelt die Inhaltsströme der Seiten
+type pdfDokument struct {
+	breite, hoehe float64
+	seiten        []*bytes.Buffer
+}
+
+func neuesDokument(breite, hoehe float64) *pdfDokument {
+	return &pdfDokument{breite: breite, hoehe: hoehe}
+}
+
+func (d *pdfDokument) neueSeite() *bytes.Buffer {
+	s := &bytes.Buffer{}
+	d.seiten = append(d.seiten, s)
+	return s
+}
+
+// Text an (x, y); y von unten gemessen wie in PDF üblich
+func schreibeText(s *bytes.Buffer, f schrift, groesse, x, y float64, text string) {
+	fmt.Fprintf(s, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		f, zahl(groesse), zahl(x), zahl(y), pdfString(text))
+}
+
+// Rechteck (nur Rand)
+func schreibeRahmen(s *bytes.Buffer, x, y, breite, hoehe, staerke float64) {
+	fmt.Fprintf(s, "%s w %s %s %s %s re S\n",
+		zahl(staerke), zahl(x), zahl(y), zahl(breite), zahl(hoehe))
+}
+
+// Linie von (x1, y1) nach (x2, y2)
+func schreibeLinie(s *bytes.Buffer, x1, y1, x2, y2, staerke float64) {
+	fmt.Fprintf(s, "%s w %s %s m %s %s l S\n",
+		zahl(staerke), zahl(x1), zahl(y1), zahl(x2), zahl(y2))
+}
+
+// Bytes erzeugt die fertige PDF-Datei
+func (d *pdfDokument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	objekt := func(inhalt string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), inhalt)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: Katalog, 2: Seitenbaum, 3/4: Schriften, danach je Seite Page + Content
+	kids := make([]string, len(d.seiten))
+	for i := range d.seiten {
+		kids[i] = strconv.Itoa(5+2*i) + " 0 R"
+	}
+	objekt("<< /Type /Catalog /Pages 2 0 R >>")
+	objekt(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.seiten)))
+	objekt("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objekt("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, s := range d.seiten {
+		objekt(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			zahl(d.breite), zahl(d.hoehe), 6+2*i))
+		objekt(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", s.Len(), s.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+func zahl(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Wandelt UTF-8 in WinAnsi (cp1252) und escaped Klammern und Backslash.
+// Nicht darstellbare Zeichen werden zu "?".
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 32 || c > 126 {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// cp1252: 0xA0–0xFF entspricht Latin-1, 0x80–0x9F nur teilweise belegt
+var winAnsiSonder = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '–': 0x96, '—': 0x97,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	c, ok := winAnsiSonder[r]
+	return c, ok
+}
+
+// Textbreite in Punkt (für zentrierte und rechtsbündige Ausrichtung)
+func textBreite(f schrift, groesse float64, text string) float64 {
+	tabelle := helveticaBreiten
+	if f == schriftFett {
+		tabelle = helveticaFettBreiten
+	}
+	summe := 0
+	for _, r := range text {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		w, ok := tabelle[umlautBasis(c)]
+		if !ok {
+			w = 556
+		}
+		summe += w
+	}
+	return float64(summe) * groesse / 1000
+}
+
+// Umlaute und Akzente haben die Breite ihres Grundbuchstabens
+func umlautBasis(c byte) byte {
+	switch {
+	case c >= 0xC0 && c <= 0xC5:
+		return 'A'
+	case c >= 0xD2 && c <= 0xD6:
+		return 'O'
+	case c >= 0xD9 && c <= 0xDC:
+		return 'U'
+	case c >= 0xE0 && c <= 0xE5:
+		return 'a'
+	case c >= 0xE8 && c <= 0xEB:
+		return 'e'
+	case c >= 0xF2 && c <= 0xF6:
+		return 'o'
+	case c >= 0xF9 && c <= 0xFC:
+		return 'u'
+	case c == 0xDF: // ß
+		return 0xDF
+	}
+	return c
+}
+
+// Zeichenbreiten (1/1000 em) aus den Adobe-AFM-Dateien, ASCII 32–126 und ß
+var helveticaBreiten = breitenTabelle([]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}, 611)
+
+var helveticaFettBreiten = breitenTabelle([]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}, 611)
+
+func breitenTabelle(ascii []int, eszett int) map[byte]int {
+	t := make(map[byte]int, len(ascii)+1)
+	for i, w := range ascii {
+		t[byte(32+i)] = w
+	}
+	t[0xDF] = eszett
+	return t
+}
@@ -0,0 +1,206 @@
+// Package urkunde erzeugt die Urkunden des Sporttags als PDF.
+//
+// Layout und Texte kommen aus einer JSON-Vorlage (urkunde_vorlage.json),
+// die Urkundenart (Teilnehmer, Sieger, Ehren) aus Punkteschwellen je
+// Jahrgang und Geschlecht. Die PDFs werden lokal erzeugt, ohne externen
+// Dienst.
+package urkunde
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// Art der Urkunde
+type Art string
+
+const (
+	ArtTeilnehmer Art = "teilnehmer"
+	ArtSieger     Art = "sieger"
+	ArtEhren      Art = "ehren"
+)
+
+// Schwelle: ab wie vielen Punkten es Sieger- bzw. Ehrenurkunden gibt
+type Schwelle struct {
+	Geschlecht  string `json:"geschlecht,omitempty"` // "" = alle
+	JahrgangVon int    `json:"jahrgangVon"`
+	JahrgangBis int    `json:"jahrgangBis"`
+	Sieger      int    `json:"sieger"`
+	Ehren       int    `json:"ehren"`
+}
+
+// Konfiguration aus config.json ("urkunden")
+type Konfiguration struct {
+	Vorlage   string     `json:"vorlage"`
+	Schwellen []Schwelle `json:"schwellen"`
+}
+
+// Element der Vorlage: Text, Linie oder Rahmen
+type Element struct {
+	Typ         string  `json:"typ,omitempty"` // text (Standard) | linie | rahmen
+	Text        string  `json:"text,omitempty"`
+	X           float64 `json:"x"`
+	Y           float64 `json:"y"`
+	X2          float64 `json:"x2,omitempty"` // linie: Endpunkt
+	Y2          float64 `json:"y2,omitempty"`
+	Breite      float64 `json:"breite,omitempty"` // rahmen
+	Hoehe       float64 `json:"hoehe,omitempty"`
+	Groesse     float64 `json:"groesse,omitempty"` // Schriftgröße, Standard 12
+	Fett        bool    `json:"fett,omitempty"`
+	Ausrichtung string  `json:"ausrichtung,omitempty"` // links (Standard) | mitte | rechts
+	Staerke     float64 `json:"staerke,omitempty"`     // Linienstärke, Standard 1
+	NurArt      Art     `json:"nurArt,omitempty"`      // Element nur bei dieser Urkundenart
+
+	vorlage *template.Template
+}
+
+// Vorlage – Inhalt von urkunde_vorlage.json
+type Vorlage struct {
+	Breite   float64        `json:"breite"` // Punkt, A4 = 595 × 842
+	Hoehe    float64        `json:"hoehe"`
+	Titel    map[Art]string `json:"titel"`
+	Elemente []Element      `json:"elemente"`
+}
+
+// Daten einer Urkunde; in den Texten als {{.VorName}} usw. verfügbar
+type Daten struct {
+	VorName      string
+	NachName     string
+	Jahrgang     int
+	Geschlecht   string
+	Punkte       int
+	AnzStationen int
+	RiegenNummer int
+	Jahr         int
+	Art          Art
+	Titel        string
+}
+
+// Generator erzeugt Urkunden nach Vorlage und Schwellen
+type Generator struct {
+	vorlage   Vorlage
+	schwellen []Schwelle
+}
+
+// NewGenerator lädt und prüft die Vorlage
+func NewGenerator(k Konfiguration) (*Generator, error) {
+	b, err := os.ReadFile(k.Vorlage)
+	if err != nil {
+		return nil, err
+	}
+	var v Vorlage
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: %w", k.Vorlage, err)
+	}
+	if v.Breite <= 0 || v.Hoehe <= 0 {
+		return nil, fmt.Errorf("%s: breite und hoehe erforderlich", k.Vorlage)
+	}
+	for i := range v.Elemente {
+		e := &v.Elemente[i]
+		switch e.Typ {
+		case "", "text":
+			e.vorlage, err = template.New(fmt.Sprint(i)).Option("missingkey=error").Parse(e.Text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: Element %d: %w", k.Vorlage, i+1, err)
+			}
+		case "linie", "rahmen":
+		default:
+			return nil, fmt.Errorf("%s: Element %d: unbekannter typ %q", k.Vorlage, i+1, e.Typ)
+		}
+	}
+	for i, s := range k.Schwellen {
+		if s.JahrgangVon > s.JahrgangBis || s.Sieger > s.Ehren {
+			return nil, fmt.Errorf("Urkunden-Schwelle %d ungültig", i+1)
+		}
+	}
+	return &Generator{vorlage: v, schwellen: k.Schwellen}, nil
+}
+
+// Art bestimmt die Urkundenart aus Punkten, Jahrgang und Geschlecht.
+// Ohne passende Schwelle gibt es eine Teilnehmerurkunde.
+func (g *Generator) Art(jahrgang int, geschlecht string, punkte int) Art {
+	var passend *Schwelle
+	for i, s := range g.schwellen {
+		if jahrgang < s.JahrgangVon || jahrgang > s.JahrgangBis {
+			continue
+		}
+		if s.Geschlecht == geschlecht {
+			passend = &g.schwellen[i]
+			break
+		}
+		if s.Geschlecht == "" && passend == nil {
+			passend = &g.schwellen[i]
+		}
+	}
+	switch {
+	case passend == nil:
+		return ArtTeilnehmer
+	case punkte >= passend.Ehren:
+		return ArtEhren
+	case punkte >= passend.Sieger:
+		return ArtSieger
+	}
+	return ArtTeilnehmer
+}
+
+// PDF erzeugt ein Dokument mit einer Seite je Urkunde.
+// Art und Titel werden ergänzt, falls sie nicht gesetzt sind.
+func (g *Generator) PDF(urkunden []Daten) ([]byte, error) {
+	if len(urkunden) == 0 {
+		return nil, errors.New("keine Urkunden")
+	}
+
+	dok := neuesDokument(g.vorlage.Breite, g.vorlage.Hoehe)
+	for _, d := range urkunden {
+		if d.Art == "" {
+			d.Art = g.Art(d.Jahrgang, d.Geschlecht, d.Punkte)
+		}
+		if d.Titel == "" {
+			d.Titel = g.vorlage.Titel[d.Art]
+		}
+
+		seite := dok.neueSeite()
+		for _, e := range g.vorlage.Elemente {
+			if e.NurArt != "" && e.NurArt != d.Art {
+				continue
+			}
+			staerke := e.Staerke
+			if staerke <= 0 {
+				staerke = 1
+			}
+
+			switch e.Typ {
+			case "linie":
+				schreibeLinie(seite, e.X, e.Y, e.X2, e.Y2, staerke)
+			case "rahmen":
+				schreibeRahmen(seite, e.X, e.Y, e.Breite, e.Hoehe, staerke)
+			default:
+				var text bytes.Buffer
+				if err := e.vorlage.Execute(&text, d); err != nil {
+					return nil, fmt.Errorf("Vorlage: %w", err)
+				}
+				groesse := e.Groesse
+				if groesse <= 0 {
+					groesse = 12
+				}
+				f := schriftNormal
+				if e.Fett {
+					f = schriftFett
+				}
+				x := e.X
+				switch e.Ausrichtung {
+				case "mitte":
+					x -= textBreite(f, groesse, text.String()) / 2
+				case "rechts":
+					x -= textBreite(f, groesse, text.String())
+				}
+				schreibeText(seite, f, groesse, x, e.Y, text.String())
+			}
+		}
+	}
+	return dok.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+// Package wertung rechnet Messwerte (Zeit, Weite, Anzahl) anhand von
+// Punktetabellen im Stil der Bundesjugendspiele in Punkte um.
+//
+// Die Tabellen liegen in einer versionierten JSON-Datei (punktetabellen.json),
+// damit jederzeit nachvollziehbar ist, nach welcher Tabelle gewertet wurde.
+package wertung
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Disziplin einer Station
+type Disziplin string
+
+const (
+	DisziplinZeit   Disziplin = "zeit"   // Sekunden
+	DisziplinWeite  Disziplin = "weite"  // Meter
+	DisziplinAnzahl Disziplin = "anzahl" // Wiederholungen, Treffer …
+)
+
+func (d Disziplin) Gueltig() bool {
+	switch d {
+	case DisziplinZeit, DisziplinWeite, DisziplinAnzahl:
+		return true
+	}
+	return false
+}
+
+// Richtung: welcher Messwert ist besser?
+type Richtung string
+
+const (
+	KleinerBesser  Richtung = "kleinerBesser"
+	GroesserBesser Richtung = "groesserBesser"
+)
+
+func (r Richtung) Gueltig() bool {
+	return r == KleinerBesser || r == GroesserBesser
+}
+
+// Übliche Richtung der Disziplin: Zeit → kleiner ist besser, sonst größer
+func StandardRichtung(d Disziplin) Richtung {
+	if d == DisziplinZeit {
+		return KleinerBesser
+	}
+	return GroesserBesser
+}
+
+var (
+	ErrKeineTabelle   = errors.New("Punktetabelle nicht vorhanden")
+	ErrKeineWertung   = errors.New("keine Wertung für Jahrgang und Geschlecht")
+	ErrMesswert       = errors.New("Messwert ungültig")
+	ErrFalscheTabelle = errors.New("Punktetabelle passt nicht zur Disziplin")
+)
+
+// Stufe: ab dieser Grenze gibt es die Punkte
+// (kleinerBesser: Messwert ≤ Grenze, groesserBesser: Messwert ≥ Grenze)
+type Stufe struct {
+	Grenze float64 `json:"grenze"`
+	Punkte int     `json:"punkte"`
+}
+
+// Wertung für einen Jahrgangsbereich und ein Geschlecht ("" = alle)
+type Wertung struct {
+	Geschlecht  string  `json:"geschlecht,omitempty"`
+	JahrgangVon int     `json:"jahrgangVon"`
+	JahrgangBis int     `json:"jahrgangBis"`
+	Stufen      []Stufe `json:"stufen"`
+}
+
+// Tabelle einer Disziplin (z. B. "sprint50m")
+type Tabelle struct {
+	Disziplin Disziplin `json:"disziplin"`
+	Einheit   string    `json:"einheit,omitempty"`
+	Wertungen []Wertung `json:"wertungen"`
+}
+
+// Tabellen – Inhalt von punktetabellen.json
+type Tabellen struct {
+	Version   string             `json:"version"`
+	GueltigAb string             `json:"gueltigAb,omitempty"`
+	Quelle    string             `json:"quelle,omitempty"`
+	Tabellen  map[string]Tabelle `json:"tabellen"`
+}
+
+// Laden liest und prüft die Tabellen-Datei
+func Laden(pfad string) (*Tabellen, error) {
+	b, err := os.ReadFile(pfad)
+	if err != nil {
+		return nil, err
+	}
+	var t Tabellen
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", pfad, err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", pfad, err)
+	}
+	return &t, nil
+}
+
+// Prüft Version, Disziplinen und Jahrgangsbereiche
+func (t *Tabellen) Validate() error {
+	if t.Version == "" {
+		return errors.New("version fehlt")
+	}
+	for name, tab := range t.Tabellen {
+		if !tab.Disziplin.Gueltig() {
+			return fmt.Errorf("Tabelle %s: ungültige disziplin %q", name, tab.Disziplin)
+		}
+		for i, w := range tab.Wertungen {
+			if w.JahrgangVon > w.JahrgangBis {
+				return fmt.Errorf("Tabelle %s, Wertung %d: jahrgangVon > jahrgangBis", name, i+1)
+			}
+			if len(w.Stufen) == 0 {
+				return fmt.Errorf("Tabelle %s, Wertung %d: keine Stufen", name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Tabelle liefert die Tabelle mit dem Namen
+func (t *Tabellen) Tabelle(name string) (Tabelle, bool) {
+	if t == nil {
+		return Tabelle{}, false
+	}
+	tab, ok := t.Tabellen[name]
+	return tab, ok
+}
+
+// Punkte rechnet den Messwert eines Kindes in Punkte um.
+// Unterhalb der niedrigsten Stufe gibt es 0 Punkte. Bei kleinerBesser ist 0
+// kein Messwert (leeres Zeitfeld) und würde sonst jede Stufe erreichen.
+func (t *Tabellen) Punkte(name string, d Disziplin, r Richtung, jahrgang int, geschlecht string, messwert float64) (int, error) {
+	tab, ok := t.Tabelle(name)
+	if !ok {
+		return 0, ErrKeineTabelle
+	}
+	if tab.Disziplin != d {
+		return 0, ErrFalscheTabelle
+	}
+	if math.IsNaN(messwert) || math.IsInf(messwert, 0) || messwert < 0 {
+		return 0, ErrMesswert
+	}
+	if r == KleinerBesser && messwert == 0 {
+		return 0, ErrMesswert
+	}
+
+	w, ok := tab.wertungFuer(jahrgang, geschlecht)
+	if !ok {
+		return 0, ErrKeineWertung
+	}
+
+	punkte := 0
+	for _, s := range w.Stufen {
+		erreicht := messwert >= s.Grenze
+		if r == KleinerBesser {
+			erreicht = messwert <= s.Grenze
+		}
+		if erreicht && s.Punkte > punkte {
+			punkte = s.Punkte
+		}
+	}
+	return punkte, nil
+}
+
+// Eine Wertung für genau das Geschlecht hat Vorrang vor einer für alle
+func (tab Tabelle) wertungFuer(jahrgang int, geschlecht string) (Wertung, bool) {
+	var fallback *Wertung
+	for i, w := range tab.Wertungen {
+		if jahrgang < w.JahrgangVon || jahrgang > w.JahrgangBis {
+			continue
+		}
+		if w.Geschlecht == geschlecht {
+			return w, true
+		}
+		if w.Geschlecht == "" && fallback == nil {
+			fallback = &tab.Wertungen[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Wertung{}, false
+}
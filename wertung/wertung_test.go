@@ -0,0 +1,53 @@
+package wertung
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPunkte(t *testing.T) {
+	tab := &Tabellen{
+		Version: "test",
+		Tabellen: map[string]Tabelle{
+			"sprint50m": {Disziplin: DisziplinZeit, Wertungen: []Wertung{
+				{JahrgangVon: 2010, JahrgangBis: 2016, Stufen: []Stufe{{Grenze: 10, Punkte: 1}, {Grenze: 8, Punkte: 3}}},
+			}},
+			"weitsprung": {Disziplin: DisziplinWeite, Wertungen: []Wertung{
+				{JahrgangVon: 2010, JahrgangBis: 2016, Stufen: []Stufe{{Grenze: 0, Punkte: 0}, {Grenze: 2, Punkte: 2}}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		tabelle  string
+		d        Disziplin
+		r        Richtung
+		messwert float64
+		want     int
+		wantErr  error
+	}{
+		{"Zeit schnell", "sprint50m", DisziplinZeit, KleinerBesser, 7.9, 3, nil},
+		{"Zeit auf der Grenze", "sprint50m", DisziplinZeit, KleinerBesser, 10, 1, nil},
+		{"Zeit zu langsam", "sprint50m", DisziplinZeit, KleinerBesser, 12, 0, nil},
+		{"Zeit 0", "sprint50m", DisziplinZeit, KleinerBesser, 0, 0, ErrMesswert},
+		{"Zeit negativ", "sprint50m", DisziplinZeit, KleinerBesser, -1, 0, ErrMesswert},
+		{"Zeit NaN", "sprint50m", DisziplinZeit, KleinerBesser, math.NaN(), 0, ErrMesswert},
+		{"Weite 0", "weitsprung", DisziplinWeite, GroesserBesser, 0, 0, nil},
+		{"Weite", "weitsprung", DisziplinWeite, GroesserBesser, 2.5, 2, nil},
+		{"falsche Disziplin", "weitsprung", DisziplinZeit, KleinerBesser, 1, 0, ErrFalscheTabelle},
+		{"unbekannte Tabelle", "hochsprung", DisziplinWeite, GroesserBesser, 1, 0, ErrKeineTabelle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tab.Punkte(tt.tabelle, tt.d, tt.r, 2014, "w", tt.messwert)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Punkte = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}